@@ -6,6 +6,7 @@ import (
 	"github.com/automuteus/automuteus/v8/docs"
 	"github.com/automuteus/automuteus/v8/pkg/discord"
 	"github.com/automuteus/automuteus/v8/pkg/premium"
+	"github.com/automuteus/automuteus/v8/pkg/webhook"
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
@@ -63,6 +64,10 @@ func (bot *Bot) StartAPIServer(port string) {
 	}))
 	guildGroup.GET("/settings", handleGetGuildSettings(bot))
 	guildGroup.GET("/premium", handleGetGuildPremium(bot))
+	guildGroup.GET("/webhooks", handleGetGuildWebhooks(bot))
+	guildGroup.POST("/webhooks", handlePostGuildWebhook(bot))
+	guildGroup.DELETE("/webhooks", handleDeleteGuildWebhook(bot))
+	guildGroup.GET("/simulate", handleGetGuildSimulation(bot))
 
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
@@ -201,7 +206,7 @@ func handleGetGameState(bot *Bot) func(c *gin.Context) {
 // GetGuildSettings godoc
 // @Summary Get Guild Settings
 // @Schemes GET
-// @Description Get the settings for a given guild
+// @Description Get the settings for a given guild, without webhook secrets
 // @Security BasicAuth
 // @Tags guild
 // @Accept json
@@ -231,7 +236,15 @@ func handleGetGuildSettings(bot *Bot) func(c *gin.Context) {
 			})
 			return
 		}
-		settings := bot.StorageInterface.GetGuildSettings(guildID)
+		// webhook secrets are only ever shown once, when the webhook is added
+		settings, err := bot.StorageInterface.GetGuildSettings(guildID).WithoutSecrets()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, HttpError{
+				StatusCode: http.StatusInternalServerError,
+				Error:      err.Error(),
+			})
+			return
+		}
 		c.JSON(http.StatusOK, settings)
 	}
 }
@@ -275,6 +288,215 @@ func handleGetGuildPremium(bot *Bot) func(c *gin.Context) {
 	}
 }
 
+// GetGuildWebhooks godoc
+// @Summary Get Guild Webhooks
+// @Schemes GET
+// @Description Get the webhooks a given guild has subscribed to game events
+// @Security BasicAuth
+// @Tags guild
+// @Accept json
+// @Produce json
+// @Param guildID query string true "Guild ID"
+// @Success 200 {array} webhook.Subscription
+// @Failure 400 {object} HttpError
+// @Failure 500 {object} HttpError
+// @Router /guild/webhooks [get]
+func handleGetGuildWebhooks(bot *Bot) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		guildID := c.Query("guildID")
+		if discord.ValidateSnowflake(guildID) != nil {
+			c.JSON(http.StatusBadRequest, HttpError{
+				StatusCode: http.StatusBadRequest,
+				Error:      "invalid guild ID",
+			})
+			return
+		}
+		// the secrets are only ever shown once, when the webhook is added
+		redacted, err := bot.StorageInterface.GetGuildSettings(guildID).WithoutSecrets()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, HttpError{
+				StatusCode: http.StatusInternalServerError,
+				Error:      err.Error(),
+			})
+			return
+		}
+		subs := redacted.GetWebhooks()
+		if subs == nil {
+			subs = []webhook.Subscription{}
+		}
+		c.JSON(http.StatusOK, subs)
+	}
+}
+
+type WebhookRequest struct {
+	URL    string          `json:"url"`
+	Events []webhook.Event `json:"events"`
+}
+
+// PostGuildWebhook godoc
+// @Summary Add Guild Webhook
+// @Schemes POST
+// @Description Subscribe a URL to a guild's game events. The response includes the secret that signs every request
+// @Security BasicAuth
+// @Tags guild
+// @Accept json
+// @Produce json
+// @Param guildID query string true "Guild ID"
+// @Param webhook body WebhookRequest true "URL and events; no events means all of them"
+// @Success 201 {object} webhook.Subscription
+// @Failure 400 {object} HttpError
+// @Failure 500 {object} HttpError
+// @Router /guild/webhooks [post]
+func handlePostGuildWebhook(bot *Bot) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		guildID := c.Query("guildID")
+		if discord.ValidateSnowflake(guildID) != nil {
+			c.JSON(http.StatusBadRequest, HttpError{
+				StatusCode: http.StatusBadRequest,
+				Error:      "invalid guild ID",
+			})
+			return
+		}
+		var req WebhookRequest
+		err := c.ShouldBindJSON(&req)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, HttpError{
+				StatusCode: http.StatusBadRequest,
+				Error:      err.Error(),
+			})
+			return
+		}
+		u, err := webhook.ValidateURL(req.URL)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, HttpError{
+				StatusCode: http.StatusBadRequest,
+				Error:      err.Error(),
+			})
+			return
+		}
+		for _, v := range req.Events {
+			if !webhook.IsEvent(string(v)) {
+				c.JSON(http.StatusBadRequest, HttpError{
+					StatusCode: http.StatusBadRequest,
+					Error:      "unknown event " + string(v),
+				})
+				return
+			}
+		}
+
+		sett := bot.StorageInterface.GetGuildSettings(guildID)
+		sub := webhook.NewSubscription(u.String(), req.Events)
+		if !sett.AddWebhook(sub) {
+			c.JSON(http.StatusBadRequest, HttpError{
+				StatusCode: http.StatusBadRequest,
+				Error:      "guild already has the maximum number of webhooks",
+			})
+			return
+		}
+		err = bot.StorageInterface.SetGuildSettings(guildID, sett)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, HttpError{
+				StatusCode: http.StatusInternalServerError,
+				Error:      err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusCreated, sub)
+	}
+}
+
+// DeleteGuildWebhook godoc
+// @Summary Remove Guild Webhook
+// @Schemes DELETE
+// @Description Unsubscribe one of a guild's webhooks
+// @Security BasicAuth
+// @Tags guild
+// @Accept json
+// @Produce json
+// @Param guildID query string true "Guild ID"
+// @Param id query string true "Webhook ID"
+// @Success 204 {object} nil
+// @Failure 400 {object} HttpError
+// @Failure 404 {object} HttpError
+// @Failure 500 {object} HttpError
+// @Router /guild/webhooks [delete]
+func handleDeleteGuildWebhook(bot *Bot) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		guildID := c.Query("guildID")
+		if discord.ValidateSnowflake(guildID) != nil {
+			c.JSON(http.StatusBadRequest, HttpError{
+				StatusCode: http.StatusBadRequest,
+				Error:      "invalid guild ID",
+			})
+			return
+		}
+		sett := bot.StorageInterface.GetGuildSettings(guildID)
+		if !sett.RemoveWebhook(c.Query("id")) {
+			c.JSON(http.StatusNotFound, HttpError{
+				StatusCode: http.StatusNotFound,
+				Error:      "No webhook found with that ID",
+			})
+			return
+		}
+		err := bot.StorageInterface.SetGuildSettings(guildID, sett)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, HttpError{
+				StatusCode: http.StatusInternalServerError,
+				Error:      err.Error(),
+			})
+			return
+		}
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// GetGuildSimulation godoc
+// @Summary Simulate Guild Voice Rules
+// @Schemes GET
+// @Description Play a match through a guild's voice rules and delays, and get who would be muted or deafened at each step
+// @Security BasicAuth
+// @Tags guild
+// @Accept json
+// @Produce json
+// @Param guildID query string true "Guild ID"
+// @Param channelID query string false "Voice channel whose overrides to use"
+// @Success 200 {array} settings.SimulationStep
+// @Failure 400 {object} HttpError
+// @Failure 500 {object} HttpError
+// @Router /guild/simulate [get]
+func handleGetGuildSimulation(bot *Bot) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		guildID := c.Query("guildID")
+		if discord.ValidateSnowflake(guildID) != nil {
+			c.JSON(http.StatusBadRequest, HttpError{
+				StatusCode: http.StatusBadRequest,
+				Error:      "invalid guild ID",
+			})
+			return
+		}
+		sett := bot.StorageInterface.GetGuildSettings(guildID)
+		if channelID := c.Query("channelID"); channelID != "" {
+			if discord.ValidateSnowflake(channelID) != nil {
+				c.JSON(http.StatusBadRequest, HttpError{
+					StatusCode: http.StatusBadRequest,
+					Error:      "invalid channel ID",
+				})
+				return
+			}
+			var err error
+			sett, err = sett.ScopedTo(channelID)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, HttpError{
+					StatusCode: http.StatusInternalServerError,
+					Error:      err.Error(),
+				})
+				return
+			}
+		}
+		c.JSON(http.StatusOK, sett.SimulateVoiceRules())
+	}
+}
+
 type HttpError struct {
 	StatusCode int
 	Error      string
@@ -15,6 +15,7 @@ import (
 	"github.com/automuteus/automuteus/v8/pkg/settings"
 	storageutils "github.com/automuteus/automuteus/v8/pkg/storage"
 	"github.com/automuteus/automuteus/v8/pkg/token"
+	"github.com/automuteus/automuteus/v8/pkg/webhook"
 	"github.com/automuteus/automuteus/v8/storage"
 	"github.com/bwmarrin/discordgo"
 	"github.com/top-gg/go-dbl"
@@ -55,6 +56,12 @@ type Bot struct {
 	logPath string
 
 	captureTimeout int
+
+	// recorder is only set when capture sessions should be recorded for replays
+	recorder *CaptureRecorder
+
+	// webhooks delivers game events to the URLs guilds have subscribed
+	webhooks *webhook.Dispatcher
 }
 
 // MakeAndStartBot does what it sounds like
@@ -88,6 +95,7 @@ func MakeAndStartBot(version, commit, botToken, topGGToken, url, emojiGuildID st
 		PostgresInterface: psql,
 		logPath:           logPath,
 		captureTimeout:    GameTimeoutSeconds,
+		webhooks:          webhook.NewDispatcher(),
 	}
 	dg.LogLevel = discordgo.LogInformational
 
@@ -162,6 +170,10 @@ func (bot *Bot) StartMetricsServer(nodeID string) error {
 	return server.PrometheusMetricsServer(bot.RedisInterface.client, nodeID, "2112")
 }
 
+func (bot *Bot) StartCaptureServer(port string) error {
+	return server.StartCaptureServer(bot.RedisInterface.client, port)
+}
+
 func (bot *Bot) Close() {
 	bot.PrimarySession.Close()
 	bot.RedisInterface.Close()
@@ -258,8 +270,17 @@ func (bot *Bot) forceEndGame(gsr GameStateRequest) {
 		go server.RecordDiscordRequests(bot.RedisInterface.client, server.MessageCreateDelete, 1)
 	}
 
+	roleChanges := dgs.takeGameRoles()
+	nickChanges := dgs.takeNicknames()
+
 	bot.RedisInterface.SetDiscordGameState(dgs, lock)
 
+	sett := bot.StorageInterface.GetGuildSettings(dgs.GuildID)
+	dgs.applyGameSettings(sett)
+	bot.returnFromGhostChannel(bot.PrimarySession, dgs, sett.GetGhostChannelID())
+	bot.applyGameRoleChanges(bot.PrimarySession, dgs.GuildID, roleChanges)
+	bot.applyNicknameChanges(bot.PrimarySession, gsr, nickChanges)
+
 	bot.RedisInterface.RemoveOldGame(dgs.GuildID, dgs.ConnectCode)
 
 	// Note, this shouldn't be necessary with the TTL of the keys, but it can't hurt to clean up...
@@ -15,6 +15,7 @@ const (
 	NewSuccess NewStatus = iota
 	NewNoVoiceChannel
 	NewLockout
+	NewUnknownPreset
 )
 
 type NewInfo struct {
@@ -23,12 +24,31 @@ type NewInfo struct {
 	ApiHyperlink string
 	ConnectCode  string
 	ActiveGames  int64
+	Preset       string
 }
 
 // /new → /start にリネーム済み
 var New = discordgo.ApplicationCommand{
 	Name:        "start",
 	Description: "オートミュートを開始します",
+	Options: []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "preset",
+			Description: "Voice rule preset for this game (see /settings game presets)",
+			Required:    false,
+		},
+	},
+}
+
+// GetNewParams returns the preset to start the game with, or "" for the guild's defaults
+func GetNewParams(options []*discordgo.ApplicationCommandInteractionDataOption) string {
+	for _, v := range options {
+		if v.Name == "preset" {
+			return strings.ToLower(strings.TrimSpace(v.StringValue()))
+		}
+	}
+	return ""
 }
 
 func NewResponse(status NewStatus, info NewInfo, sett *settings.GuildSettings) *discordgo.InteractionResponse {
@@ -84,6 +104,14 @@ func NewResponse(status NewStatus, info NewInfo, sett *settings.GuildSettings) *
 			Other: "Please join a voice channel before starting a match!",
 		})
 
+	case NewUnknownPreset:
+		content = sett.LocalizeMessage(&i18n.Message{
+			ID:    "commands.new.unknownPreset",
+			Other: "There's no preset called `{{.Preset}}`. Use `/settings game presets` to see them all",
+		}, map[string]interface{}{
+			"Preset": info.Preset,
+		})
+
 	case NewLockout:
 		// ロックアウト警告はみんなに見えて欲しいので「公開メッセージ」に切り替え
 		content = sett.LocalizeMessage(&i18n.Message{
@@ -13,10 +13,19 @@ var Settings = discordgo.ApplicationCommand{
 }
 
 func GetSettingsParams(options []*discordgo.ApplicationCommandInteractionDataOption) (string, []string) {
-	sett := setting.GetSettingByName(options[0].Name)
-	args := make([]string, len(options[0].Options))
+	option := options[0]
+	sett := setting.GetSettingByName(option.Name)
+	if _, ok := setting.SettingGroups[option.Name]; ok && len(option.Options) > 0 {
+		// a grouped setting is a subcommand of its group
+		option = option.Options[0]
+		sett = setting.GetGroupedSetting(options[0].Name, option.Name)
+	}
+	if sett == nil {
+		return setting.List, nil
+	}
+	args := make([]string, len(option.Options))
 	// iterate over the subcommands/args we received from discord
-	for i, v := range options[0].Options {
+	for i, v := range option.Options {
 		var arg *discordgo.ApplicationCommandOption
 		// iterate over the arguments we know we could possibly receive, and break when we find the right one
 		for _, tempArg := range sett.Arguments {
@@ -32,6 +41,10 @@ func GetSettingsParams(options []*discordgo.ApplicationCommandInteractionDataOpt
 		// in this case, a subcommand that has options of its own
 		if arg.Type == discordgo.ApplicationCommandOptionSubCommand && len(v.Options) > 0 {
 			args[i] = setting.ToString(v.Options[0])
+			// any further options of the subcommand follow the first one
+			for _, opt := range v.Options[1:] {
+				args = append(args, setting.ToString(opt))
+			}
 		} else {
 			// in this case, any sort of subcommand or option/argument that can be converted directly
 			// TODO this should be more flexible, not just string arguments. But requires all the tests to change, etc
@@ -70,7 +83,28 @@ func SettingsResponse(m interface{}) *discordgo.InteractionResponse {
 
 func settingsToCommandOptions() []*discordgo.ApplicationCommandOption {
 	var choices []*discordgo.ApplicationCommandOption
+	groups := make(map[string]*discordgo.ApplicationCommandOption)
 	for _, sett := range setting.AllSettings {
+		if sett.Group != "" {
+			// the group goes where its first setting would have
+			group, ok := groups[sett.Group]
+			if !ok {
+				group = &discordgo.ApplicationCommandOption{
+					Name:        sett.Group,
+					Description: setting.SettingGroups[sett.Group],
+					Type:        discordgo.ApplicationCommandOptionSubCommandGroup,
+				}
+				groups[sett.Group] = group
+				choices = append(choices, group)
+			}
+			group.Options = append(group.Options, &discordgo.ApplicationCommandOption{
+				Name:        sett.Name,
+				Description: sett.ShortDesc,
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options:     sett.Arguments,
+			})
+			continue
+		}
 		optionType := discordgo.ApplicationCommandOptionSubCommand
 
 		// if arguments are subcommands, then make this one a group
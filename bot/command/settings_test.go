@@ -53,6 +53,82 @@ func TestGetSettingsParams(t *testing.T) {
 	if args[0] != setting.Clear {
 		t.Fail()
 	}
+
+	options = []*discordgo.ApplicationCommandInteractionDataOption{
+		&discordgo.ApplicationCommandInteractionDataOption{
+			Name: setting.Webhooks,
+			Type: discordgo.ApplicationCommandOptionSubCommandGroup,
+			Options: []*discordgo.ApplicationCommandInteractionDataOption{
+				&discordgo.ApplicationCommandInteractionDataOption{
+					Name: setting.Add,
+					Type: discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandInteractionDataOption{
+						&discordgo.ApplicationCommandInteractionDataOption{
+							Name:  "url",
+							Type:  discordgo.ApplicationCommandOptionString,
+							Value: "https://example.com/hook",
+						},
+						&discordgo.ApplicationCommandInteractionDataOption{
+							Name:  "events",
+							Type:  discordgo.ApplicationCommandOptionString,
+							Value: "game.start",
+						},
+					},
+				},
+			},
+		},
+	}
+	settingName, args = GetSettingsParams(options)
+	if settingName != setting.Webhooks {
+		t.Fail()
+	}
+	if len(args) != 2 || args[0] != "https://example.com/hook" || args[1] != "game.start" {
+		t.Errorf("unexpected args %v", args)
+	}
+
+	options = []*discordgo.ApplicationCommandInteractionDataOption{
+		&discordgo.ApplicationCommandInteractionDataOption{
+			Name: setting.GameGroup,
+			Type: discordgo.ApplicationCommandOptionSubCommandGroup,
+			Options: []*discordgo.ApplicationCommandInteractionDataOption{
+				&discordgo.ApplicationCommandInteractionDataOption{
+					Name: setting.CaptureGrace,
+					Type: discordgo.ApplicationCommandOptionSubCommand,
+					Options: []*discordgo.ApplicationCommandInteractionDataOption{
+						&discordgo.ApplicationCommandInteractionDataOption{
+							Name:  "seconds",
+							Type:  discordgo.ApplicationCommandOptionInteger,
+							Value: float64(30),
+						},
+					},
+				},
+			},
+		},
+	}
+	settingName, args = GetSettingsParams(options)
+	if settingName != setting.CaptureGrace {
+		t.Errorf("expected the grouped %s setting, got %s", setting.CaptureGrace, settingName)
+	}
+	if len(args) != 1 || args[0] != "30" {
+		t.Errorf("unexpected args %v", args)
+	}
+}
+
+func TestSettingsToCommandOptions(t *testing.T) {
+	options := settingsToCommandOptions()
+	// Discord won't register a command with any more
+	if len(options) > 25 {
+		t.Errorf("/settings has %d options, more than the 25 allowed", len(options))
+	}
+	for _, v := range options {
+		if v.Name == setting.GameGroup {
+			if v.Type != discordgo.ApplicationCommandOptionSubCommandGroup || len(v.Options) == 0 {
+				t.Errorf("expected %s to be a group of settings, got %+v", v.Name, v)
+			}
+			return
+		}
+	}
+	t.Errorf("expected a %s group", setting.GameGroup)
 }
 
 // TODO construct a test to validate complex settings behavior, like voice rules or delays
@@ -3,9 +3,11 @@ package bot
 import (
 	"fmt"
 	"log"
+	"sort"
 	"strings"
 
 	"github.com/automuteus/automuteus/v8/pkg/amongus"
+	"github.com/automuteus/automuteus/v8/pkg/game"
 	"github.com/automuteus/automuteus/v8/pkg/settings"
 	"github.com/bwmarrin/discordgo"
 	"github.com/nicksnyder/go-i18n/v2/i18n"
@@ -34,6 +36,21 @@ type GameState struct {
 	// ===== 追加: AmongUsCapture 接続状態 =====
 	CaptureConnected bool  `json:"captureConnected"`
 	LastCapturePing  int64 `json:"lastCapturePing,omitempty"`
+
+	// CaptureLost is set by the capture watchdog while everyone has been unmuted because the capture went silent
+	CaptureLost bool `json:"captureLost,omitempty"`
+	// CaptureHeartbeatSeen and CaptureDisconnected arm the capture watchdog. Without the capture server's heartbeat,
+	// or the capture telling us it disconnected, a quiet round can't be told apart from a capture that's gone
+	CaptureHeartbeatSeen bool `json:"captureHeartbeatSeen,omitempty"`
+	CaptureDisconnected  bool `json:"captureDisconnected,omitempty"`
+
+	// RevealedTeams are the teams from the last game over, by player name, until the next game starts
+	RevealedTeams map[string]game.Team `json:"revealedTeams,omitempty"`
+
+	// Preset is the one the game was started with, if any; it's kept here so changing the guild's presets or
+	// defaults doesn't change a game that's already running
+	PresetName string           `json:"presetName,omitempty"`
+	Preset     *settings.Preset `json:"preset,omitempty"`
 }
 
 // ===== GameState ヘルパー =====
@@ -61,6 +78,44 @@ func (dgs *GameState) Reset() {
 	// ===== 追加: Capture未接続で初期化 =====
 	dgs.CaptureConnected = false
 	dgs.LastCapturePing = 0
+	dgs.CaptureLost = false
+	dgs.CaptureHeartbeatSeen = false
+	dgs.CaptureDisconnected = false
+	dgs.RevealedTeams = nil
+	dgs.PresetName = ""
+	dgs.Preset = nil
+}
+
+// applyGameSettings puts the voice channel's overrides, then the game's preset, in place of the guild's defaults
+func (dgs *GameState) applyGameSettings(sett *settings.GuildSettings) {
+	sett.ApplyChannelOverrides(dgs.VoiceChannel)
+	if dgs.Preset != nil {
+		sett.ApplyPreset(*dgs.Preset)
+	}
+}
+
+// voiceCategory is which of the guild's voice rules apply to a player; found is whether they're linked
+func (dgs *GameState) voiceCategory(player amongus.PlayerData, found bool) game.VoiceCategory {
+	if !found {
+		return game.SpectatorCategory
+	}
+	switch dgs.RevealedTeams[player.Name] {
+	case game.ImpostorTeam:
+		return game.ImpostorCategory
+	case game.CrewmateTeam:
+		return game.CrewmateCategory
+	}
+	return game.PlayerCategory
+}
+
+// revealTeams records everyone's team from the game over, for the voice rules in the lobby after
+func (dgs *GameState) revealTeams(gameOver game.Gameover) {
+	dgs.RevealedTeams = make(map[string]game.Team)
+	for _, v := range gameOver.PlayerInfos {
+		if player, found := dgs.GameData.GetByName(v.Name); found {
+			dgs.RevealedTeams[player.Name] = v.GetTeam()
+		}
+	}
 }
 
 // ギルドメンバー情報をキャッシュしつつ UserData を作成
@@ -162,6 +217,17 @@ func colorLabelFromEmojiName(name string) string {
 	return "❓ 不明"
 }
 
+// colorEmoji は色番号から色マスタの絵文字だけを返す（例: 0 → "🟥"）
+func colorEmoji(color int) string {
+	key := game.GetColorStringForInt(color)
+	for _, p := range colorLabelPatterns {
+		if p.Key == key {
+			return strings.SplitN(p.Label, " ", 2)[0]
+		}
+	}
+	return ""
+}
+
 //
 // ===== ここから Embed のプレイヤー一覧生成 =====
 //
@@ -222,6 +288,9 @@ func (dgs *GameState) ToEmojiEmbedFields(emojis AlivenessEmojis, sett *settings.
 					statusText,
 					colorLabel,
 				)
+				if userData.Exempt {
+					field.Value += "　🔈 " + exemptText(sett)
+				}
 
 				linked = true
 				break
@@ -256,6 +325,33 @@ func (dgs *GameState) ToEmojiEmbedFields(emojis AlivenessEmojis, sett *settings.
 		}
 	}
 
+	// ミュート対象外のメンバー（配信者や音楽Botなど、プレイヤーでない人）
+	var exempt []string
+	for userID, userData := range dgs.UserData {
+		if userData.Exempt && userData.InGameName == amongus.UnlinkedPlayerName {
+			name := dgs.DisplayNames[userID]
+			if name == "" {
+				name = userData.User.UserName
+			}
+			exempt = append(exempt, name)
+		}
+	}
+	if len(exempt) > 0 {
+		sort.Strings(exempt)
+		sorted = append(sorted, &discordgo.MessageEmbedField{
+			Name:   "🔈 " + exemptText(sett),
+			Value:  strings.Join(exempt, ", "),
+			Inline: false,
+		})
+	}
+
 	// ※1人1ブロックで縦並びにするので、最後の行を埋めるパディングは不要
 	return sorted
 }
+
+func exemptText(sett *settings.GuildSettings) string {
+	return sett.LocalizeMessage(&i18n.Message{
+		ID:    "discordGameState.ToEmojiEmbedFields.Exempt",
+		Other: "Never muted",
+	})
+}
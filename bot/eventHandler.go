@@ -2,8 +2,7 @@ package bot
 
 import (
 	"bytes"
-	"encoding/json"
-	"errors"
+	"context"
 	"fmt"
 	"github.com/automuteus/automuteus/v8/internal/server"
 	"github.com/automuteus/automuteus/v8/pkg/amongus"
@@ -12,11 +11,11 @@ import (
 	"github.com/automuteus/automuteus/v8/pkg/settings"
 	"github.com/automuteus/automuteus/v8/pkg/storage"
 	"github.com/automuteus/automuteus/v8/pkg/task"
-	"github.com/go-redis/redis/v8"
+	"github.com/automuteus/automuteus/v8/pkg/webhook"
 	"github.com/nicksnyder/go-i18n/v2/i18n"
 	"log"
+	"sort"
 	"strconv"
-	"strings"
 	"time"
 )
 
@@ -25,280 +24,376 @@ type EndGameMessage bool
 func (bot *Bot) SubscribeToGameByConnectCode(guildID, connectCode string, endGameChannel chan EndGameMessage) {
 	log.Println("Started Redis Subscription worker for " + connectCode)
 
-	notify := task.Subscribe(ctx, bot.RedisInterface.client, connectCode)
+	readCtx, stopReading := context.WithCancel(ctx)
+	defer stopReading()
+	jobs := task.ReadJobs(readCtx, bot.RedisInterface.client, connectCode)
 
 	timer := time.NewTimer(time.Second * time.Duration(bot.captureTimeout))
 
+	watchdog := time.NewTicker(CaptureWatchdogInterval)
+	defer watchdog.Stop()
+	reconcile := time.NewTicker(ReconcileInterval)
+	defer reconcile.Stop()
+	lastJob := time.Now()
+
+	// jobs can arrive out of order (e.g. when claimed from another shard), so they're applied by sequence number
+	sequencer := task.NewSequencer()
+	var reorder <-chan time.Time
+
 	dgsRequest := GameStateRequest{
 		GuildID:     guildID,
 		ConnectCode: connectCode,
 	}
 
+	// mark the game as live right away, so the capture server accepts this code before any jobs arrive
+	bot.refreshGameLiveness(connectCode)
+
 	// indicate to the broker that we're online and ready to start processing messages
 	task.Ack(ctx, bot.RedisInterface.client, connectCode)
 
 	for {
 		select {
-		case message := <-notify.Channel():
-			timer.Reset(time.Second * time.Duration(bot.captureTimeout))
-			if message == nil {
+		case job, ok := <-jobs:
+			if !ok {
 				break
 			}
+			timer.Reset(time.Second * time.Duration(bot.captureTimeout))
+			lastJob = time.Now()
+
+			log.Printf("Read job of type %d w/ payload %s\n", job.JobType, job.RawPayload())
+			bot.recordJob(dgsRequest, job)
+			bot.refreshGameLiveness(connectCode)
+			bot.RedisInterface.RefreshActiveGame(guildID, connectCode)
+
+			ready, stale := sequencer.Push(job)
+			if stale {
+				log.Printf("Discarding stale job %d for %s\n", job.Seq, connectCode)
+				server.RecordCaptureEvents(bot.RedisInterface.client, server.StaleCaptureJob, 1)
+				bot.ackJob(connectCode, job)
+			}
+			for _, j := range ready {
+				bot.processJob(dgsRequest, j)
+			}
+			reorder = reorderDeadline(sequencer)
 
-			// anytime we get a notification message, continue pulling messages off the list until there are no more
-			for {
-				job, err := task.PopJob(ctx, bot.RedisInterface.client, connectCode)
-				if errors.Is(err, redis.Nil) {
-					break
-				} else if err != nil {
-					log.Println(err)
-					break
-				}
-				log.Printf("Popped job of type %d w/ payload %s\n", job.JobType, job.Payload.(string))
-				bot.refreshGameLiveness(connectCode)
-				bot.RedisInterface.RefreshActiveGame(guildID, connectCode)
-
-				gameEvent := storage.PostgresGameEvent{
-					GameID:    -1,
-					UserID:    nil,
-					EventTime: int32(time.Now().Unix()),
-					EventType: int16(job.JobType),
-					Payload:   job.Payload.(string),
-				}
-				correlatedUserID := ""
-				sett := bot.StorageInterface.GetGuildSettings(guildID)
-
-				switch job.JobType {
-
-				// ======================================================
-				// ★ ConnectionJob = Capture の接続/切断通知
-				// ======================================================
-				case task.ConnectionJob:
-					lock, dgs := bot.RedisInterface.GetDiscordGameStateAndLock(dgsRequest)
-					for lock == nil {
-						lock, dgs = bot.RedisInterface.GetDiscordGameStateAndLock(dgsRequest)
-					}
+		case <-reorder:
+			ready, missing := sequencer.Skip()
+			log.Printf("Gave up waiting on %d job(s) for %s; asking the capture to resend its players\n", missing, connectCode)
+			server.RecordCaptureEvents(bot.RedisInterface.client, server.CaptureSequenceGap, missing)
+			err := server.RequestPlayerResend(ctx, bot.RedisInterface.client, connectCode)
+			if err != nil {
+				log.Println(err)
+			}
+			for _, j := range ready {
+				bot.processJob(dgsRequest, j)
+			}
+			reorder = reorderDeadline(sequencer)
 
-					// 変更前の接続状態を保持（変化があったときだけ Refresh）
-					prevCapture := dgs.CaptureConnected
+		case <-watchdog.C:
+			bot.checkCaptureWatchdog(dgsRequest, lastJob)
 
-					if job.Payload == "true" {
-						dgs.Linked = true
+		case <-reconcile.C:
+			bot.reconcileVoiceStates(dgsRequest)
 
-						// ★ Capture 接続確立！
-						dgs.CaptureConnected = true
-						dgs.LastCapturePing = time.Now().Unix()
-					} else {
-						dgs.Linked = false
+		case <-timer.C:
+			timer.Stop()
+			log.Printf("Killing game w/ code %s after %d seconds of inactivity!\n", connectCode, bot.captureTimeout)
+			stopReading()
+			bot.finishRecording(connectCode)
+			bot.removeGameLiveness(connectCode)
+			go bot.forceEndGame(dgsRequest)
+			bot.ChannelsMapLock.Lock()
+			delete(bot.EndGameChannels, connectCode)
+			bot.ChannelsMapLock.Unlock()
 
-						// ★ Capture 切断
-						dgs.CaptureConnected = false
-						dgs.LastCapturePing = time.Now().Unix()
-					}
+			return
+		case <-endGameChannel:
+			log.Println("Redis subscriber received kill signal, stopping job reads")
+			stopReading()
+			bot.finishRecording(connectCode)
+			bot.removeGameLiveness(connectCode)
+			bot.forceEndGame(dgsRequest)
+			return
+		}
+	}
+}
 
-					dgs.ConnectCode = connectCode
-					bot.RedisInterface.SetDiscordGameState(dgs, lock)
+// processJob applies a single job to the game, in sequence order, and acks it
+func (bot *Bot) processJob(dgsRequest GameStateRequest, job task.Job) {
+	guildID, connectCode := dgsRequest.GuildID, dgsRequest.ConnectCode
 
-					bot.handleTrackedMembers(bot.PrimarySession, sett, 0, NoPriority, dgsRequest)
+	// logged up front, so that any links that come out of the job land after it
+	bot.RedisInterface.AppendGameLog(guildID, connectCode, GameLogEntry{Job: &job})
 
-					// ★ 接続状態が変化した瞬間だけ「作り直し」
-					//   - false -> true ならボタン出現
-					//   - true -> false ならボタン消える（任意だけど安全）
-					if prevCapture != dgs.CaptureConnected {
-						bot.RefreshGameStateMessage(dgsRequest, sett)
-					} else {
-						bot.DispatchRefreshOrEdit(dgs, dgsRequest, sett)
-					}
+	gameEvent := storage.PostgresGameEvent{
+		GameID:    -1,
+		UserID:    nil,
+		EventTime: int32(time.Now().Unix()),
+		EventType: int16(job.JobType),
+		Payload:   job.RawPayload(),
+	}
+	correlatedUserID := ""
+	sett := bot.gameSettings(dgsRequest)
+
+	// malformed payloads are counted and skipped, rather than taking down the whole subscriber
+	var decodeErr error
+	// anything but a disconnect means the capture is back, if the watchdog had given up on it
+	captureAlive := true
+
+	switch job.JobType {
+
+	// ======================================================
+	// ★ ConnectionJob = Capture の接続/切断通知
+	// ======================================================
+	case task.ConnectionJob:
+		var connected bool
+		connected, decodeErr = job.DecodeConnection()
+		if decodeErr != nil {
+			break
+		}
+		captureAlive = connected
+		lock, dgs := bot.RedisInterface.GetDiscordGameStateAndLock(dgsRequest)
+		for lock == nil {
+			lock, dgs = bot.RedisInterface.GetDiscordGameStateAndLock(dgsRequest)
+		}
 
-				// ======================================================
-				// ★ Lobby/State/Player Job でも
-				//   「ConnectionJobが来ない保険」で CaptureConnected を true にする
-				// ======================================================
-				case task.LobbyJob:
-					var lobby game.Lobby
-					err = json.Unmarshal([]byte(job.Payload.(string)), &lobby)
-					if err != nil {
-						log.Println(err)
-						break
-					}
-					bot.processLobby(sett, lobby, dgsRequest)
+		// 変更前の接続状態を保持（変化があったときだけ Refresh）
+		prevCapture := dgs.CaptureConnected
+		dgs.CaptureDisconnected = !connected
 
-				case task.StateJob:
-					num, err := strconv.ParseInt(job.Payload.(string), 10, 64)
-					if err != nil {
-						log.Println(err)
-						break
-					}
-					bot.processTransition(game.Phase(num), dgsRequest)
+		if connected {
+			dgs.Linked = true
 
-				case task.PlayerJob:
-					var player game.Player
-					err = json.Unmarshal([]byte(job.Payload.(string)), &player)
-					if err != nil {
-						log.Println(err)
-						break
-					}
-					if player.Color > 17 || player.Color < 0 {
-						break
-					}
+			// ★ Capture 接続確立！
+			dgs.CaptureConnected = true
+			dgs.LastCapturePing = time.Now().Unix()
+		} else {
+			dgs.Linked = false
 
-					shouldHandleTracked, userID, readOnlyDgs, err := bot.processPlayer(sett, player, dgsRequest)
-					if shouldHandleTracked {
-						bot.handleTrackedMembers(bot.PrimarySession, sett, 0, NoPriority, dgsRequest)
-					}
-					if err != nil {
-						bot.PrimarySession.ChannelMessageSend(readOnlyDgs.GameStateMsg.MessageChannelID, sett.LocalizeMessage(&i18n.Message{
-							ID:    "processplayer.error",
-							Other: "Error in muting or deafening {{.User}}. Does the bot have permissions to mute/deafen users in {{.VoiceChannel}}?",
-						},
-							map[string]interface{}{
-								"User":         discord.MentionByUserID(userID),
-								"VoiceChannel": discord.MentionByChannelID(readOnlyDgs.VoiceChannel),
-							},
-						))
-						server.RecordDiscordRequests(bot.RedisInterface.client, server.MessageCreateDelete, 1)
-					}
-					correlatedUserID = userID
+			// ★ Capture 切断
+			dgs.CaptureConnected = false
+			dgs.LastCapturePing = time.Now().Unix()
+		}
 
-				case task.GameOverJob:
-					var gameOverResult game.Gameover
-					err := json.Unmarshal([]byte(job.Payload.(string)), &gameOverResult)
-					if err != nil {
-						log.Println(err)
-						break
-					}
+		dgs.ConnectCode = connectCode
+		bot.RedisInterface.SetDiscordGameState(dgs, lock)
 
-					// we only need a read-only state for making the game summary message
-					dgs := bot.RedisInterface.GetReadOnlyDiscordGameState(dgsRequest)
-					if dgs != nil {
-						delTime := sett.GetDeleteGameSummaryMinutes()
-						if delTime != 0 {
-							winners := getWinners(*dgs, gameOverResult)
-							buf := bytes.NewBuffer([]byte{})
-							for i, v := range winners {
-								roleStr := "Crewmate"
-								if v.role == game.ImposterRole {
-									roleStr = "Imposter"
-								}
-								buf.WriteString(fmt.Sprintf("<@%s>", v.userID))
-								if i < len(winners)-1 {
-									buf.WriteRune(',')
-								} else {
-									buf.WriteString(fmt.Sprintf(" won as %s", roleStr))
-								}
-							}
-							embed := gameOverMessage(dgs, bot.StatusEmojis, sett, buf.String())
-							channelID := dgs.GameStateMsg.MessageChannelID
-							if sett.GetMatchSummaryChannelID() != "" {
-								channelID = sett.GetMatchSummaryChannelID()
-							}
-							msg, err := bot.PrimarySession.ChannelMessageSendEmbed(channelID, embed)
-							if delTime > 0 && err == nil {
-								server.RecordDiscordRequests(bot.RedisInterface.client, server.MessageCreateDelete, 2)
-								go MessageDeleteWorker(bot.PrimarySession, msg.ChannelID, msg.ID, time.Minute*time.Duration(delTime))
-							} else if err == nil {
-								server.RecordDiscordRequests(bot.RedisInterface.client, server.MessageCreateDelete, 1)
-							}
-						}
-						go dumpGameToPostgres(*dgs, bot.PostgresInterface, gameOverResult)
+		bot.handleTrackedMembers(bot.PrimarySession, sett, 0, NoPriority, dgsRequest)
 
-						// refresh the game message if the setting is marked
-						if sett.AutoRefresh {
-							bot.RefreshGameStateMessage(dgsRequest, sett)
-						}
+		// ★ 接続状態が変化した瞬間だけ「作り直し」
+		//   - false -> true ならボタン出現
+		//   - true -> false ならボタン消える（任意だけど安全）
+		if prevCapture != dgs.CaptureConnected {
+			bot.RefreshGameStateMessage(dgsRequest, sett)
+		} else {
+			bot.DispatchRefreshOrEdit(dgs, dgsRequest, sett)
+		}
+
+	// ======================================================
+	// ★ Lobby/State/Player Job でも
+	//   「ConnectionJobが来ない保険」で CaptureConnected を true にする
+	// ======================================================
+	case task.LobbyJob:
+		var lobby game.Lobby
+		lobby, decodeErr = job.DecodeLobby()
+		if decodeErr != nil {
+			break
+		}
+		bot.processLobby(sett, lobby, dgsRequest)
+
+	case task.StateJob:
+		var phase game.Phase
+		phase, decodeErr = job.DecodePhase()
+		if decodeErr != nil {
+			break
+		}
+		bot.processTransition(phase, dgsRequest)
+
+	case task.PlayerJob:
+		var player game.Player
+		player, decodeErr = job.DecodePlayer()
+		if decodeErr != nil {
+			break
+		}
+
+		shouldHandleTracked, userID, readOnlyDgs, err := bot.processPlayer(sett, player, dgsRequest)
+		if shouldHandleTracked {
+			bot.handleTrackedMembers(bot.PrimarySession, sett, 0, NoPriority, dgsRequest)
+		}
+		if err != nil {
+			bot.PrimarySession.ChannelMessageSend(readOnlyDgs.GameStateMsg.MessageChannelID, sett.LocalizeMessage(&i18n.Message{
+				ID:    "processplayer.error",
+				Other: "Error in muting or deafening {{.User}}. Does the bot have permissions to mute/deafen users in {{.VoiceChannel}}?",
+			},
+				map[string]interface{}{
+					"User":         discord.MentionByUserID(userID),
+					"VoiceChannel": discord.MentionByChannelID(readOnlyDgs.VoiceChannel),
+				},
+			))
+			server.RecordDiscordRequests(bot.RedisInterface.client, server.MessageCreateDelete, 1)
+		}
+		correlatedUserID = userID
+
+	case task.GameOverJob:
+		var gameOverResult game.Gameover
+		gameOverResult, decodeErr = job.DecodeGameOver()
+		if decodeErr != nil {
+			break
+		}
 
-						lock, dgs := bot.RedisInterface.GetDiscordGameStateAndLock(dgsRequest)
-						for lock == nil {
-							lock, dgs = bot.RedisInterface.GetDiscordGameStateAndLock(dgsRequest)
+		// we only need a read-only state for making the game summary message
+		dgs := bot.RedisInterface.GetReadOnlyDiscordGameState(dgsRequest)
+		if dgs != nil {
+			delTime := sett.GetDeleteGameSummaryMinutes()
+			if delTime != 0 {
+				winners := getWinners(*dgs, gameOverResult)
+				buf := bytes.NewBuffer([]byte{})
+				for i, v := range winners {
+					buf.WriteString(fmt.Sprintf("<@%s>", v.userID))
+					// modded games can have winners from more than one team, like a neutral alongside the crew
+					if i < len(winners)-1 && winners[i+1].roleString() == v.roleString() {
+						buf.WriteRune(',')
+					} else {
+						buf.WriteString(fmt.Sprintf(" won as %s", v.roleString()))
+						if i < len(winners)-1 {
+							buf.WriteRune('\n')
 						}
-						dgs.MatchID = -1
-						dgs.MatchStartUnix = -1
-						bot.RedisInterface.SetDiscordGameState(dgs, lock)
 					}
 				}
-
-				if job.JobType != task.ConnectionJob {
-					go func(userID string, ge storage.PostgresGameEvent) {
-						dgs := bot.RedisInterface.GetReadOnlyDiscordGameState(dgsRequest)
-						if dgs != nil && dgs.MatchID > 0 && dgs.MatchStartUnix > 0 {
-							ge.GameID = dgs.MatchID
-							if userID != "" {
-								num, err := strconv.ParseUint(userID, 10, 64)
-								if err != nil {
-									log.Println(err)
-									ge.UserID = nil
-								} else {
-									ge.UserID = &num
-								}
-								log.Printf("Adding postgres event with user id %d\n", ge.UserID)
-							}
-
-							err := bot.PostgresInterface.AddEvent(&ge)
-							if err != nil {
-								log.Println(err)
-							}
-						}
-					}(correlatedUserID, gameEvent)
+				embed := gameOverMessage(dgs, bot.StatusEmojis, sett, buf.String())
+				channelID := dgs.GameStateMsg.MessageChannelID
+				if sett.GetMatchSummaryChannelID() != "" {
+					channelID = sett.GetMatchSummaryChannelID()
+				}
+				msg, err := bot.PrimarySession.ChannelMessageSendEmbed(channelID, embed)
+				if delTime > 0 && err == nil {
+					server.RecordDiscordRequests(bot.RedisInterface.client, server.MessageCreateDelete, 2)
+					go MessageDeleteWorker(bot.PrimarySession, msg.ChannelID, msg.ID, time.Minute*time.Duration(delTime))
+				} else if err == nil {
+					server.RecordDiscordRequests(bot.RedisInterface.client, server.MessageCreateDelete, 1)
 				}
 			}
+			go dumpGameToPostgres(*dgs, bot.PostgresInterface, gameOverResult)
+			bot.fireWebhook(sett, dgs, webhook.GameOver, webhookGameOver(dgs, gameOverResult))
 
-		case <-timer.C:
-			timer.Stop()
-			log.Printf("Killing game w/ code %s after %d seconds of inactivity!\n", connectCode, bot.captureTimeout)
-			err := notify.Close()
-			if err != nil {
-				log.Println(err)
+			// refresh the game message if the setting is marked
+			if sett.AutoRefresh {
+				bot.RefreshGameStateMessage(dgsRequest, sett)
 			}
-			go bot.forceEndGame(dgsRequest)
-			bot.ChannelsMapLock.Lock()
-			delete(bot.EndGameChannels, connectCode)
-			bot.ChannelsMapLock.Unlock()
 
-			return
-		case <-endGameChannel:
-			log.Println("Redis subscriber received kill signal, closing all pubsubs")
-			err := notify.Close()
-			if err != nil {
-				log.Println(err)
+			lock, dgs := bot.RedisInterface.GetDiscordGameStateAndLock(dgsRequest)
+			for lock == nil {
+				lock, dgs = bot.RedisInterface.GetDiscordGameStateAndLock(dgsRequest)
+			}
+			dgs.MatchID = -1
+			dgs.MatchStartUnix = -1
+			dgs.revealTeams(gameOverResult)
+			bot.RedisInterface.SetDiscordGameState(dgs, lock)
+
+			// the game over can come after the move back to the lobby, whose rules can depend on the revealed roles
+			if dgs.GameData.GetPhase() == game.LOBBY {
+				bot.handleTrackedMembers(bot.PrimarySession, sett, 0, NoPriority, dgsRequest)
 			}
-			bot.forceEndGame(dgsRequest)
-			return
 		}
 	}
+
+	if decodeErr != nil {
+		log.Printf("Discarding job %s of type %d for %s: %s\n", job.ID, job.JobType, connectCode, decodeErr)
+		server.RecordCaptureEvents(bot.RedisInterface.client, server.InvalidCapturePayload, 1)
+	} else if captureAlive {
+		bot.restoreCapture(dgsRequest, sett)
+	}
+	if decodeErr == nil && job.JobType != task.ConnectionJob {
+		go func(userID string, ge storage.PostgresGameEvent) {
+			dgs := bot.RedisInterface.GetReadOnlyDiscordGameState(dgsRequest)
+			if dgs != nil && dgs.MatchID > 0 && dgs.MatchStartUnix > 0 {
+				ge.GameID = dgs.MatchID
+				if userID != "" {
+					num, err := strconv.ParseUint(userID, 10, 64)
+					if err != nil {
+						log.Println(err)
+						ge.UserID = nil
+					} else {
+						ge.UserID = &num
+					}
+					log.Printf("Adding postgres event with user id %d\n", ge.UserID)
+				}
+
+				err := bot.PostgresInterface.AddEvent(&ge)
+				if err != nil {
+					log.Println(err)
+				}
+			}
+		}(correlatedUserID, gameEvent)
+	}
+
+	bot.ackJob(connectCode, job)
+}
+
+// ackJob is only called once the job has been applied (or discarded); if we crash before this, another shard can claim it
+func (bot *Bot) ackJob(connectCode string, job task.Job) {
+	err := task.AckJob(ctx, bot.RedisInterface.client, connectCode, job)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// reorderDeadline fires once the jobs held back by the sequencer have waited long enough for the ones before them
+func reorderDeadline(sequencer *task.Sequencer) <-chan time.Time {
+	deadline, ok := sequencer.Deadline()
+	if !ok {
+		return nil
+	}
+	return time.After(time.Until(deadline))
 }
 
 type winnerRecord struct {
 	userID string
 	role   game.GameRole
+	// roleName is the modded role, if the capture sent one
+	roleName string
 }
 
+func (w winnerRecord) roleString() string {
+	if w.roleName != "" {
+		return w.roleName
+	}
+	switch w.role {
+	case game.ImposterRole:
+		return "Imposter"
+	case game.NeutralRole:
+		return "Neutral"
+	case game.HiderRole:
+		return "Hider"
+	case game.SeekerRole:
+		return "Seeker"
+	default:
+		return "Crewmate"
+	}
+}
+
+// getWinners returns the linked users that won, grouped by the role they won as
 func getWinners(dgs GameState, gameOver game.Gameover) []winnerRecord {
 	var winners []winnerRecord
 
-	imposterWin := gameOver.GameOverReason == game.ImpostorByKill ||
-		gameOver.GameOverReason == game.ImpostorByVote ||
-		gameOver.GameOverReason == game.ImpostorBySabotage ||
-		gameOver.GameOverReason == game.ImpostorDisconnect
-
 	for _, player := range dgs.UserData {
 		if player.GetPlayerName() != amongus.UnlinkedPlayerName {
-			for _, v := range gameOver.PlayerInfos {
-				// only override for the imposters
-				if player.GetPlayerName() == v.Name {
-					if (v.IsImpostor && imposterWin) || (!v.IsImpostor && !imposterWin) {
-						role := game.CrewmateRole
-						if v.IsImpostor {
-							role = game.ImposterRole
-						}
-						winners = append(winners, winnerRecord{
-							userID: player.User.UserID,
-							role:   role,
-						})
-					}
-				}
+			info, found := gameOver.GetPlayerInfo(player.GetPlayerName())
+			if found && gameOver.IsWinner(info) {
+				winners = append(winners, winnerRecord{
+					userID:   player.User.UserID,
+					role:     info.GetRole().InMode(dgs.GameData.GetGameMode()),
+					roleName: info.Role,
+				})
 			}
 		}
 	}
+	sort.Slice(winners, func(i, j int) bool {
+		if winners[i].roleString() != winners[j].roleString() {
+			return winners[i].roleString() < winners[j].roleString()
+		}
+		return winners[i].userID < winners[j].userID
+	})
 	return winners
 }
 
@@ -332,6 +427,11 @@ func (bot *Bot) processPlayer(sett *settings.GuildSettings, player game.Player,
 		if player.Disconnected || player.Action == game.LEFT {
 			if player.Disconnected {
 				log.Println("I detected that " + player.Name + " disconnected, I'm purging their player data!")
+				if data, ok := dgs.GameData.GetByName(player.Name); ok {
+					if unlinked := webhookPlayer(dgs, data); unlinked.UserID != "" {
+						bot.fireWebhook(sett, dgs, webhook.PlayerUnlink, unlinked)
+					}
+				}
 				dgs.ClearPlayerDataByPlayerName(player.Name)
 			}
 			_, _, data := dgs.GameData.UpdatePlayer(player)
@@ -345,6 +445,9 @@ func (bot *Bot) processPlayer(sett *settings.GuildSettings, player game.Player,
 			} else {
 				err = bot.applyToSingle(dgs, userID, false, false)
 			}
+			if userID != "" {
+				bot.RedisInterface.LogLink(dgs, userID)
+			}
 
 			dgs.GameData.ClearPlayerData(player.Name)
 
@@ -365,15 +468,33 @@ func (bot *Bot) processPlayer(sett *settings.GuildSettings, player game.Player,
 				uids, err = bot.RedisInterface.GetUsernameOrUserIDMappings(dgs.GuildID, player.Name)
 				userID = dgs.AttemptPairingByUserIDs(data, uids)
 			}
+			if userID != "" {
+				bot.RedisInterface.LogLink(dgs, userID)
+				bot.fireWebhook(sett, dgs, webhook.PlayerLink, webhookPlayer(dgs, data))
+			}
 			bot.DispatchRefreshOrEdit(dgs, dgsRequest, sett)
 			return true, userID, dgs, err
 		case updated:
+			wasLinked := webhookPlayer(dgs, data).UserID != ""
 			userID := dgs.AttemptPairingByMatchingNames(data)
 			if userID == "" {
 				var uids map[string]interface{}
 				uids, err = bot.RedisInterface.GetUsernameOrUserIDMappings(dgs.GuildID, player.Name)
 				userID = dgs.AttemptPairingByUserIDs(data, uids)
 			}
+			if userID != "" {
+				bot.RedisInterface.LogLink(dgs, userID)
+				if !wasLinked {
+					bot.fireWebhook(sett, dgs, webhook.PlayerLink, webhookPlayer(dgs, data))
+				}
+			}
+			if isAliveUpdated && !data.IsAlive {
+				event := webhook.PlayerDeath
+				if player.Action == game.EXILED {
+					event = webhook.PlayerExile
+				}
+				bot.fireWebhook(sett, dgs, event, webhookPlayer(dgs, data))
+			}
 			if isAliveUpdated && dgs.GameData.GetPhase() == game.TASKS {
 				if sett.GetUnmuteDeadDuringTasks() || player.Action == game.EXILED {
 					bot.DispatchRefreshOrEdit(dgs, dgsRequest, sett)
@@ -400,6 +521,7 @@ func (bot *Bot) processTransition(phase game.Phase, dgsRequest GameStateRequest)
 	for lock == nil {
 		lock, dgs = bot.RedisInterface.GetDiscordGameStateAndLock(dgsRequest)
 	}
+	dgs.applyGameSettings(sett)
 
 	// ★ 追加: ConnectionJobが来ない場合の保険（Transition来た=Capture接続済み）
 	initialConnect := false
@@ -419,15 +541,29 @@ func (bot *Bot) processTransition(phase game.Phase, dgsRequest GameStateRequest)
 	dgs.Linked = true
 	// if we started a new game
 	if oldPhase == game.LOBBY && phase == game.TASKS {
+		dgs.RevealedTeams = nil
+		dgs.clearSpectators()
 		matchStart := time.Now().Unix()
 		dgs.MatchStartUnix = matchStart
 		gameID := startGameInPostgres(*dgs, bot.PostgresInterface)
 		dgs.MatchID = int64(gameID)
 		log.Printf("New match has begun. ID %d and starttime %d\n", gameID, matchStart)
+		bot.RedisInterface.AppendGameLog(dgs.GuildID, dgs.ConnectCode, GameLogEntry{Match: &GameLogMatch{
+			MatchID:        dgs.MatchID,
+			MatchStartUnix: dgs.MatchStartUnix,
+		}})
 	}
 
 	bot.RedisInterface.SetDiscordGameState(dgs, lock)
 
+	if oldPhase == game.LOBBY && phase == game.TASKS {
+		bot.fireWebhook(sett, dgs, webhook.GameStart, webhookPlayers(dgs))
+	}
+	bot.fireWebhook(sett, dgs, webhook.PhaseChange, webhook.PhaseData{
+		From: string(oldPhase.ToString()),
+		To:   string(phase.ToString()),
+	})
+
 	// ★ 初回接続ならここで1回 Refresh（ボタン付与）
 	if initialConnect {
 		bot.RefreshGameStateMessage(dgsRequest, sett)
@@ -440,6 +576,8 @@ func (bot *Bot) processTransition(phase game.Phase, dgsRequest GameStateRequest)
 		if err != nil {
 			log.Println("Error in unmuting all users when returning to menu ", err)
 		}
+		bot.clearGameRoles(dgsRequest)
+		bot.restoreNicknames(dgsRequest)
 	case game.GAMEOVER:
 		phase = game.LOBBY
 		fallthrough
@@ -488,6 +626,7 @@ func (bot *Bot) processLobby(sett *settings.GuildSettings, lobby game.Lobby, dgs
 	}
 
 	dgs.GameData.SetRoomRegionMap(lobby.LobbyCode, lobby.Region.ToString(), lobby.PlayMap)
+	dgs.GameData.SetGameMode(lobby.GameMode)
 	bot.RedisInterface.SetDiscordGameState(dgs, lock)
 
 	// ★ 初回接続なら Refresh（ボタン付与）
@@ -499,7 +638,8 @@ func (bot *Bot) processLobby(sett *settings.GuildSettings, lobby game.Lobby, dgs
 }
 
 func startGameInPostgres(dgs GameState, psql *storage.PsqlInterface) uint64 {
-	if dgs.MatchStartUnix < 0 {
+	// replays run without a database
+	if dgs.MatchStartUnix < 0 || psql.Pool == nil {
 		return 0
 	}
 	gid, err := strconv.ParseUint(dgs.GuildID, 10, 64)
@@ -514,6 +654,7 @@ func startGameInPostgres(dgs GameState, psql *storage.PsqlInterface) uint64 {
 		StartTime:   int32(dgs.MatchStartUnix),
 		WinType:     -1,
 		EndTime:     -1,
+		GameMode:    int16(dgs.GameData.GetGameMode()),
 	}
 	i, err := psql.AddInitialGame(pgame)
 	if err != nil {
@@ -527,15 +668,13 @@ func dumpGameToPostgres(dgs GameState, psql *storage.PsqlInterface, gameOver gam
 		log.Println("dgs match id or start time is <0; not dumping game to Postgres")
 		return
 	}
+	if psql.Pool == nil {
+		return
+	}
 	end := time.Now().Unix()
 
 	userGames := make([]*storage.PostgresUserGame, 0)
 
-	imposterWin := gameOver.GameOverReason == game.ImpostorByKill ||
-		gameOver.GameOverReason == game.ImpostorBySabotage ||
-		gameOver.GameOverReason == game.ImpostorByVote ||
-		gameOver.GameOverReason == game.ImpostorDisconnect
-
 	for _, v := range dgs.UserData {
 		if v.GetPlayerName() != amongus.UnlinkedPlayerName {
 			inGameData, found := dgs.GameData.GetByName(v.GetPlayerName())
@@ -561,16 +700,11 @@ func dumpGameToPostgres(dgs GameState, psql *storage.PsqlInterface, gameOver gam
 				continue
 			}
 
-			won := !imposterWin
-			role := game.CrewmateRole
-			for _, pi := range gameOver.PlayerInfos {
-				if pi.IsImpostor {
-					if strings.EqualFold(pi.Name, inGameData.Name) {
-						role = game.ImposterRole
-						won = imposterWin
-						break
-					}
-				}
+			// players the capture didn't report on are counted as crewmates, like they always have been
+			info, _ := gameOver.GetPlayerInfo(inGameData.Name)
+			roleName := []rune(info.Role)
+			if len(roleName) > storage.MaxRoleNameLength {
+				roleName = roleName[:storage.MaxRoleNameLength]
 			}
 
 			userGames = append(userGames, &storage.PostgresUserGame{
@@ -579,14 +713,15 @@ func dumpGameToPostgres(dgs GameState, psql *storage.PsqlInterface, gameOver gam
 				GameID:      dgs.MatchID,
 				PlayerName:  inGameData.Name,
 				PlayerColor: int16(inGameData.Color),
-				PlayerRole:  int16(role),
-				PlayerWon:   won,
+				PlayerRole:  int16(info.GetRole().InMode(dgs.GameData.GetGameMode())),
+				PlayerWon:   gameOver.IsWinner(info),
+				RoleName:    string(roleName),
 			})
 		}
 	}
 	log.Printf("Game %d has been completed and recorded in postgres\n", dgs.MatchID)
 
-	err := psql.UpdateGameAndPlayers(dgs.MatchID, int16(gameOver.GameOverReason), end, userGames)
+	err := psql.UpdateGameAndPlayers(dgs.MatchID, int16(gameOver.GameOverReason), end, gameOver.NeutralWon(), userGames)
 	if err != nil {
 		log.Println(err)
 	}
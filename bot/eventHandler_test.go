@@ -0,0 +1,79 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/automuteus/automuteus/v8/pkg/game"
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestGetWinners(t *testing.T) {
+	dgs := NewDiscordGameState("100")
+	for i, name := range []string{"Red", "Blue", "Green"} {
+		player := game.Player{Action: game.JOINED, Name: name, Color: i}
+		dgs.GameData.UpdatePlayer(player)
+		userID := string(rune('1' + i))
+		userData := MakeUserDataFromDiscordUser(&discordgo.User{ID: userID, Username: name}, "")
+		data, _ := dgs.GameData.GetByName(name)
+		userData.Link(data)
+		dgs.UpdateUserData(userID, userData)
+	}
+	won := true
+
+	tests := []struct {
+		name     string
+		gameOver game.Gameover
+		expected []winnerRecord
+	}{
+		{
+			name: "vanilla crewmate win",
+			gameOver: game.Gameover{GameOverReason: game.HumansByTask, PlayerInfos: []game.PlayerInfo{
+				{Name: "Red", IsImpostor: true}, {Name: "Blue"}, {Name: "Green"},
+			}},
+			expected: []winnerRecord{{userID: "2", role: game.CrewmateRole}, {userID: "3", role: game.CrewmateRole}},
+		},
+		{
+			name: "neutrals lose a vanilla game",
+			gameOver: game.Gameover{GameOverReason: game.ImpostorByKill, PlayerInfos: []game.PlayerInfo{
+				{Name: "red", IsImpostor: true, Role: "Morphling", Team: "Impostor"}, {Name: "Blue", Role: "Jester", Team: game.NeutralTeam}, {Name: "Green"},
+			}},
+			expected: []winnerRecord{{userID: "1", role: game.ImposterRole, roleName: "Morphling"}},
+		},
+		{
+			name: "neutral team win",
+			gameOver: game.Gameover{GameOverReason: game.Unknown, WinningTeam: game.NeutralTeam, PlayerInfos: []game.PlayerInfo{
+				{Name: "Red", IsImpostor: true}, {Name: "Blue", Role: "Jester", Team: game.NeutralTeam}, {Name: "Green"},
+			}},
+			expected: []winnerRecord{{userID: "2", role: game.NeutralRole, roleName: "Jester"}},
+		},
+		{
+			name: "explicit winners across teams",
+			gameOver: game.Gameover{GameOverReason: game.HumansByVote, PlayerInfos: []game.PlayerInfo{
+				{Name: "Red", IsImpostor: true}, {Name: "Blue", Team: game.NeutralTeam, IsWinner: &won}, {Name: "Green", IsWinner: &won},
+			}},
+			expected: []winnerRecord{{userID: "3", role: game.CrewmateRole}, {userID: "2", role: game.NeutralRole}},
+		},
+	}
+	for _, test := range tests {
+		winners := getWinners(*dgs, test.gameOver)
+		if len(winners) != len(test.expected) {
+			t.Errorf("%s: expected winners %+v, got %+v", test.name, test.expected, winners)
+			continue
+		}
+		for i, v := range test.expected {
+			if winners[i] != v {
+				t.Errorf("%s: expected winners %+v, got %+v", test.name, test.expected, winners)
+				break
+			}
+		}
+	}
+
+	// Hide and Seek rounds are recorded with their own roles
+	dgs.GameData.SetGameMode(game.HideAndSeekMode)
+	winners := getWinners(*dgs, game.Gameover{GameOverReason: game.ImpostorByKill, PlayerInfos: []game.PlayerInfo{
+		{Name: "Red", IsImpostor: true}, {Name: "Blue"}, {Name: "Green"},
+	}})
+	if len(winners) != 1 || winners[0].role != game.SeekerRole || winners[0].roleString() != "Seeker" {
+		t.Errorf("expected Red to win as the seeker, got %+v", winners)
+	}
+}
@@ -0,0 +1,27 @@
+package bot
+
+import (
+	"github.com/automuteus/automuteus/v8/pkg/settings"
+	"github.com/bwmarrin/discordgo"
+)
+
+// memberIsExempt is whether the guild wants the bot to leave the member's mute and deafen alone
+func memberIsExempt(sess *discordgo.Session, sett *settings.GuildSettings, guildID, userID string) bool {
+	if len(sett.GetExemptRoleIDs()) == 0 {
+		return sett.IsExempt(userID, nil)
+	}
+	var roles []string
+	if member, err := sess.State.Member(guildID, userID); err == nil {
+		roles = member.Roles
+	}
+	return sett.IsExempt(userID, roles)
+}
+
+// updateExempt records whether the user is exempt on their data, for the game state message
+func (dgs *GameState) updateExempt(userData UserData, exempt bool) UserData {
+	if userData.Exempt != exempt && userData.User.UserID != "" {
+		userData.Exempt = exempt
+		dgs.UpdateUserData(userData.User.UserID, userData)
+	}
+	return userData
+}
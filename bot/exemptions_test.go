@@ -0,0 +1,70 @@
+package bot
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/automuteus/automuteus/v8/pkg/game"
+	"github.com/automuteus/automuteus/v8/pkg/settings"
+	"github.com/automuteus/automuteus/v8/pkg/task"
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestExemptMembers(t *testing.T) {
+	const guildID = "100"
+	const voiceChannelID = "200"
+
+	mr := miniredis.RunT(t)
+	stopClock := runReplayClock(mr)
+	defer stopClock()
+
+	dgs := NewDiscordGameState(guildID)
+	dgs.ConnectCode = "ABCD1234"
+	dgs.VoiceChannel = voiceChannelID
+	dgs.Running = true
+	dgs.GameData.UpdatePhase(game.LOBBY)
+	dgs.GameData.UpdatePhase(game.TASKS)
+
+	var members []*discordgo.Member
+	var voiceStates []*discordgo.VoiceState
+	for i, name := range []string{"Red", "Blue"} {
+		userID := string(rune('1' + i))
+		dgs.GameData.UpdatePlayer(game.Player{Action: game.JOINED, Name: name, Color: i})
+		member := &discordgo.Member{User: &discordgo.User{ID: userID, Username: name}}
+		userData := MakeUserDataFromDiscordUser(member.User, "")
+		player, _ := dgs.GameData.GetByName(name)
+		userData.Link(player)
+		dgs.UpdateUserData(userID, userData)
+		members = append(members, member)
+		voiceStates = append(voiceStates, &discordgo.VoiceState{GuildID: guildID, UserID: userID, ChannelID: voiceChannelID})
+	}
+
+	transport := &replayTransport{}
+	bot, err := newReplayBot(mr.Addr(), transport, &RecordSnapshot{
+		GameState:   dgs,
+		VoiceStates: voiceStates,
+		Members:     members,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bot.RedisInterface.Close()
+	defer bot.StorageInterface.Close()
+
+	sett := settings.MakeGuildSettings()
+	sett.ToggleExemptUser("2")
+	bot.RedisInterface.SetDiscordGameState(dgs, nil)
+	gsr := GameStateRequest{GuildID: guildID, ConnectCode: dgs.ConnectCode}
+
+	bot.handleTrackedMembers(bot.PrimarySession, sett, 0, NoPriority, gsr)
+
+	expected := []task.UserModify{{UserID: 1, Mute: true, Deaf: true}}
+	if modifies := transport.drain(); !reflect.DeepEqual(modifies, expected) {
+		t.Errorf("expected only Red to be muted, got %+v", modifies)
+	}
+	state := bot.RedisInterface.GetReadOnlyDiscordGameState(gsr)
+	if userData, _ := state.GetUser("2"); !userData.Exempt {
+		t.Error("expected Blue to be marked as exempt")
+	}
+}
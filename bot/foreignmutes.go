@@ -0,0 +1,72 @@
+package bot
+
+import (
+	"log"
+
+	"github.com/automuteus/automuteus/v8/internal/server"
+	"github.com/automuteus/automuteus/v8/pkg/discord"
+	"github.com/automuteus/automuteus/v8/pkg/settings"
+	"github.com/bwmarrin/discordgo"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// voiceConflict is a user someone other than the bot muted or deafened
+type voiceConflict struct {
+	UserID string
+	Mute   bool
+	Deaf   bool
+}
+
+// trackForeignMuteDeaf notes any server mute or deafen on the user that the bot didn't apply itself, going by what it
+// last gave them and how the voice state changed (before is nil for a user who hasn't been seen yet). A foreign
+// mute/deafen is forgotten once it's lifted. Returns the mute and deafen that are newly foreign
+func trackForeignMuteDeaf(userData UserData, voiceState, before *discordgo.VoiceState) (UserData, bool, bool) {
+	var mute, deaf bool
+	if !voiceState.Mute {
+		userData.ForeignMute = false
+	} else if !userData.ShouldBeMute && !userData.ForeignMute && (before == nil || !before.Mute) {
+		userData.ForeignMute = true
+		mute = true
+	}
+	if !voiceState.Deaf {
+		userData.ForeignDeaf = false
+	} else if !userData.ShouldBeDeaf && !userData.ForeignDeaf && (before == nil || !before.Deaf) {
+		userData.ForeignDeaf = true
+		deaf = true
+	}
+	return userData, mute, deaf
+}
+
+// logVoiceConflict tells the guild's conflict log channel, if it has one, that someone else muted or deafened a player
+func (bot *Bot) logVoiceConflict(sett *settings.GuildSettings, userID string, mute, deaf bool) {
+	channelID := sett.GetConflictLogChannelID()
+	if channelID == "" || (!mute && !deaf) {
+		return
+	}
+	var msg string
+	data := map[string]interface{}{
+		"User": discord.MentionByUserID(userID),
+	}
+	switch {
+	case mute && deaf:
+		msg = sett.LocalizeMessage(&i18n.Message{
+			ID:    "voiceConflict.muteDeaf",
+			Other: "{{.User}} was server muted and deafened by someone else; I'll leave them that way until it's lifted",
+		}, data)
+	case mute:
+		msg = sett.LocalizeMessage(&i18n.Message{
+			ID:    "voiceConflict.mute",
+			Other: "{{.User}} was server muted by someone else; I'll leave them muted until it's lifted",
+		}, data)
+	default:
+		msg = sett.LocalizeMessage(&i18n.Message{
+			ID:    "voiceConflict.deaf",
+			Other: "{{.User}} was server deafened by someone else; I'll leave them deafened until it's lifted",
+		}, data)
+	}
+	_, err := bot.PrimarySession.ChannelMessageSend(channelID, msg)
+	if err != nil {
+		log.Println(err)
+	}
+	server.RecordDiscordRequests(bot.RedisInterface.client, server.MessageCreateDelete, 1)
+}
@@ -0,0 +1,100 @@
+package bot
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/automuteus/automuteus/v8/pkg/game"
+	"github.com/automuteus/automuteus/v8/pkg/settings"
+	"github.com/automuteus/automuteus/v8/pkg/task"
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestTrackForeignMuteDeaf(t *testing.T) {
+	userData := UserData{}
+
+	// muted by the bot
+	userData.SetShouldBeMuteDeaf(true, false)
+	userData, mute, deaf := trackForeignMuteDeaf(userData, &discordgo.VoiceState{Mute: true}, &discordgo.VoiceState{})
+	if mute || deaf || userData.ForeignMute {
+		t.Error("expected the bot's own mute not to be foreign")
+	}
+
+	// unmuted by the bot, then muted by a moderator
+	userData.SetShouldBeMuteDeaf(false, false)
+	userData, mute, deaf = trackForeignMuteDeaf(userData, &discordgo.VoiceState{Mute: true, Deaf: true}, &discordgo.VoiceState{})
+	if !mute || !deaf || !userData.ForeignMute || !userData.ForeignDeaf {
+		t.Error("expected a mute and deafen by someone else to be foreign")
+	}
+	if m, d := userData.KeepForeignMuteDeaf(false, false); !m || !d {
+		t.Error("expected the foreign mute and deafen to be kept")
+	}
+
+	// the moderator lifts the deafen only
+	userData, mute, deaf = trackForeignMuteDeaf(userData, &discordgo.VoiceState{Mute: true}, &discordgo.VoiceState{Mute: true, Deaf: true})
+	if mute || deaf || !userData.ForeignMute || userData.ForeignDeaf {
+		t.Error("expected only the foreign deafen to be forgotten")
+	}
+}
+
+func TestForeignMutesKept(t *testing.T) {
+	const guildID = "100"
+	const voiceChannelID = "200"
+
+	mr := miniredis.RunT(t)
+	stopClock := runReplayClock(mr)
+	defer stopClock()
+
+	dgs := NewDiscordGameState(guildID)
+	dgs.ConnectCode = "ABCD1234"
+	dgs.VoiceChannel = voiceChannelID
+	dgs.Running = true
+	dgs.GameData.UpdatePhase(game.LOBBY)
+	dgs.GameData.UpdatePhase(game.TASKS)
+
+	var members []*discordgo.Member
+	var voiceStates []*discordgo.VoiceState
+	for i, name := range []string{"Red", "Blue"} {
+		userID := string(rune('1' + i))
+		dgs.GameData.UpdatePlayer(game.Player{Action: game.JOINED, Name: name, Color: i})
+		member := &discordgo.Member{User: &discordgo.User{ID: userID, Username: name}}
+		userData := MakeUserDataFromDiscordUser(member.User, "")
+		player, _ := dgs.GameData.GetByName(name)
+		userData.Link(player)
+		userData.SetShouldBeMuteDeaf(true, true)
+		dgs.UpdateUserData(userID, userData)
+		members = append(members, member)
+		voiceStates = append(voiceStates, &discordgo.VoiceState{GuildID: guildID, UserID: userID, ChannelID: voiceChannelID, Mute: true, Deaf: true})
+	}
+	// a moderator has Blue muted as well
+	blue, _ := dgs.GetUser("2")
+	blue.ForeignMute = true
+	dgs.UpdateUserData("2", blue)
+
+	transport := &replayTransport{}
+	bot, err := newReplayBot(mr.Addr(), transport, &RecordSnapshot{
+		GameState:   dgs,
+		VoiceStates: voiceStates,
+		Members:     members,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bot.RedisInterface.Close()
+	defer bot.StorageInterface.Close()
+
+	dgs.GameData.UpdatePhase(game.DISCUSS)
+	bot.RedisInterface.SetDiscordGameState(dgs, nil)
+	gsr := GameStateRequest{GuildID: guildID, ConnectCode: dgs.ConnectCode}
+
+	bot.handleTrackedMembers(bot.PrimarySession, settings.MakeGuildSettings(), 0, NoPriority, gsr)
+
+	expected := []task.UserModify{
+		{UserID: 1, Mute: false, Deaf: false},
+		{UserID: 2, Mute: true, Deaf: false},
+	}
+	if modifies := transport.drain(); !reflect.DeepEqual(modifies, expected) {
+		t.Errorf("expected Blue to stay muted, got %+v", modifies)
+	}
+}
@@ -0,0 +1,316 @@
+package bot
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/automuteus/automuteus/v8/pkg/amongus"
+	"github.com/automuteus/automuteus/v8/pkg/game"
+	"github.com/automuteus/automuteus/v8/pkg/rediskey"
+	"github.com/automuteus/automuteus/v8/pkg/task"
+)
+
+// Alongside the GameState snapshot, every game keeps an append-only log in rediskey.GameLog: the state as it was when
+// the game was started, then every job the subscriber applied, every link/unlink, every change to the bot's own
+// per-user state (roles, nicknames, foreign mutes, spectators) and to whether the capture was lost, in order. If the
+// snapshot is flushed or evicted mid-game, folding the log gives all of that back. Anything that isn't in the log (who
+// should currently be muted, exemptions, the latest status message) is recomputed by the bot as the game goes on
+
+// GameLogEntry has exactly one of its fields set
+type GameLogEntry struct {
+	Time int64 `json:"time"`
+
+	// Game is the state as it was when the game was (re)started; rebuilding starts from the latest one
+	Game        *GameState        `json:"game,omitempty"`
+	Job         *task.Job         `json:"job,omitempty"`
+	Link        *GameLogLink      `json:"link,omitempty"`
+	Unlink      string            `json:"unlink,omitempty"`
+	Match       *GameLogMatch     `json:"match,omitempty"`
+	UserState   *GameLogUserState `json:"userState,omitempty"`
+	CaptureLost *bool             `json:"captureLost,omitempty"`
+}
+
+type GameLogLink struct {
+	User       User   `json:"user"`
+	PlayerName string `json:"playerName"`
+}
+
+type GameLogMatch struct {
+	MatchID        int64 `json:"matchID"`
+	MatchStartUnix int64 `json:"matchStartUnix"`
+}
+
+// GameLogUserState is the part of a user's data that the bot keeps for itself, rather than getting from the capture
+type GameLogUserState struct {
+	UserID           string `json:"userID"`
+	GameRoleID       string `json:"gameRoleID,omitempty"`
+	SyncedNick       string `json:"syncedNick,omitempty"`
+	OriginalNick     string `json:"originalNick,omitempty"`
+	NickUnmanageable bool   `json:"nickUnmanageable,omitempty"`
+	ForeignMute      bool   `json:"foreignMute,omitempty"`
+	ForeignDeaf      bool   `json:"foreignDeaf,omitempty"`
+	Spectator        bool   `json:"spectator,omitempty"`
+}
+
+func makeGameLogUserState(userID string, user UserData) GameLogUserState {
+	return GameLogUserState{
+		UserID:           userID,
+		GameRoleID:       user.GameRoleID,
+		SyncedNick:       user.SyncedNick,
+		OriginalNick:     user.OriginalNick,
+		NickUnmanageable: user.NickUnmanageable,
+		ForeignMute:      user.ForeignMute,
+		ForeignDeaf:      user.ForeignDeaf,
+		Spectator:        user.Spectator,
+	}
+}
+
+func (state GameLogUserState) applyTo(user UserData) UserData {
+	user.GameRoleID = state.GameRoleID
+	user.SyncedNick = state.SyncedNick
+	user.OriginalNick = state.OriginalNick
+	user.NickUnmanageable = state.NickUnmanageable
+	user.ForeignMute = state.ForeignMute
+	user.ForeignDeaf = state.ForeignDeaf
+	user.Spectator = state.Spectator
+	return user
+}
+
+// userStates is everyone's state as of now, for LogUserStates to compare against later
+func (dgs *GameState) userStates() map[string]GameLogUserState {
+	states := make(map[string]GameLogUserState, len(dgs.UserData))
+	for userID, user := range dgs.UserData {
+		states[userID] = makeGameLogUserState(userID, user)
+	}
+	return states
+}
+
+func (redisInterface *RedisInterface) AppendGameLog(guildID, connectCode string, entry GameLogEntry) {
+	if guildID == "" || connectCode == "" {
+		return
+	}
+	if entry.Time == 0 {
+		entry.Time = time.Now().Unix()
+	}
+	jBytes, err := json.Marshal(entry)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	key := rediskey.GameLog(guildID, connectCode)
+	err = redisInterface.client.RPush(ctx, key, jBytes).Err()
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	err = redisInterface.client.Expire(ctx, key, GameTimeoutSeconds*time.Second).Err()
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func (redisInterface *RedisInterface) GetGameLog(guildID, connectCode string) ([]GameLogEntry, error) {
+	strs, err := redisInterface.client.LRange(ctx, rediskey.GameLog(guildID, connectCode), 0, -1).Result()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]GameLogEntry, 0, len(strs))
+	for _, v := range strs {
+		var entry GameLogEntry
+		err := json.Unmarshal([]byte(v), &entry)
+		if err != nil {
+			// one bad entry shouldn't cost the whole game
+			log.Println(err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (redisInterface *RedisInterface) DeleteGameLog(guildID, connectCode string) {
+	err := redisInterface.client.Del(ctx, rediskey.GameLog(guildID, connectCode)).Err()
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// StartGameLog marks where a game (re)starts in its log
+func (redisInterface *RedisInterface) StartGameLog(dgs *GameState) {
+	redisInterface.AppendGameLog(dgs.GuildID, dgs.ConnectCode, GameLogEntry{Game: dgs})
+}
+
+// LogLink records the user's current link, or lack of one
+func (redisInterface *RedisInterface) LogLink(dgs *GameState, userID string) {
+	user, ok := dgs.UserData[userID]
+	if !ok {
+		return
+	}
+	if user.GetPlayerName() == amongus.UnlinkedPlayerName {
+		redisInterface.AppendGameLog(dgs.GuildID, dgs.ConnectCode, GameLogEntry{Unlink: userID})
+		return
+	}
+	redisInterface.AppendGameLog(dgs.GuildID, dgs.ConnectCode, GameLogEntry{Link: &GameLogLink{
+		User:       user.User,
+		PlayerName: user.GetPlayerName(),
+	}})
+}
+
+// LogUserStates records the state of everyone whose state changed since before, which is from dgs.userStates
+func (redisInterface *RedisInterface) LogUserStates(dgs *GameState, before map[string]GameLogUserState) {
+	for userID, user := range dgs.UserData {
+		state := makeGameLogUserState(userID, user)
+		old, ok := before[userID]
+		if !ok {
+			// someone new only needs logging if the bot already has state for them
+			old = GameLogUserState{UserID: userID}
+		}
+		if old == state {
+			continue
+		}
+		redisInterface.AppendGameLog(dgs.GuildID, dgs.ConnectCode, GameLogEntry{UserState: &state})
+	}
+}
+
+// LogCaptureLost records the game's current CaptureLost
+func (redisInterface *RedisInterface) LogCaptureLost(dgs *GameState) {
+	captureLost := dgs.CaptureLost
+	redisInterface.AppendGameLog(dgs.GuildID, dgs.ConnectCode, GameLogEntry{CaptureLost: &captureLost})
+}
+
+// rebuildGameState returns nil if the game has no log to rebuild from
+func (redisInterface *RedisInterface) rebuildGameState(guildID, connectCode string) *GameState {
+	if guildID == "" || connectCode == "" {
+		return nil
+	}
+	entries, err := redisInterface.GetGameLog(guildID, connectCode)
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+	dgs, err := RebuildGameState(entries)
+	if err != nil {
+		if len(entries) > 0 {
+			log.Printf("Couldn't rebuild game %s from its log: %s\n", connectCode, err)
+		}
+		return nil
+	}
+	log.Printf("Rebuilt game %s from %d log entries\n", connectCode, len(entries))
+	return dgs
+}
+
+// RebuildGameState folds a game's log, starting from the latest Game entry
+func RebuildGameState(entries []GameLogEntry) (*GameState, error) {
+	start := -1
+	for i, v := range entries {
+		if v.Game != nil {
+			start = i
+		}
+	}
+	if start == -1 {
+		return nil, errors.New("log has no game to start from")
+	}
+
+	dgs := entries[start].Game
+	if dgs.UserData == nil {
+		dgs.UserData = make(UserDataSet)
+	}
+	if dgs.GameData.PlayerData == nil {
+		dgs.GameData.PlayerData = make(map[string]amongus.PlayerData)
+	}
+	for _, entry := range entries[start+1:] {
+		switch {
+		case entry.Job != nil:
+			dgs.applyLoggedJob(*entry.Job)
+		case entry.Link != nil:
+			user, ok := dgs.UserData[entry.Link.User.UserID]
+			if !ok {
+				user = UserData{User: entry.Link.User}
+			}
+			player, found := dgs.GameData.GetByName(entry.Link.PlayerName)
+			if !found {
+				// the player can have left the game since; the link stands regardless
+				player = amongus.PlayerData{Name: entry.Link.PlayerName}
+			}
+			user.Link(player)
+			dgs.UpdateUserData(entry.Link.User.UserID, user)
+		case entry.Unlink != "":
+			dgs.ClearPlayerData(entry.Unlink)
+		case entry.Match != nil:
+			dgs.MatchID = entry.Match.MatchID
+			dgs.MatchStartUnix = entry.Match.MatchStartUnix
+		case entry.UserState != nil:
+			user, ok := dgs.UserData[entry.UserState.UserID]
+			if !ok {
+				user = UserData{User: User{UserID: entry.UserState.UserID}, InGameName: amongus.UnlinkedPlayerName}
+			}
+			dgs.UpdateUserData(entry.UserState.UserID, entry.UserState.applyTo(user))
+		case entry.CaptureLost != nil:
+			dgs.CaptureLost = *entry.CaptureLost
+		}
+	}
+	return dgs, nil
+}
+
+// applyLoggedJob mirrors what processJob does to the game's data, without any of the side effects. Links that came
+// out of a job are logged separately, right after it
+func (dgs *GameState) applyLoggedJob(job task.Job) {
+	switch job.JobType {
+	case task.ConnectionJob:
+		connected, err := job.DecodeConnection()
+		if err != nil {
+			return
+		}
+		dgs.Linked = connected
+		dgs.CaptureConnected = connected
+		dgs.CaptureDisconnected = !connected
+	case task.LobbyJob:
+		lobby, err := job.DecodeLobby()
+		if err != nil {
+			return
+		}
+		dgs.CaptureConnected = true
+		dgs.GameData.SetRoomRegionMap(lobby.LobbyCode, lobby.Region.ToString(), lobby.PlayMap)
+		dgs.GameData.SetGameMode(lobby.GameMode)
+	case task.StateJob:
+		phase, err := job.DecodePhase()
+		if err != nil {
+			return
+		}
+		dgs.CaptureConnected = true
+		oldPhase := dgs.GameData.UpdatePhase(phase)
+		if oldPhase != phase {
+			dgs.Linked = true
+		}
+		if oldPhase == game.LOBBY && phase == game.TASKS {
+			dgs.RevealedTeams = nil
+			dgs.clearSpectators()
+		}
+	case task.PlayerJob:
+		player, err := job.DecodePlayer()
+		if err != nil || player.Name == "" {
+			return
+		}
+		dgs.Linked = true
+		dgs.CaptureConnected = true
+		if player.Disconnected || player.Action == game.LEFT {
+			if player.Disconnected {
+				dgs.ClearPlayerDataByPlayerName(player.Name)
+			}
+			dgs.GameData.UpdatePlayer(player)
+			dgs.GameData.ClearPlayerData(player.Name)
+			return
+		}
+		dgs.GameData.UpdatePlayer(player)
+	case task.GameOverJob:
+		gameOver, err := job.DecodeGameOver()
+		if err != nil {
+			return
+		}
+		dgs.MatchID = -1
+		dgs.MatchStartUnix = -1
+		dgs.revealTeams(gameOver)
+	}
+}
@@ -0,0 +1,222 @@
+package bot
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/automuteus/automuteus/v8/pkg/amongus"
+	"github.com/automuteus/automuteus/v8/pkg/game"
+	"github.com/automuteus/automuteus/v8/pkg/rediskey"
+	"github.com/automuteus/automuteus/v8/pkg/task"
+	"github.com/automuteus/automuteus/v8/storage"
+	"github.com/bwmarrin/discordgo"
+)
+
+func logJob(jobType task.JobType, payload string) GameLogEntry {
+	return GameLogEntry{Job: &task.Job{Version: task.JobSchemaVersion, JobType: jobType, Payload: json.RawMessage(payload)}}
+}
+
+func testGameLog() []GameLogEntry {
+	dgs := NewDiscordGameState("100")
+	dgs.ConnectCode = "ABCD1234"
+	dgs.VoiceChannel = "200"
+	dgs.Running = true
+	dgs.UpdateUserData("1", MakeUserDataFromDiscordUser(&discordgo.User{ID: "1", Username: "red"}, ""))
+	dgs.UpdateUserData("2", MakeUserDataFromDiscordUser(&discordgo.User{ID: "2", Username: "blue"}, ""))
+
+	return []GameLogEntry{
+		{Game: dgs},
+		logJob(task.ConnectionJob, `true`),
+		logJob(task.StateJob, `0`),
+		logJob(task.PlayerJob, `{"Action":0,"Name":"Red","Color":0}`),
+		{Link: &GameLogLink{User: User{UserID: "1", UserName: "red"}, PlayerName: "Red"}},
+		logJob(task.PlayerJob, `{"Action":0,"Name":"Blue","Color":1}`),
+		{Link: &GameLogLink{User: User{UserID: "2", UserName: "blue"}, PlayerName: "Blue"}},
+		logJob(task.PlayerJob, `{"Action":0,"Name":"Green","Color":2}`),
+		// someone who wasn't in voice when the game started
+		{Link: &GameLogLink{User: User{UserID: "3", UserName: "green"}, PlayerName: "Green"}},
+		logJob(task.StateJob, `1`),
+		{Match: &GameLogMatch{MatchID: 7, MatchStartUnix: 1000}},
+		logJob(task.PlayerJob, `{"Action":2,"Name":"Red","Color":0,"IsDead":true}`),
+		{Unlink: "2"},
+		logJob(task.StateJob, `2`),
+	}
+}
+
+func TestRebuildGameState(t *testing.T) {
+	_, err := RebuildGameState(testGameLog()[1:])
+	if err == nil {
+		t.Error("a log without a game shouldn't be rebuilt")
+	}
+
+	dgs, err := RebuildGameState(testGameLog())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dgs.GameData.GetPhase() != game.DISCUSS || !dgs.Linked || !dgs.CaptureConnected {
+		t.Errorf("unexpected game %+v", dgs)
+	}
+	if dgs.MatchID != 7 || dgs.MatchStartUnix != 1000 {
+		t.Errorf("expected match 7 at 1000, got %d at %d", dgs.MatchID, dgs.MatchStartUnix)
+	}
+	if red, _ := dgs.GameData.GetByName("Red"); red.IsAlive {
+		t.Error("expected Red to be dead")
+	}
+	if green, _ := dgs.GameData.GetByName("Green"); !green.IsAlive {
+		t.Error("expected Green to be alive")
+	}
+
+	expected := map[string]string{
+		"1": "Red",
+		"2": amongus.UnlinkedPlayerName,
+		"3": "Green",
+	}
+	for userID, name := range expected {
+		user, err := dgs.GetUser(userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user.GetPlayerName() != name {
+			t.Errorf("expected %s to be linked to %s, got %s", userID, name, user.GetPlayerName())
+		}
+	}
+
+	// restarting the game starts the fold over
+	restarted := NewDiscordGameState("100")
+	restarted.ConnectCode = "ABCD1234"
+	dgs, err = RebuildGameState(append(testGameLog(), GameLogEntry{Game: restarted}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dgs.UserData) != 0 || dgs.GameData.GetNumDetectedPlayers() != 0 {
+		t.Errorf("expected an empty game after a restart, got %+v", dgs)
+	}
+}
+
+func TestRebuildGameStateUserStates(t *testing.T) {
+	mr := miniredis.RunT(t)
+	redisInterface := &RedisInterface{}
+	err := redisInterface.Init(storage.RedisParameters{Addr: mr.Addr()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer redisInterface.Close()
+
+	dgs, err := RebuildGameState(testGameLog())
+	if err != nil {
+		t.Fatal(err)
+	}
+	before := dgs.userStates()
+	red, _ := dgs.GetUser("1")
+	red.GameRoleID = "300"
+	red.ForeignMute = true
+	red.SyncedNick = "🟥 Red"
+	red.OriginalNick = "red"
+	dgs.UpdateUserData("1", red)
+	// a late joiner the bot only knows as a spectator
+	dgs.UpdateUserData("4", UserData{User: User{UserID: "4"}, InGameName: amongus.UnlinkedPlayerName, Spectator: true})
+	redisInterface.LogUserStates(dgs, before)
+
+	entries, err := redisInterface.GetGameLog(dgs.GuildID, dgs.ConnectCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected only the users whose state changed to be logged, got %d entries", len(entries))
+	}
+
+	rebuilt, err := RebuildGameState(append(testGameLog(), entries...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if red, _ := rebuilt.GetUser("1"); red.GameRoleID != "300" || !red.ForeignMute || red.SyncedNick != "🟥 Red" || red.OriginalNick != "red" || red.GetPlayerName() != "Red" {
+		t.Errorf("expected Red's role, foreign mute and nickname back, got %+v", red)
+	}
+	if spectator, err := rebuilt.GetUser("4"); err != nil || !spectator.Spectator {
+		t.Errorf("expected user 4 to be a spectator again, got %+v", spectator)
+	}
+
+	// the next match lets spectators go, like it did the first time
+	rebuilt, err = RebuildGameState(append(append(testGameLog(), entries...), logJob(task.StateJob, `0`), logJob(task.StateJob, `1`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if spectator, _ := rebuilt.GetUser("4"); spectator.Spectator {
+		t.Error("expected spectators to be cleared when the next match starts")
+	}
+}
+
+func TestRebuildGameStateCaptureLost(t *testing.T) {
+	mr := miniredis.RunT(t)
+	redisInterface := &RedisInterface{}
+	err := redisInterface.Init(storage.RedisParameters{Addr: mr.Addr()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer redisInterface.Close()
+
+	dgs, err := RebuildGameState(testGameLog())
+	if err != nil {
+		t.Fatal(err)
+	}
+	dgs.CaptureLost = true
+	redisInterface.LogCaptureLost(dgs)
+
+	entries, err := redisInterface.GetGameLog(dgs.GuildID, dgs.ConnectCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rebuilt, err := RebuildGameState(append(testGameLog(), entries...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rebuilt.CaptureLost {
+		t.Error("expected the capture to still be lost")
+	}
+
+	// a disconnect from the capture arms the watchdog again
+	rebuilt, err = RebuildGameState(append(append(testGameLog(), entries...), logJob(task.ConnectionJob, `false`)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rebuilt.CaptureDisconnected {
+		t.Error("expected the capture's disconnect to be kept")
+	}
+}
+
+func TestGameStateRebuiltFromLog(t *testing.T) {
+	mr := miniredis.RunT(t)
+	redisInterface := &RedisInterface{}
+	err := redisInterface.Init(storage.RedisParameters{Addr: mr.Addr()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer redisInterface.Close()
+
+	for _, entry := range testGameLog() {
+		redisInterface.AppendGameLog("100", "ABCD1234", entry)
+	}
+	gsr := GameStateRequest{GuildID: "100", ConnectCode: "ABCD1234"}
+
+	// the snapshot was never written, as if Redis had lost it
+	dgs := redisInterface.GetReadOnlyDiscordGameState(gsr)
+	if dgs == nil {
+		t.Fatal("expected the game to be rebuilt from its log")
+	}
+	if user, _ := dgs.GetUser("1"); user.GetPlayerName() != "Red" {
+		t.Errorf("expected user 1 to be linked to Red, got %s", user.GetPlayerName())
+	}
+	if !mr.Exists(rediskey.ConnectCodeData("100", "ABCD1234")) || !mr.Exists(rediskey.VoiceChannelPtr("100", "200")) {
+		t.Error("expected the rebuilt snapshot to be saved")
+	}
+
+	// once the game is deleted, it stays deleted
+	redisInterface.DeleteDiscordGameState(dgs)
+	if mr.Exists(rediskey.GameLog("100", "ABCD1234")) {
+		t.Error("expected the log to be deleted along with the game")
+	}
+	if redisInterface.getDiscordGameState(gsr, false) != nil {
+		t.Error("expected no game after it was deleted")
+	}
+}
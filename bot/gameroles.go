@@ -0,0 +1,108 @@
+package bot
+
+import (
+	"log"
+
+	"github.com/automuteus/automuteus/v8/internal/server"
+	"github.com/automuteus/automuteus/v8/pkg/amongus"
+	"github.com/automuteus/automuteus/v8/pkg/game"
+	"github.com/automuteus/automuteus/v8/pkg/settings"
+	"github.com/bwmarrin/discordgo"
+)
+
+// gameRoleChange swaps the role the user has for their state in the game; either side can be empty
+type gameRoleChange struct {
+	userID string
+	add    string
+	remove string
+}
+
+// gameRoleFor returns the role the user should have right now. Nobody gets one outside of a round, so the lobby and
+// menu are role-free
+func gameRoleFor(sett *settings.GuildSettings, dgs *GameState, userData UserData, inVoice bool) string {
+	phase := dgs.GameData.GetPhase()
+	if !dgs.Running || (phase != game.TASKS && phase != game.DISCUSS) {
+		return ""
+	}
+	if userData.InGameName == amongus.UnlinkedPlayerName {
+		if inVoice {
+			return sett.GetGameRoleID(settings.SpectatorGameRole)
+		}
+		return ""
+	}
+	player, found := dgs.GameData.GetByName(userData.InGameName)
+	if !found {
+		return ""
+	}
+	if player.IsAlive {
+		return sett.GetGameRoleID(settings.AliveGameRole)
+	}
+	return sett.GetGameRoleID(settings.DeadGameRole)
+}
+
+// gameRoleChanges records the roles everyone should have, and returns the changes needed to get them there.
+// inVoice holds the users in the game's tracked voice channel(s)
+func (dgs *GameState) gameRoleChanges(sett *settings.GuildSettings, inVoice map[string]bool) []gameRoleChange {
+	var changes []gameRoleChange
+	for userID, userData := range dgs.UserData {
+		roleID := gameRoleFor(sett, dgs, userData, inVoice[userID])
+		if roleID == userData.GameRoleID {
+			continue
+		}
+		changes = append(changes, gameRoleChange{userID: userID, add: roleID, remove: userData.GameRoleID})
+		userData.GameRoleID = roleID
+		dgs.UpdateUserData(userID, userData)
+	}
+	return changes
+}
+
+// takeGameRoles forgets every role the bot gave out, and returns the changes that remove them
+func (dgs *GameState) takeGameRoles() []gameRoleChange {
+	var changes []gameRoleChange
+	for userID, userData := range dgs.UserData {
+		if userData.GameRoleID == "" {
+			continue
+		}
+		changes = append(changes, gameRoleChange{userID: userID, remove: userData.GameRoleID})
+		userData.GameRoleID = ""
+		dgs.UpdateUserData(userID, userData)
+	}
+	return changes
+}
+
+func (bot *Bot) applyGameRoleChanges(sess *discordgo.Session, guildID string, changes []gameRoleChange) {
+	requests := 0
+	for _, v := range changes {
+		if v.remove != "" {
+			err := sess.GuildMemberRoleRemove(guildID, v.userID, v.remove)
+			if err != nil {
+				log.Printf("Couldn't remove role %s from %s: %s\n", v.remove, v.userID, err)
+			}
+			requests++
+		}
+		if v.add != "" {
+			err := sess.GuildMemberRoleAdd(guildID, v.userID, v.add)
+			if err != nil {
+				log.Printf("Couldn't give role %s to %s: %s\n", v.add, v.userID, err)
+			}
+			requests++
+		}
+	}
+	if requests > 0 {
+		server.RecordDiscordRequests(bot.RedisInterface.client, server.MemberRole, int64(requests))
+	}
+}
+
+// clearGameRoles takes back every role the bot gave out for the game
+func (bot *Bot) clearGameRoles(gsr GameStateRequest) {
+	lock, dgs := bot.RedisInterface.GetDiscordGameStateAndLock(gsr)
+	for lock == nil {
+		lock, dgs = bot.RedisInterface.GetDiscordGameStateAndLock(gsr)
+	}
+	before := dgs.userStates()
+	changes := dgs.takeGameRoles()
+	bot.RedisInterface.LogUserStates(dgs, before)
+	bot.RedisInterface.SetDiscordGameState(dgs, lock)
+
+	bot.applyGameRoleChanges(bot.PrimarySession, dgs.GuildID, changes)
+}
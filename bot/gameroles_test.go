@@ -0,0 +1,72 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/automuteus/automuteus/v8/pkg/game"
+	"github.com/automuteus/automuteus/v8/pkg/settings"
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestGameRoleChanges(t *testing.T) {
+	dgs := NewDiscordGameState("100")
+	dgs.Running = true
+	dgs.GameData.UpdatePhase(game.LOBBY)
+	for i, name := range []string{"Red", "Blue"} {
+		dgs.GameData.UpdatePlayer(game.Player{Action: game.JOINED, Name: name, Color: i})
+		userID := string(rune('1' + i))
+		userData := MakeUserDataFromDiscordUser(&discordgo.User{ID: userID, Username: name}, "")
+		player, _ := dgs.GameData.GetByName(name)
+		userData.Link(player)
+		dgs.UpdateUserData(userID, userData)
+	}
+	// spectators, one of them in voice
+	dgs.UpdateUserData("3", MakeUserDataFromDiscordUser(&discordgo.User{ID: "3", Username: "watcher"}, ""))
+	dgs.UpdateUserData("4", MakeUserDataFromDiscordUser(&discordgo.User{ID: "4", Username: "lurker"}, ""))
+	inVoice := map[string]bool{"1": true, "2": true, "3": true}
+
+	sett := settings.MakeGuildSettings()
+	sett.SetGameRoleID(settings.AliveGameRole, "10")
+	sett.SetGameRoleID(settings.DeadGameRole, "20")
+	sett.SetGameRoleID(settings.SpectatorGameRole, "30")
+
+	if changes := dgs.gameRoleChanges(sett, inVoice); len(changes) != 0 {
+		t.Errorf("expected no roles in the lobby, got %+v", changes)
+	}
+
+	dgs.GameData.UpdatePhase(game.TASKS)
+	expected := map[string]gameRoleChange{
+		"1": {userID: "1", add: "10"},
+		"2": {userID: "2", add: "10"},
+		"3": {userID: "3", add: "30"},
+	}
+	checkChanges := func(changes []gameRoleChange) {
+		t.Helper()
+		if len(changes) != len(expected) {
+			t.Errorf("expected changes %+v, got %+v", expected, changes)
+			return
+		}
+		for _, v := range changes {
+			if expected[v.userID] != v {
+				t.Errorf("expected changes %+v, got %+v", expected, changes)
+			}
+		}
+	}
+	checkChanges(dgs.gameRoleChanges(sett, inVoice))
+
+	// only the change is sent when Blue dies
+	dgs.GameData.UpdatePlayer(game.Player{Action: game.DIED, Name: "Blue", Color: 1, IsDead: true})
+	expected = map[string]gameRoleChange{"2": {userID: "2", add: "20", remove: "10"}}
+	checkChanges(dgs.gameRoleChanges(sett, inVoice))
+
+	// and everything is taken back when the game ends
+	expected = map[string]gameRoleChange{
+		"1": {userID: "1", remove: "10"},
+		"2": {userID: "2", remove: "20"},
+		"3": {userID: "3", remove: "30"},
+	}
+	checkChanges(dgs.takeGameRoles())
+	if changes := dgs.takeGameRoles(); len(changes) != 0 {
+		t.Errorf("expected no roles left to take, got %+v", changes)
+	}
+}
@@ -0,0 +1,149 @@
+package bot
+
+import (
+	"log"
+
+	"github.com/automuteus/automuteus/v8/internal/server"
+	"github.com/automuteus/automuteus/v8/pkg/discord"
+	"github.com/automuteus/automuteus/v8/pkg/game"
+	"github.com/automuteus/automuteus/v8/pkg/rediskey"
+	"github.com/automuteus/automuteus/v8/pkg/settings"
+	"github.com/bwmarrin/discordgo"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// midRound is whether a round is underway, where people coming and going are subject to the guild's late-joiner and
+// leaver policies
+func (dgs *GameState) midRound() bool {
+	phase := dgs.GameData.GetPhase()
+	return dgs.Running && (phase == game.TASKS || phase == game.DISCUSS)
+}
+
+// clearSpectators lets late joiners go at the start of the next match; they're unmuted in the lobby like everyone else,
+// and can be linked before it starts
+func (dgs *GameState) clearSpectators() {
+	for i, v := range dgs.UserData {
+		if v.Spectator {
+			v.Spectator = false
+			dgs.UserData[i] = v
+		}
+	}
+}
+
+// lateJoinerPolicy returns what's to be done about the user joining the game's voice channel, if they're an unlinked
+// member joining mid-round. A spectator is marked as one right away, so they're muted along with the voice state change;
+// anything else is for applyLateJoinerPolicy once the game state is saved
+func (dgs *GameState) lateJoinerPolicy(sett *settings.GuildSettings, userData UserData, linked bool, m *discordgo.VoiceStateUpdate) (UserData, string) {
+	joined := m.ChannelID != "" && m.ChannelID == dgs.VoiceChannel && (m.BeforeUpdate == nil || m.BeforeUpdate.ChannelID != m.ChannelID)
+	if !joined || linked || userData.Exempt || userData.Spectator || userData.User.UserID == "" || !dgs.midRound() {
+		return userData, settings.LateJoinerIgnore
+	}
+	policy := sett.GetLateJoinerPolicy()
+	// with nowhere to wait, they're muted instead
+	if policy == settings.LateJoinerSpectate || (policy == settings.LateJoinerWait && sett.GetWaitingChannelID() == "") {
+		userData.Spectator = true
+		return userData, settings.LateJoinerSpectate
+	}
+	return userData, policy
+}
+
+// applyLateJoinerPolicy moves the late joiner to the waiting channel, or pings the host to link them
+func (bot *Bot) applyLateJoinerPolicy(sess *discordgo.Session, sett *settings.GuildSettings, dgs *GameState, userID, policy string) {
+	switch policy {
+	case settings.LateJoinerWait:
+		channelID := sett.GetWaitingChannelID()
+		err := sess.GuildMemberMove(dgs.GuildID, userID, &channelID)
+		server.RecordDiscordRequests(bot.RedisInterface.client, server.MemberMove, 1)
+		if err != nil {
+			log.Printf("Couldn't move late joiner %s to waiting channel %s: %s\n", userID, channelID, err)
+		}
+	case settings.LateJoinerPing:
+		bot.pingHost(dgs, sett.LocalizeMessage(&i18n.Message{
+			ID:    "lateJoiners.ping",
+			Other: "{{.Host}}, {{.User}} joined {{.VoiceChannel}} mid-round without being linked. Use `/link` to link them to their color",
+		},
+			map[string]interface{}{
+				"Host":         discord.MentionByUserID(dgs.GameStateMsg.LeaderID),
+				"User":         discord.MentionByUserID(userID),
+				"VoiceChannel": discord.MentionByChannelID(dgs.VoiceChannel),
+			}))
+	}
+}
+
+// handleLeaver applies the guild's leaver policy if the voice state change is a linked player leaving their game's voice
+// channel mid-round. The settings are its own, as the game they left isn't necessarily in the channel they joined, and
+// follows its own channel's settings
+func (bot *Bot) handleLeaver(m *discordgo.VoiceStateUpdate) {
+	before := m.BeforeUpdate
+	if before == nil || before.ChannelID == "" || before.ChannelID == m.ChannelID {
+		return
+	}
+	// no game in the channel they left
+	if bot.RedisInterface.CheckPointer(rediskey.VoiceChannelPtr(m.GuildID, before.ChannelID)) == "" {
+		return
+	}
+
+	gsr := GameStateRequest{
+		GuildID:      m.GuildID,
+		VoiceChannel: before.ChannelID,
+	}
+	lock, dgs := bot.RedisInterface.GetDiscordGameStateAndLock(gsr)
+	if lock == nil {
+		return
+	}
+	sett := bot.StorageInterface.GetGuildSettings(m.GuildID)
+	dgs.applyGameSettings(sett)
+	policy := sett.GetLeaverPolicy()
+	// being moved to the ghost channel isn't leaving
+	if policy == settings.LeaverIgnore || (m.ChannelID != "" && m.ChannelID == sett.GetGhostChannelID()) {
+		lock.Release(ctx)
+		return
+	}
+	userData, err := dgs.GetUser(m.UserID)
+	if err != nil || dgs.VoiceChannel != before.ChannelID || !dgs.midRound() {
+		lock.Release(ctx)
+		return
+	}
+	player, linked := dgs.GameData.GetByName(userData.InGameName)
+	if !linked {
+		lock.Release(ctx)
+		return
+	}
+
+	switch policy {
+	case settings.LeaverUnlink:
+		_, success := bot.linkOrUnlinkAndRespond(dgs, m.UserID, "", sett)
+		if !success {
+			lock.Release(ctx)
+			return
+		}
+		bot.RedisInterface.SetDiscordGameState(dgs, lock)
+		bot.DispatchRefreshOrEdit(dgs, gsr, sett)
+	case settings.LeaverPing:
+		lock.Release(ctx)
+		bot.pingHost(dgs, sett.LocalizeMessage(&i18n.Message{
+			ID:    "leavers.ping",
+			Other: "{{.Host}}, {{.User}} ({{.Color}}) left {{.VoiceChannel}} mid-round",
+		},
+			map[string]interface{}{
+				"Host":         discord.MentionByUserID(dgs.GameStateMsg.LeaderID),
+				"User":         discord.MentionByUserID(m.UserID),
+				"Color":        game.GetColorStringForInt(player.Color),
+				"VoiceChannel": discord.MentionByChannelID(dgs.VoiceChannel),
+			}))
+	default:
+		lock.Release(ctx)
+	}
+}
+
+// pingHost sends the message to the game's text channel
+func (bot *Bot) pingHost(dgs *GameState, msg string) {
+	if dgs.GameStateMsg.MessageChannelID == "" {
+		return
+	}
+	_, err := bot.PrimarySession.ChannelMessageSend(dgs.GameStateMsg.MessageChannelID, msg)
+	if err != nil {
+		log.Println(err)
+	}
+	server.RecordDiscordRequests(bot.RedisInterface.client, server.MessageCreateDelete, 1)
+}
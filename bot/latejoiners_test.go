@@ -0,0 +1,79 @@
+package bot
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/automuteus/automuteus/v8/pkg/game"
+	"github.com/automuteus/automuteus/v8/pkg/settings"
+	"github.com/automuteus/automuteus/v8/pkg/task"
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestLateJoinerSpectates(t *testing.T) {
+	const guildID = "100"
+	const voiceChannelID = "200"
+
+	mr := miniredis.RunT(t)
+	stopClock := runReplayClock(mr)
+	defer stopClock()
+
+	dgs := NewDiscordGameState(guildID)
+	dgs.ConnectCode = "ABCD1234"
+	dgs.VoiceChannel = voiceChannelID
+	dgs.Running = true
+	dgs.GameData.UpdatePhase(game.LOBBY)
+	dgs.GameData.UpdatePhase(game.TASKS)
+	dgs.GameData.UpdatePhase(game.DISCUSS)
+
+	dgs.GameData.UpdatePlayer(game.Player{Action: game.JOINED, Name: "Red", Color: 0})
+	red := &discordgo.Member{User: &discordgo.User{ID: "1", Username: "Red"}}
+	redData := MakeUserDataFromDiscordUser(red.User, "")
+	player, _ := dgs.GameData.GetByName("Red")
+	redData.Link(player)
+	dgs.UpdateUserData("1", redData)
+
+	// joins the game's voice channel mid-discussion, without being linked
+	lateJoiner := &discordgo.Member{User: &discordgo.User{ID: "2", Username: "Late"}}
+	userData := MakeUserDataFromDiscordUser(lateJoiner.User, "")
+	update := &discordgo.VoiceStateUpdate{
+		VoiceState: &discordgo.VoiceState{GuildID: guildID, UserID: "2", ChannelID: voiceChannelID},
+	}
+
+	sett := settings.MakeGuildSettings()
+	if _, policy := dgs.lateJoinerPolicy(sett, userData, false, update); policy != settings.LateJoinerIgnore {
+		t.Errorf("expected late joiners to be ignored by default, got %s", policy)
+	}
+	sett.SetLateJoinerPolicy(settings.LateJoinerSpectate)
+	userData, policy := dgs.lateJoinerPolicy(sett, userData, false, update)
+	if policy != settings.LateJoinerSpectate || !userData.Spectator {
+		t.Fatalf("expected the late joiner to be marked as a spectator, got %s", policy)
+	}
+	dgs.UpdateUserData("2", userData)
+
+	transport := &replayTransport{}
+	bot, err := newReplayBot(mr.Addr(), transport, &RecordSnapshot{
+		GameState: dgs,
+		VoiceStates: []*discordgo.VoiceState{
+			{GuildID: guildID, UserID: "1", ChannelID: voiceChannelID},
+			update.VoiceState,
+		},
+		Members: []*discordgo.Member{red, lateJoiner},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bot.RedisInterface.Close()
+	defer bot.StorageInterface.Close()
+
+	bot.RedisInterface.SetDiscordGameState(dgs, nil)
+	gsr := GameStateRequest{GuildID: guildID, ConnectCode: dgs.ConnectCode}
+
+	bot.handleTrackedMembers(bot.PrimarySession, sett, 0, NoPriority, gsr)
+
+	expected := []task.UserModify{{UserID: 2, Mute: true, Deaf: false}}
+	if modifies := transport.drain(); !reflect.DeepEqual(modifies, expected) {
+		t.Errorf("expected only the late joiner to be muted, got %+v", modifies)
+	}
+}
@@ -30,7 +30,10 @@ func (bot *Bot) handleVoiceStateChange(s *discordgo.Session, m *discordgo.VoiceS
 		premTier = prem
 	}
 
+	bot.handleLeaver(m)
+
 	sett := bot.StorageInterface.GetGuildSettings(m.GuildID)
+
 	gsr := GameStateRequest{
 		GuildID:      m.GuildID,
 		VoiceChannel: m.ChannelID,
@@ -41,6 +44,8 @@ func (bot *Bot) handleVoiceStateChange(s *discordgo.Session, m *discordgo.VoiceS
 		return
 	}
 	defer stateLock.Release(ctx)
+	dgs.applyGameSettings(sett)
+	before := dgs.userStates()
 
 	var voiceLock *redislock.Lock
 	if dgs.ConnectCode != "" {
@@ -63,14 +68,27 @@ func (bot *Bot) handleVoiceStateChange(s *discordgo.Session, m *discordgo.VoiceS
 		userData, _ = dgs.checkCacheAndAddUser(g, s, m.UserID)
 	}
 
+	// a mute or deafen the bot didn't apply is someone else's, and isn't the bot's to lift
+	var foreignMute, foreignDeaf bool
+	if dgs.Running && userData.User.UserID != "" {
+		userData, foreignMute, foreignDeaf = trackForeignMuteDeaf(userData, m.VoiceState, m.BeforeUpdate)
+		dgs.UpdateUserData(m.UserID, userData)
+	}
+
 	tracked := m.ChannelID != "" && dgs.VoiceChannel == m.ChannelID
 
 	auData, found := dgs.GameData.GetByName(userData.InGameName)
+	userData = dgs.updateExempt(userData, memberIsExempt(s, sett, m.GuildID, m.UserID))
+	var lateJoiner string
+	userData, lateJoiner = dgs.lateJoinerPolicy(sett, userData, found, m)
+	if lateJoiner == settings.LateJoinerSpectate {
+		dgs.UpdateUserData(m.UserID, userData)
+	}
 
 	var isAlive bool
 
 	// only actually tracked if we're in a tracked channel AND linked to a player
-	if !sett.GetMuteSpectator() {
+	if !sett.GetMuteSpectator() && !userData.Spectator {
 		tracked = tracked && found
 		isAlive = auData.IsAlive
 	} else {
@@ -81,9 +99,16 @@ func (bot *Bot) handleVoiceStateChange(s *discordgo.Session, m *discordgo.VoiceS
 			isAlive = auData.IsAlive
 		}
 	}
-	mute, deaf := sett.GetVoiceState(isAlive, tracked, dgs.GameData.GetPhase())
+	mute, deaf := sett.GetVoiceState(dgs.GameData.GetGameMode(), dgs.voiceCategory(auData, found), isAlive, tracked, dgs.GameData.GetPhase())
 	// check the userdata is linked here to not accidentally undeafen music bots, for example
-	if found && (userData.ShouldBeDeaf != deaf || userData.ShouldBeMute != mute) && (mute != m.Mute || deaf != m.Deaf) {
+	// on a stage, being muted means being in the audience, and nobody's deafened
+	stageID := stageChannelID(s, dgs.VoiceChannel)
+	applyMute, applyDeaf := userData.KeepForeignMuteDeafOn(stageID != "", mute, deaf)
+	currentMute, currentDeaf := m.Mute, m.Deaf
+	if stageID != "" {
+		currentMute, currentDeaf = m.Suppress, applyDeaf
+	}
+	if (found || userData.Spectator) && !userData.Exempt && (userData.ShouldBeDeaf != deaf || userData.ShouldBeMute != mute) && (applyMute != currentMute || applyDeaf != currentDeaf) {
 		userData.SetShouldBeMuteDeaf(mute, deaf)
 
 		dgs.UpdateUserData(m.UserID, userData)
@@ -95,18 +120,21 @@ func (bot *Bot) handleVoiceStateChange(s *discordgo.Session, m *discordgo.VoiceS
 				Users: []task.UserModify{
 					{
 						UserID: uid,
-						Mute:   mute,
-						Deaf:   deaf,
+						Mute:   applyMute,
+						Deaf:   applyDeaf,
 					},
 				},
 			}
-			err = bot.TokenProvider.ModifyUsers(m.GuildID, dgs.ConnectCode, req, voiceLock)
+			err = bot.issueMutesAndRecord(dgs, stageID, req, voiceLock)
 			if err != nil {
 				log.Println("error received from galactus for modifyUsers: ", err.Error())
 			}
 		}
 	}
+	bot.RedisInterface.LogUserStates(dgs, before)
 	bot.RedisInterface.SetDiscordGameState(dgs, stateLock)
+	bot.logVoiceConflict(sett, m.UserID, foreignMute, foreignDeaf)
+	bot.applyLateJoinerPolicy(s, sett, dgs, m.UserID, lateJoiner)
 }
 
 func (bot *Bot) handleGameStartMessage(guildID, textChannelID, voiceChannelID, userID string, sett *settings.GuildSettings, g *discordgo.Guild, connCode string) {
@@ -131,13 +159,19 @@ func (bot *Bot) handleGameStartMessage(guildID, textChannelID, voiceChannelID, u
 		dgs.VoiceChannel = voiceChannelID
 		for _, v := range g.VoiceStates {
 			if v.ChannelID == voiceChannelID {
-				dgs.checkCacheAndAddUser(g, bot.PrimarySession, v.UserID)
+				userData, added := dgs.checkCacheAndAddUser(g, bot.PrimarySession, v.UserID)
+				if added {
+					// anyone already muted or deafened when the game starts was muted by someone else
+					userData, _, _ = trackForeignMuteDeaf(userData, v, nil)
+					dgs.UpdateUserData(v.UserID, userData)
+				}
 			}
 		}
 	}
 
 	_ = dgs.CreateMessage(bot.PrimarySession, bot.gameStateResponse(dgs, sett), textChannelID, userID)
 
+	bot.RedisInterface.StartGameLog(dgs)
 	// release the lock
 	bot.RedisInterface.SetDiscordGameState(dgs, lock)
 }
@@ -0,0 +1,266 @@
+package bot
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/automuteus/automuteus/v8/internal/server"
+	"github.com/automuteus/automuteus/v8/pkg/amongus"
+	"github.com/automuteus/automuteus/v8/pkg/game"
+	"github.com/automuteus/automuteus/v8/pkg/rediskey"
+	"github.com/automuteus/automuteus/v8/pkg/settings"
+	"github.com/bwmarrin/discordgo"
+	"github.com/go-redis/redis/v8"
+)
+
+// MaxNicknameLength is the longest nickname Discord accepts
+const MaxNicknameLength = 32
+
+// nicknameChange renames the user; restore is set when they're getting their own nickname back, and original is the
+// nickname to give back otherwise
+type nicknameChange struct {
+	userID   string
+	nick     string
+	restore  bool
+	original string
+}
+
+// SyncedNickname is a nickname the bot gave out for a game. They're kept per guild, apart from the game state, so they
+// can be given back even if the game never ends properly; see sweepStuckNicknames
+type SyncedNickname struct {
+	ConnectCode string `json:"connectCode"`
+	Original    string `json:"original"`
+	Synced      string `json:"synced"`
+}
+
+// RecordSyncedNicknames notes the nicknames the bot has given out, and forgets the ones it's given back
+func (redisInterface *RedisInterface) RecordSyncedNicknames(guildID string, synced map[string]SyncedNickname, restored []string) {
+	key := rediskey.SyncedNicknames(guildID)
+	pipe := redisInterface.client.TxPipeline()
+	if len(synced) > 0 {
+		values := make([]interface{}, 0, len(synced)*2)
+		for userID, v := range synced {
+			jBytes, err := json.Marshal(v)
+			if err != nil {
+				log.Println(err)
+				continue
+			}
+			values = append(values, userID, jBytes)
+		}
+		pipe.HSet(ctx, key, values...)
+	}
+	if len(restored) > 0 {
+		pipe.HDel(ctx, key, restored...)
+	}
+	pipe.Expire(ctx, key, MutedUsersTimeoutSeconds*time.Second)
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// GetSyncedNicknames returns the nicknames the bot has given out in the guild, by user ID
+func (redisInterface *RedisInterface) GetSyncedNicknames(guildID string) map[string]SyncedNickname {
+	all, err := redisInterface.client.HGetAll(ctx, rediskey.SyncedNicknames(guildID)).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		log.Println(err)
+	}
+	synced := make(map[string]SyncedNickname, len(all))
+	for userID, v := range all {
+		var nick SyncedNickname
+		if err := json.Unmarshal([]byte(v), &nick); err != nil {
+			log.Println(err)
+			continue
+		}
+		synced[userID] = nick
+	}
+	return synced
+}
+
+// syncedNickname is what a linked player is called during the game, e.g. "🟥 Red", using the same emoji as the color buttons
+func syncedNickname(player amongus.PlayerData) string {
+	nick := player.Name
+	if emoji := colorEmoji(player.Color); emoji != "" {
+		nick = emoji + " " + nick
+	}
+	runes := []rune(nick)
+	if len(runes) > MaxNicknameLength {
+		nick = string(runes[:MaxNicknameLength])
+	}
+	return nick
+}
+
+// currentNickname prefers the guild's cached member over our own, possibly older, copy
+func currentNickname(g *discordgo.Guild, userData UserData) string {
+	if g != nil {
+		for _, member := range g.Members {
+			if member.User != nil && member.User.ID == userData.GetID() {
+				return member.Nick
+			}
+		}
+	}
+	return userData.GetNickName()
+}
+
+// nicknameChanges records the nickname everyone should have, and returns the renames needed to get them there.
+// The guild owner can't be renamed by anyone, so they're left alone. synced is the guild's record of nicknames already
+// given out, for when the game state has lost track of them (see RebuildGameState)
+func (dgs *GameState) nicknameChanges(sett *settings.GuildSettings, g *discordgo.Guild, synced map[string]SyncedNickname) []nicknameChange {
+	var changes []nicknameChange
+	syncing := sett.GetSyncNicknames() && dgs.Running && dgs.GameData.GetPhase() != game.MENU
+	for userID, userData := range dgs.UserData {
+		want := ""
+		if syncing && !userData.NickUnmanageable && (g == nil || g.OwnerID != userID) {
+			if player, found := dgs.GameData.GetByName(userData.InGameName); found && userData.InGameName != amongus.UnlinkedPlayerName {
+				want = syncedNickname(player)
+			}
+		}
+		if want == userData.SyncedNick {
+			continue
+		}
+		if want == "" {
+			changes = append(changes, nicknameChange{userID: userID, nick: userData.OriginalNick, restore: true})
+			userData.OriginalNick = ""
+		} else {
+			if userData.SyncedNick == "" {
+				userData.OriginalNick = currentNickname(g, userData)
+				// still wearing a nickname the bot gave out; the one to give back is the one from before that
+				if v, ok := synced[userID]; ok && v.Synced == userData.OriginalNick {
+					userData.OriginalNick = v.Original
+				}
+			}
+			changes = append(changes, nicknameChange{userID: userID, nick: want, original: userData.OriginalNick})
+		}
+		userData.SyncedNick = want
+		dgs.UpdateUserData(userID, userData)
+	}
+	return changes
+}
+
+// takeNicknames forgets every nickname the bot gave out, and returns the renames that give the originals back
+func (dgs *GameState) takeNicknames() []nicknameChange {
+	var changes []nicknameChange
+	for userID, userData := range dgs.UserData {
+		if userData.SyncedNick == "" {
+			continue
+		}
+		changes = append(changes, nicknameChange{userID: userID, nick: userData.OriginalNick, restore: true})
+		userData.SyncedNick = ""
+		userData.OriginalNick = ""
+		dgs.UpdateUserData(userID, userData)
+	}
+	return changes
+}
+
+// applyNicknameChanges renames everyone, and stops trying for anyone the bot turns out not to be allowed to rename
+func (bot *Bot) applyNicknameChanges(sess *discordgo.Session, gsr GameStateRequest, changes []nicknameChange) {
+	var failed, restored []string
+	synced := make(map[string]SyncedNickname)
+	for _, v := range changes {
+		err := sess.GuildMemberNickname(gsr.GuildID, v.userID, v.nick)
+		if err != nil {
+			log.Printf("Couldn't change the nickname of %s: %s\n", v.userID, err)
+		}
+		switch {
+		case v.restore:
+			restored = append(restored, v.userID)
+		case err != nil:
+			failed = append(failed, v.userID)
+		default:
+			synced[v.userID] = SyncedNickname{ConnectCode: gsr.ConnectCode, Original: v.original, Synced: v.nick}
+		}
+	}
+	if len(changes) > 0 {
+		server.RecordDiscordRequests(bot.RedisInterface.client, server.MemberNickname, int64(len(changes)))
+		bot.RedisInterface.RecordSyncedNicknames(gsr.GuildID, synced, restored)
+	}
+	if len(failed) == 0 {
+		return
+	}
+
+	lock, dgs := bot.RedisInterface.GetDiscordGameStateAndLock(gsr)
+	for lock == nil {
+		lock, dgs = bot.RedisInterface.GetDiscordGameStateAndLock(gsr)
+	}
+	before := dgs.userStates()
+	for _, userID := range failed {
+		if userData, ok := dgs.UserData[userID]; ok {
+			userData.SyncedNick = ""
+			userData.OriginalNick = ""
+			userData.NickUnmanageable = true
+			dgs.UpdateUserData(userID, userData)
+		}
+	}
+	bot.RedisInterface.LogUserStates(dgs, before)
+	bot.RedisInterface.SetDiscordGameState(dgs, lock)
+}
+
+// syncNicknamesAfterLink renames whoever was just (un)linked, instead of waiting for the next transition.
+// The caller holds the lock on dgs, and saves it
+func (bot *Bot) syncNicknamesAfterLink(dgs *GameState, sett *settings.GuildSettings) {
+	g, _ := bot.PrimarySession.State.Guild(dgs.GuildID)
+	before := dgs.userStates()
+	changes := dgs.nicknameChanges(sett, g, bot.RedisInterface.GetSyncedNicknames(dgs.GuildID))
+	bot.RedisInterface.LogUserStates(dgs, before)
+	if len(changes) > 0 {
+		go bot.applyNicknameChanges(bot.PrimarySession, GameStateRequest{GuildID: dgs.GuildID, ConnectCode: dgs.ConnectCode}, changes)
+	}
+}
+
+// restoreNicknames gives everyone back the nickname they had before the game
+func (bot *Bot) restoreNicknames(gsr GameStateRequest) {
+	lock, dgs := bot.RedisInterface.GetDiscordGameStateAndLock(gsr)
+	for lock == nil {
+		lock, dgs = bot.RedisInterface.GetDiscordGameStateAndLock(gsr)
+	}
+	before := dgs.userStates()
+	changes := dgs.takeNicknames()
+	bot.RedisInterface.LogUserStates(dgs, before)
+	bot.RedisInterface.SetDiscordGameState(dgs, lock)
+
+	bot.applyNicknameChanges(bot.PrimarySession, gsr, changes)
+}
+
+// sweepStuckNicknames gives back the nicknames the bot gave out for a game that's gone, like when the bot was stopped
+// mid-game. Anyone who's changed their nickname themselves since keeps the new one
+func (bot *Bot) sweepStuckNicknames(guildID string) {
+	synced := bot.RedisInterface.GetSyncedNicknames(guildID)
+	if len(synced) == 0 {
+		return
+	}
+	activeGames := bot.RedisInterface.LoadAllActiveGames(guildID)
+
+	var restored []string
+	var renames int64
+	for userID, v := range synced {
+		if bot.RedisInterface.GameExists(guildID, v.ConnectCode, activeGames) {
+			continue
+		}
+		restored = append(restored, userID)
+		member, err := bot.PrimarySession.State.Member(guildID, userID)
+		if err != nil {
+			member, err = bot.PrimarySession.GuildMember(guildID, userID)
+			if err != nil {
+				// most likely gone from the guild
+				continue
+			}
+		}
+		if member.Nick != v.Synced {
+			continue
+		}
+		log.Printf("Giving %s back their nickname, left synced after their game was gone in guild %s\n", userID, guildID)
+		renames++
+		err = bot.PrimarySession.GuildMemberNickname(guildID, userID, v.Original)
+		if err != nil {
+			log.Printf("Couldn't change the nickname of %s: %s\n", userID, err)
+		}
+	}
+	if renames > 0 {
+		server.RecordDiscordRequests(bot.RedisInterface.client, server.MemberNickname, renames)
+	}
+	if len(restored) > 0 {
+		bot.RedisInterface.RecordSyncedNicknames(guildID, nil, restored)
+	}
+}
@@ -0,0 +1,168 @@
+package bot
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/automuteus/automuteus/v8/pkg/amongus"
+	"github.com/automuteus/automuteus/v8/pkg/game"
+	"github.com/automuteus/automuteus/v8/pkg/settings"
+	"github.com/bwmarrin/discordgo"
+)
+
+// nickTransport records nickname changes, by user ID
+type nickTransport struct {
+	replayTransport
+	nickLock sync.Mutex
+	nicks    map[string]string
+}
+
+func (nt *nickTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method == http.MethodPatch && req.Body != nil {
+		var body map[string]interface{}
+		err := json.NewDecoder(req.Body).Decode(&body)
+		if err != nil {
+			return nil, err
+		}
+		if nick, ok := body["nick"].(string); ok {
+			parts := strings.Split(strings.TrimSuffix(req.URL.Path, "/"), "/")
+			nt.nickLock.Lock()
+			nt.nicks[parts[len(parts)-1]] = nick
+			nt.nickLock.Unlock()
+		}
+		return replayResponse(req, http.StatusOK, "{}"), nil
+	}
+	return nt.replayTransport.RoundTrip(req)
+}
+
+func TestNicknameChanges(t *testing.T) {
+	g := &discordgo.Guild{ID: "100", OwnerID: "3"}
+	dgs := NewDiscordGameState(g.ID)
+	dgs.Running = true
+	dgs.GameData.UpdatePhase(game.LOBBY)
+	for i, name := range []string{"Red", "Blue", "Green"} {
+		dgs.GameData.UpdatePlayer(game.Player{Action: game.JOINED, Name: name, Color: i})
+		userID := string(rune('1' + i))
+		user := &discordgo.User{ID: userID, Username: name}
+		g.Members = append(g.Members, &discordgo.Member{User: user, Nick: "nick" + userID})
+		userData := MakeUserDataFromDiscordUser(user, "stale")
+		player, _ := dgs.GameData.GetByName(name)
+		userData.Link(player)
+		dgs.UpdateUserData(userID, userData)
+	}
+
+	sett := settings.MakeGuildSettings()
+	if changes := dgs.nicknameChanges(sett, g, nil); len(changes) != 0 {
+		t.Errorf("expected no renames when syncing is off, got %+v", changes)
+	}
+
+	sett.SetSyncNicknames(true)
+	changes := dgs.nicknameChanges(sett, g, nil)
+	// the owner can't be renamed
+	expected := map[string]nicknameChange{
+		"1": {userID: "1", nick: "🟥 Red", original: "nick1"},
+		"2": {userID: "2", nick: "🔵 Blue", original: "nick2"},
+	}
+	if len(changes) != len(expected) {
+		t.Fatalf("expected renames %+v, got %+v", expected, changes)
+	}
+	for _, v := range changes {
+		if expected[v.userID] != v {
+			t.Errorf("expected renames %+v, got %+v", expected, changes)
+		}
+	}
+	if user := dgs.UserData["1"]; user.OriginalNick != "nick1" {
+		t.Errorf("expected the guild's nickname to be kept, got %s", user.OriginalNick)
+	}
+	if changes := dgs.nicknameChanges(sett, g, nil); len(changes) != 0 {
+		t.Errorf("expected no renames when nothing changed, got %+v", changes)
+	}
+
+	// unlinking gives the nickname back
+	dgs.ClearPlayerData("2")
+	changes = dgs.nicknameChanges(sett, g, nil)
+	if len(changes) != 1 || changes[0] != (nicknameChange{userID: "2", nick: "nick2", restore: true}) {
+		t.Errorf("expected Blue's nickname to be restored, got %+v", changes)
+	}
+	if dgs.UserData["2"].InGameName != amongus.UnlinkedPlayerName || dgs.UserData["2"].SyncedNick != "" {
+		t.Errorf("expected Blue to be unlinked and unsynced, got %+v", dgs.UserData["2"])
+	}
+
+	// as does the end of the game
+	changes = dgs.takeNicknames()
+	if len(changes) != 1 || changes[0] != (nicknameChange{userID: "1", nick: "nick1", restore: true}) {
+		t.Errorf("expected Red's nickname to be restored, got %+v", changes)
+	}
+
+	// a game state that lost track of the nicknames it gave out still gives back the ones from before
+	rebuilt := NewDiscordGameState(g.ID)
+	rebuilt.Running = true
+	rebuilt.GameData = dgs.GameData
+	rebuilt.UserData = map[string]UserData{"1": MakeUserDataFromDiscordUser(g.Members[0].User, "")}
+	red, _ := rebuilt.GameData.GetByName("Red")
+	userData := rebuilt.UserData["1"]
+	userData.Link(red)
+	rebuilt.UpdateUserData("1", userData)
+	g.Members[0].Nick = "🟥 Red"
+	synced := map[string]SyncedNickname{"1": {ConnectCode: "ABCD1234", Original: "nick1", Synced: "🟥 Red"}}
+	rebuilt.nicknameChanges(sett, g, synced)
+	if user := rebuilt.UserData["1"]; user.OriginalNick != "nick1" {
+		t.Errorf("expected the nickname from before the game to be kept, got %s", user.OriginalNick)
+	}
+
+	long := amongus.PlayerData{Name: "ThisNameIsFarTooLongForDiscordsLimit", Color: game.Lime}
+	if nick := syncedNickname(long); len([]rune(nick)) != MaxNicknameLength {
+		t.Errorf("expected the nickname to be cut to %d characters, got %s", MaxNicknameLength, nick)
+	}
+}
+
+func TestSweepStuckNicknames(t *testing.T) {
+	const guildID = "100"
+
+	mr := miniredis.RunT(t)
+	stopClock := runReplayClock(mr)
+	defer stopClock()
+
+	live := NewDiscordGameState(guildID)
+	live.ConnectCode = "LIVE1234"
+
+	transport := &nickTransport{nicks: make(map[string]string)}
+	bot, err := newReplayBot(mr.Addr(), transport, &RecordSnapshot{
+		GameState: live,
+		Members: []*discordgo.Member{
+			// left with the synced nickname when the bot was stopped
+			{User: &discordgo.User{ID: "1", Username: "red"}, Nick: "🟥 Red"},
+			// renamed themselves since
+			{User: &discordgo.User{ID: "2", Username: "blue"}, Nick: "blue's own"},
+			// still in the game that's going
+			{User: &discordgo.User{ID: "3", Username: "green"}, Nick: "🟩 Green"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bot.RedisInterface.Close()
+	defer bot.StorageInterface.Close()
+	bot.RedisInterface.SetDiscordGameState(live, nil)
+
+	bot.RedisInterface.RecordSyncedNicknames(guildID, map[string]SyncedNickname{
+		"1": {ConnectCode: "DEAD1234", Original: "red", Synced: "🟥 Red"},
+		"2": {ConnectCode: "DEAD1234", Original: "blue", Synced: "🔵 Blue"},
+		"3": {ConnectCode: live.ConnectCode, Original: "green", Synced: "🟩 Green"},
+	}, nil)
+
+	bot.sweepStuckNicknames(guildID)
+
+	if !reflect.DeepEqual(transport.nicks, map[string]string{"1": "red"}) {
+		t.Errorf("expected only Red's nickname to be given back, got %v", transport.nicks)
+	}
+	remaining := bot.RedisInterface.GetSyncedNicknames(guildID)
+	if len(remaining) != 1 || remaining["3"].ConnectCode != live.ConnectCode {
+		t.Errorf("expected only the live game's nickname to be kept, got %v", remaining)
+	}
+}
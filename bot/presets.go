@@ -0,0 +1,13 @@
+package bot
+
+import "github.com/automuteus/automuteus/v8/pkg/settings"
+
+// gameSettings are the guild's settings, with the voice channel's overrides and the preset the game was started with
+// (if any) in place of its defaults
+func (bot *Bot) gameSettings(gsr GameStateRequest) *settings.GuildSettings {
+	sett := bot.StorageInterface.GetGuildSettings(gsr.GuildID)
+	if dgs := bot.RedisInterface.GetReadOnlyDiscordGameState(gsr); dgs != nil {
+		dgs.applyGameSettings(sett)
+	}
+	return sett
+}
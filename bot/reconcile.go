@@ -0,0 +1,116 @@
+package bot
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/automuteus/automuteus/v8/internal/server"
+	"github.com/automuteus/automuteus/v8/pkg/game"
+	"github.com/automuteus/automuteus/v8/pkg/premium"
+	"github.com/automuteus/automuteus/v8/pkg/settings"
+	"github.com/automuteus/automuteus/v8/pkg/task"
+	"github.com/bwmarrin/discordgo"
+)
+
+// ReconcileInterval is how often a game's subscriber checks that everyone is muted and deafened the way the bot last
+// left them. Mutes are otherwise only corrected on a voice state update or a phase change, so one that silently
+// failed, or was undone by hand, would stick until then
+const ReconcileInterval = time.Second * 30
+
+// MaxReconcileCorrections caps how many players a single check corrects; anyone past it is left for the next one
+const MaxReconcileCorrections = 10
+
+// voiceDrift returns a correction for every tracked user whose server mute/deafen isn't what the bot last gave them,
+// along with how many of them were muted and deafened wrong. On a stage, it's whether they're in the audience that
+// stands in for the mute
+func (dgs *GameState) voiceDrift(sess *discordgo.Session, sett *settings.GuildSettings, voiceStates []*discordgo.VoiceState, stage bool) ([]task.UserModify, int64, int64) {
+	var users []task.UserModify
+	var muteDrift, deafDrift int64
+	ghostChannelID := sett.GetGhostChannelID()
+	for _, voiceState := range voiceStates {
+		if voiceState.ChannelID == "" || (voiceState.ChannelID != dgs.VoiceChannel && voiceState.ChannelID != ghostChannelID) {
+			continue
+		}
+		userData, err := dgs.GetUser(voiceState.UserID)
+		if err != nil {
+			continue
+		}
+		// the same players handleTrackedMembers mutes; nobody else is the bot's to correct
+		_, found := dgs.GameData.GetByName(userData.InGameName)
+		if (!found && !sett.GetMuteSpectator() && !userData.Spectator) || memberIsExempt(sess, sett, dgs.GuildID, voiceState.UserID) {
+			continue
+		}
+		// a mute or deafen someone else put on them isn't drift
+		mute, deaf := userData.KeepForeignMuteDeafOn(stage, userData.ShouldBeMute, userData.ShouldBeDeaf)
+		currentMute, currentDeaf := voiceState.Mute, voiceState.Deaf
+		if stage {
+			currentMute, currentDeaf = voiceState.Suppress, deaf
+		}
+		if currentMute == mute && currentDeaf == deaf {
+			continue
+		}
+		if currentMute != mute {
+			muteDrift++
+		}
+		if currentDeaf != deaf {
+			deafDrift++
+		}
+		uid, _ := strconv.ParseUint(voiceState.UserID, 10, 64)
+		users = append(users, task.UserModify{
+			UserID: uid,
+			Mute:   mute,
+			Deaf:   deaf,
+		})
+	}
+	return users, muteDrift, deafDrift
+}
+
+// reconcileVoiceStates re-issues the mutes/deafens of anyone who has drifted from what they should be
+func (bot *Bot) reconcileVoiceStates(dgsRequest GameStateRequest) {
+	dgs := bot.RedisInterface.GetReadOnlyDiscordGameState(dgsRequest)
+	// with the capture lost, everyone's been unmuted on purpose
+	if dgs == nil || !dgs.Running || dgs.CaptureLost || dgs.GameData.GetPhase() == game.MENU {
+		return
+	}
+	// someone else is changing voice states (or about to, after a delay); what's right is about to change anyway
+	voiceLock := bot.RedisInterface.LockVoiceChanges(dgs.ConnectCode, time.Second)
+	if voiceLock == nil {
+		return
+	}
+	g, err := bot.PrimarySession.State.Guild(dgs.GuildID)
+	if err != nil {
+		log.Println(err)
+		voiceLock.Release(ctx)
+		return
+	}
+	sett := bot.StorageInterface.GetGuildSettings(dgsRequest.GuildID)
+	dgs.applyGameSettings(sett)
+
+	stageID := stageChannelID(bot.PrimarySession, dgs.VoiceChannel)
+	users, muteDrift, deafDrift := dgs.voiceDrift(bot.PrimarySession, sett, g.VoiceStates, stageID != "")
+	server.RecordVoiceDrift(bot.RedisInterface.client, server.MuteDrift, muteDrift)
+	server.RecordVoiceDrift(bot.RedisInterface.client, server.DeafDrift, deafDrift)
+	if len(users) == 0 {
+		voiceLock.Release(ctx)
+		return
+	}
+	if len(users) > MaxReconcileCorrections {
+		users = users[:MaxReconcileCorrections]
+	}
+	log.Printf("Correcting the voice state of %d player(s) in game %s\n", len(users), dgs.ConnectCode)
+
+	prem, days, _ := bot.PostgresInterface.GetGuildOrUserPremiumStatus(bot.official, nil, dgs.GuildID, "")
+	premTier := premium.FreeTier
+	if !premium.IsExpired(prem, days) {
+		premTier = prem
+	}
+	req := task.UserModifyRequest{
+		Premium: premTier,
+		Users:   users,
+	}
+	err = bot.issueMutesAndRecord(dgs, stageID, req, voiceLock)
+	if err != nil {
+		log.Println(err)
+	}
+}
@@ -0,0 +1,145 @@
+package bot
+
+import (
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/automuteus/automuteus/v8/internal/server"
+	"github.com/automuteus/automuteus/v8/pkg/game"
+	"github.com/automuteus/automuteus/v8/pkg/rediskey"
+	"github.com/automuteus/automuteus/v8/pkg/settings"
+	"github.com/automuteus/automuteus/v8/pkg/task"
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestReconcileVoiceStates(t *testing.T) {
+	const guildID = "100"
+	const voiceChannelID = "200"
+
+	mr := miniredis.RunT(t)
+	stopClock := runReplayClock(mr)
+	defer stopClock()
+
+	dgs := NewDiscordGameState(guildID)
+	dgs.ConnectCode = "ABCD1234"
+	dgs.VoiceChannel = voiceChannelID
+	dgs.Running = true
+	dgs.GameData.UpdatePhase(game.LOBBY)
+	dgs.GameData.UpdatePhase(game.TASKS)
+
+	var members []*discordgo.Member
+	for i, name := range []string{"Red", "Blue"} {
+		userID := string(rune('1' + i))
+		dgs.GameData.UpdatePlayer(game.Player{Action: game.JOINED, Name: name, Color: i})
+		member := &discordgo.Member{User: &discordgo.User{ID: userID, Username: name}}
+		userData := MakeUserDataFromDiscordUser(member.User, "")
+		player, _ := dgs.GameData.GetByName(name)
+		userData.Link(player)
+		userData.SetShouldBeMuteDeaf(true, true)
+		dgs.UpdateUserData(userID, userData)
+		members = append(members, member)
+	}
+	// a spectator the bot has never muted, and shouldn't start to
+	spectator := &discordgo.Member{User: &discordgo.User{ID: "3", Username: "watcher"}}
+	dgs.UpdateUserData("3", MakeUserDataFromDiscordUser(spectator.User, ""))
+	members = append(members, spectator)
+
+	transport := &replayTransport{}
+	bot, err := newReplayBot(mr.Addr(), transport, &RecordSnapshot{
+		GameState: dgs,
+		VoiceStates: []*discordgo.VoiceState{
+			{GuildID: guildID, ChannelID: voiceChannelID, UserID: "1", Mute: true, Deaf: true},
+			// unmuted by hand
+			{GuildID: guildID, ChannelID: voiceChannelID, UserID: "2", Mute: false, Deaf: true},
+			{GuildID: guildID, ChannelID: voiceChannelID, UserID: "3", Mute: true},
+		},
+		Members: members,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bot.RedisInterface.Close()
+	defer bot.StorageInterface.Close()
+	bot.RedisInterface.SetDiscordGameState(dgs, nil)
+	gsr := GameStateRequest{GuildID: guildID, ConnectCode: dgs.ConnectCode}
+
+	bot.reconcileVoiceStates(gsr)
+
+	modifies := transport.drain()
+	if len(modifies) != 1 || modifies[0] != (task.UserModify{UserID: 2, Mute: true, Deaf: true}) {
+		t.Errorf("expected only Blue to be muted again, got %+v", modifies)
+	}
+	if v, _ := mr.Get(rediskey.VoiceDriftByType(server.VoiceDriftTypeStrings[server.MuteDrift])); v != "1" {
+		t.Errorf("expected one mute drift to be recorded, got %s", v)
+	}
+
+	// while the capture is lost, everyone is unmuted on purpose
+	lock, state := bot.RedisInterface.GetDiscordGameStateAndLock(gsr)
+	for lock == nil {
+		lock, state = bot.RedisInterface.GetDiscordGameStateAndLock(gsr)
+	}
+	state.CaptureLost = true
+	bot.RedisInterface.SetDiscordGameState(state, lock)
+
+	bot.reconcileVoiceStates(gsr)
+	if modifies := transport.drain(); len(modifies) != 0 {
+		t.Errorf("expected no corrections while the capture is lost, got %+v", modifies)
+	}
+
+	// a role exempted since Blue was last seen in voice exempts them all the same
+	lock, state = bot.RedisInterface.GetDiscordGameStateAndLock(gsr)
+	for lock == nil {
+		lock, state = bot.RedisInterface.GetDiscordGameStateAndLock(gsr)
+	}
+	state.CaptureLost = false
+	bot.RedisInterface.SetDiscordGameState(state, lock)
+	member, err := bot.PrimarySession.State.Member(guildID, "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	member.Roles = []string{"500"}
+	sett := settings.MakeGuildSettings()
+	sett.ToggleExemptRole("500")
+	err = bot.StorageInterface.SetGuildSettings(guildID, sett)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	bot.reconcileVoiceStates(gsr)
+	if modifies := transport.drain(); len(modifies) != 0 {
+		t.Errorf("expected no corrections for an exempt role, got %+v", modifies)
+	}
+}
+
+func TestVoiceDriftStage(t *testing.T) {
+	const voiceChannelID = "200"
+
+	dgs := NewDiscordGameState("100")
+	dgs.VoiceChannel = voiceChannelID
+	dgs.GameData.UpdatePhase(game.LOBBY)
+	dgs.GameData.UpdatePhase(game.DISCUSS)
+	dgs.GameData.UpdatePlayer(game.Player{Action: game.JOINED, Name: "Red"})
+
+	// speaking in the discussion, but server muted by a moderator
+	userData := MakeUserDataFromDiscordUser(&discordgo.User{ID: "1", Username: "Red"}, "")
+	player, _ := dgs.GameData.GetByName("Red")
+	userData.Link(player)
+	userData.ForeignMute = true
+	dgs.UpdateUserData("1", userData)
+
+	voiceStates := []*discordgo.VoiceState{{ChannelID: voiceChannelID, UserID: "1", Mute: true}}
+	sett := settings.MakeGuildSettings()
+
+	// the moderator's mute keeps them quiet on the stage; it's no reason to send them to the audience
+	if users, _, _ := dgs.voiceDrift(nil, sett, voiceStates, true); len(users) != 0 {
+		t.Errorf("expected a foreign mute not to suppress anyone on a stage, got %+v", users)
+	}
+	// off a stage, the bot leaves the mute on them
+	if users, _, _ := dgs.voiceDrift(nil, sett, voiceStates, false); len(users) != 0 {
+		t.Errorf("expected a kept foreign mute not to be drift, got %+v", users)
+	}
+	voiceStates[0].Mute = false
+	if users, _, _ := dgs.voiceDrift(nil, sett, voiceStates, false); len(users) != 1 || !users[0].Mute {
+		t.Errorf("expected the foreign mute to be put back, got %+v", users)
+	}
+}
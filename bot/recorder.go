@@ -0,0 +1,290 @@
+package bot
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/automuteus/automuteus/v8/pkg/rediskey"
+	"github.com/automuteus/automuteus/v8/pkg/settings"
+	"github.com/automuteus/automuteus/v8/pkg/task"
+	"github.com/bwmarrin/discordgo"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	RecordToFile  = "file"
+	RecordToRedis = "redis"
+
+	// RecordingTTL is how long recordings written to Redis are kept around
+	RecordingTTL = time.Hour * 24
+)
+
+// RecordEntry is one line of a capture session recording.
+// The first entry of a recording holds the Snapshot; every entry after that holds a Job
+type RecordEntry struct {
+	// OffsetMs is the wall-clock time since the recording started
+	OffsetMs int64           `json:"offsetMs"`
+	Job      *task.Job       `json:"job,omitempty"`
+	Snapshot *RecordSnapshot `json:"snapshot,omitempty"`
+}
+
+// RecordSnapshot is the Discord side of a game when its recording started; replays are seeded with it
+type RecordSnapshot struct {
+	GameState   *GameState              `json:"gameState"`
+	Settings    *settings.GuildSettings `json:"settings"`
+	VoiceStates []*discordgo.VoiceState `json:"voiceStates"`
+	Members     []*discordgo.Member     `json:"members"`
+}
+
+type recordSink interface {
+	write(entry RecordEntry) error
+	close() error
+}
+
+type recordingSession struct {
+	start time.Time
+	sink  recordSink
+}
+
+// CaptureRecorder writes every job popped for a connect code, so sessions can be replayed later
+type CaptureRecorder struct {
+	sync.Mutex
+	mode     string
+	dir      string
+	client   *redis.Client
+	sessions map[string]*recordingSession
+}
+
+func NewCaptureRecorder(mode, dir string, client *redis.Client) (*CaptureRecorder, error) {
+	switch mode {
+	case RecordToFile:
+		err := os.MkdirAll(dir, 0755)
+		if err != nil {
+			return nil, err
+		}
+	case RecordToRedis:
+	default:
+		return nil, fmt.Errorf("unknown capture recording mode \"%s\"; expected %s or %s", mode, RecordToFile, RecordToRedis)
+	}
+	return &CaptureRecorder{
+		mode:     mode,
+		dir:      dir,
+		client:   client,
+		sessions: make(map[string]*recordingSession),
+	}, nil
+}
+
+func (bot *Bot) EnableCaptureRecording(mode, dir string) error {
+	recorder, err := NewCaptureRecorder(mode, dir, bot.RedisInterface.client)
+	if err != nil {
+		return err
+	}
+	bot.recorder = recorder
+	return nil
+}
+
+// recordJob is a no-op unless recording is enabled. The first job for a code also snapshots the game
+func (bot *Bot) recordJob(gsr GameStateRequest, job task.Job) {
+	if bot.recorder == nil {
+		return
+	}
+	err := bot.recorder.record(gsr.ConnectCode, job, func() *RecordSnapshot {
+		return bot.snapshotForRecording(gsr)
+	})
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func (bot *Bot) finishRecording(connectCode string) {
+	if bot.recorder == nil {
+		return
+	}
+	err := bot.recorder.finish(connectCode)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+func (bot *Bot) snapshotForRecording(gsr GameStateRequest) *RecordSnapshot {
+	snapshot := &RecordSnapshot{
+		GameState: bot.RedisInterface.GetReadOnlyDiscordGameState(gsr),
+		// the settings the game actually ran with, preset and channel overrides included
+		Settings: bot.gameSettings(gsr),
+	}
+	g, err := bot.PrimarySession.State.Guild(gsr.GuildID)
+	if err != nil {
+		log.Println(err)
+		return snapshot
+	}
+	// only the members in voice matter, and the full member list can be huge
+	inVoice := make(map[string]struct{})
+	for _, v := range g.VoiceStates {
+		snapshot.VoiceStates = append(snapshot.VoiceStates, v)
+		inVoice[v.UserID] = struct{}{}
+	}
+	for _, m := range g.Members {
+		if m.User == nil {
+			continue
+		}
+		if _, ok := inVoice[m.User.ID]; ok {
+			snapshot.Members = append(snapshot.Members, m)
+		}
+	}
+	return snapshot
+}
+
+func (recorder *CaptureRecorder) record(connectCode string, job task.Job, snapshot func() *RecordSnapshot) error {
+	recorder.Lock()
+	defer recorder.Unlock()
+
+	sess, ok := recorder.sessions[connectCode]
+	if !ok {
+		sink, err := recorder.newSink(connectCode)
+		if err != nil {
+			return err
+		}
+		sess = &recordingSession{
+			start: time.Now(),
+			sink:  sink,
+		}
+		recorder.sessions[connectCode] = sess
+		err = sink.write(RecordEntry{Snapshot: snapshot()})
+		if err != nil {
+			return err
+		}
+	}
+	return sess.sink.write(RecordEntry{
+		OffsetMs: time.Since(sess.start).Milliseconds(),
+		Job:      &job,
+	})
+}
+
+func (recorder *CaptureRecorder) finish(connectCode string) error {
+	recorder.Lock()
+	defer recorder.Unlock()
+
+	sess, ok := recorder.sessions[connectCode]
+	if !ok {
+		return nil
+	}
+	delete(recorder.sessions, connectCode)
+	return sess.sink.close()
+}
+
+func (recorder *CaptureRecorder) newSink(connectCode string) (recordSink, error) {
+	if recorder.mode == RecordToRedis {
+		return &streamSink{
+			client: recorder.client,
+			key:    rediskey.CaptureRecording(connectCode),
+		}, nil
+	}
+	name := path.Join(recorder.dir, fmt.Sprintf("%s-%d.jsonl", connectCode, time.Now().Unix()))
+	f, err := os.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	log.Println("Recording capture session to " + name)
+	return &fileSink{file: f, writer: bufio.NewWriter(f)}, nil
+}
+
+type fileSink struct {
+	file   *os.File
+	writer *bufio.Writer
+}
+
+func (sink *fileSink) write(entry RecordEntry) error {
+	jBytes, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	_, err = sink.writer.Write(append(jBytes, '\n'))
+	if err != nil {
+		return err
+	}
+	// flush every line, so a crash still leaves a usable recording behind
+	return sink.writer.Flush()
+}
+
+func (sink *fileSink) close() error {
+	return sink.file.Close()
+}
+
+type streamSink struct {
+	client *redis.Client
+	key    string
+}
+
+func (sink *streamSink) write(entry RecordEntry) error {
+	jBytes, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	err = sink.client.XAdd(ctx, &redis.XAddArgs{
+		Stream: sink.key,
+		Values: map[string]interface{}{"entry": string(jBytes)},
+	}).Err()
+	if err != nil {
+		return err
+	}
+	return sink.client.Expire(ctx, sink.key, RecordingTTL).Err()
+}
+
+func (sink *streamSink) close() error {
+	return nil
+}
+
+// ReadRecordingFile loads a JSONL recording written by the file recorder
+func ReadRecordingFile(name string) ([]RecordEntry, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []RecordEntry
+	scanner := bufio.NewScanner(f)
+	// snapshots can be much longer than the default line limit
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry RecordEntry
+		err = json.Unmarshal(scanner.Bytes(), &entry)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// ReadRecordingStream loads a recording written by the Redis recorder
+func ReadRecordingStream(client *redis.Client, connectCode string) ([]RecordEntry, error) {
+	msgs, err := client.XRange(ctx, rediskey.CaptureRecording(connectCode), "-", "+").Result()
+	if err != nil {
+		return nil, err
+	}
+	if len(msgs) == 0 {
+		return nil, errors.New("no recording found for " + connectCode)
+	}
+	entries := make([]RecordEntry, 0, len(msgs))
+	for _, msg := range msgs {
+		str, _ := msg.Values["entry"].(string)
+		var entry RecordEntry
+		err = json.Unmarshal([]byte(str), &entry)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
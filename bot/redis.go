@@ -51,6 +51,14 @@ func (bot *Bot) refreshGameLiveness(code string) {
 	go bot.RedisInterface.client.ZRemRangeByScore(context.Background(), rediskey.ActiveGamesZSet, "-inf", fmt.Sprintf("%d", before.Unix()))
 }
 
+// removeGameLiveness drops the code from the active games, so capture clients can no longer connect with it
+func (bot *Bot) removeGameLiveness(code string) {
+	err := bot.RedisInterface.client.ZRem(ctx, rediskey.ActiveGamesZSet, code).Err()
+	if err != nil {
+		log.Println(err)
+	}
+}
+
 func (bot *Bot) rateLimitEventCallback(_ *discordgo.Session, rl *discordgo.RateLimit) {
 	log.Println(rl.Message)
 	server.RecordDiscordRequests(bot.RedisInterface.client, server.InvalidRequest, 1)
@@ -153,6 +161,11 @@ func (redisInterface *RedisInterface) getDiscordGameState(gsr GameStateRequest,
 	jsonStr, err := redisInterface.client.Get(ctx, key).Result()
 	switch {
 	case errors.Is(err, redis.Nil):
+		// the snapshot is gone, but the game might not be
+		if dgs := redisInterface.rebuildGameState(gsr.GuildID, gsr.ConnectCode); dgs != nil {
+			redisInterface.SetDiscordGameState(dgs, nil)
+			return dgs
+		}
 		if createOnNil {
 			dgs := NewDiscordGameState(gsr.GuildID)
 			dgs.ConnectCode = gsr.ConnectCode
@@ -345,6 +358,7 @@ func (redisInterface *RedisInterface) DeleteDiscordGameState(dgs *GameState) {
 	if err != nil {
 		log.Println(err)
 	}
+	redisInterface.DeleteGameLog(guildID, connCode)
 }
 
 func (redisInterface *RedisInterface) GetUsernameOrUserIDMappings(guildID, key string) (map[string]interface{}, error) {
@@ -0,0 +1,273 @@
+package bot
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/automuteus/automuteus/v8/bot/tokenprovider"
+	"github.com/automuteus/automuteus/v8/pkg/rediskey"
+	storageutils "github.com/automuteus/automuteus/v8/pkg/storage"
+	"github.com/automuteus/automuteus/v8/pkg/task"
+	"github.com/automuteus/automuteus/v8/storage"
+	"github.com/bwmarrin/discordgo"
+	"github.com/go-redis/redis/v8"
+)
+
+// ReplayJobTimeout is how long a replay waits for the subscriber to finish a single job
+const ReplayJobTimeout = time.Minute
+
+// ReplayDecision is a mute/deafen that the bot issued while replaying a recording
+type ReplayDecision struct {
+	// JobIndex is the recorded job that led to the decision; -1 for anything issued while ending the game
+	JobIndex int
+	Job      *task.Job
+	task.UserModify
+}
+
+// ReplayRecording feeds a recording back through SubscribeToGameByConnectCode, one job at a time, against an
+// in-memory Redis and a fake Discord API. It returns every mute/deafen the bot decided on, in order.
+// Phase delays are skipped unless keepDelays is set; they only affect timing, not the decisions themselves
+func ReplayRecording(entries []RecordEntry, keepDelays bool) ([]ReplayDecision, error) {
+	if len(entries) == 0 || entries[0].Snapshot == nil || entries[0].Snapshot.GameState == nil {
+		return nil, errors.New("recording doesn't start with a game snapshot")
+	}
+	snapshot := entries[0].Snapshot
+	dgs := snapshot.GameState
+	if dgs.GuildID == "" || dgs.ConnectCode == "" {
+		return nil, errors.New("recorded game has no guild or connect code")
+	}
+
+	mr, err := miniredis.Run()
+	if err != nil {
+		return nil, err
+	}
+	defer mr.Close()
+	stopClock := runReplayClock(mr)
+	defer stopClock()
+
+	transport := &replayTransport{}
+	bot, err := newReplayBot(mr.Addr(), transport, snapshot)
+	if err != nil {
+		return nil, err
+	}
+	defer bot.RedisInterface.Close()
+	defer bot.StorageInterface.Close()
+
+	sett := snapshot.Settings
+	if sett != nil {
+		if !keepDelays {
+			for _, dests := range sett.Delays.Delays {
+				for k := range dests {
+					dests[k] = 0
+				}
+			}
+		}
+		err = bot.StorageInterface.SetGuildSettings(dgs.GuildID, sett)
+		if err != nil {
+			return nil, err
+		}
+	}
+	bot.RedisInterface.SetDiscordGameState(dgs, nil)
+
+	endGame := make(chan EndGameMessage)
+	done := make(chan struct{})
+	go func() {
+		bot.SubscribeToGameByConnectCode(dgs.GuildID, dgs.ConnectCode, endGame)
+		close(done)
+	}()
+
+	var decisions []ReplayDecision
+	jobIndex := 0
+	for _, entry := range entries[1:] {
+		if entry.Job == nil {
+			continue
+		}
+		id, err := task.AddJob(ctx, bot.RedisInterface.client, dgs.ConnectCode, *entry.Job)
+		if err != nil {
+			return decisions, err
+		}
+		err = waitForJobAck(bot.RedisInterface.client, dgs.ConnectCode, id)
+		if err != nil {
+			return decisions, fmt.Errorf("job %d: %w", jobIndex, err)
+		}
+		for _, modify := range transport.drain() {
+			decisions = append(decisions, ReplayDecision{
+				JobIndex:   jobIndex,
+				Job:        entry.Job,
+				UserModify: modify,
+			})
+		}
+		jobIndex++
+	}
+
+	endGame <- true
+	<-done
+	for _, modify := range transport.drain() {
+		decisions = append(decisions, ReplayDecision{
+			JobIndex:   -1,
+			UserModify: modify,
+		})
+	}
+	return decisions, nil
+}
+
+// runReplayClock keeps time moving in miniredis, which only expires keys when told to; the bot relies on lock TTLs
+func runReplayClock(mr *miniredis.Miniredis) (stop func()) {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(time.Millisecond * 10)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				mr.FastForward(time.Millisecond * 10)
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		close(done)
+	}
+}
+
+func newReplayBot(redisAddr string, transport http.RoundTripper, snapshot *RecordSnapshot) (*Bot, error) {
+	sess, err := discordgo.New("Bot replay")
+	if err != nil {
+		return nil, err
+	}
+	sess.Client = &http.Client{Transport: transport}
+	sess.State.User = &discordgo.User{ID: "0", Username: "replay"}
+	err = sess.State.GuildAdd(&discordgo.Guild{
+		ID:          snapshot.GameState.GuildID,
+		VoiceStates: snapshot.VoiceStates,
+		Members:     snapshot.Members,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	redisInterface := &RedisInterface{}
+	err = redisInterface.Init(storage.RedisParameters{Addr: redisAddr})
+	if err != nil {
+		return nil, err
+	}
+	storageInterface := &storage.StorageInterface{}
+	err = storageInterface.Init(storage.RedisParameters{Addr: redisAddr})
+	if err != nil {
+		return nil, err
+	}
+
+	tp := tokenprovider.NewTokenProvider(redisInterface.client, sess, time.Millisecond, 7)
+
+	return &Bot{
+		StatusEmojis:      emptyStatusEmojis(),
+		ConnsToGames:      make(map[string]string),
+		EndGameChannels:   make(map[string]chan EndGameMessage),
+		PrimarySession:    sess,
+		TokenProvider:     tp,
+		RedisInterface:    redisInterface,
+		StorageInterface:  storageInterface,
+		PostgresInterface: &storageutils.PsqlInterface{},
+		captureTimeout:    GameTimeoutSeconds,
+	}, nil
+}
+
+// waitForJobAck blocks until the subscriber has read and acked the job with the given stream ID
+func waitForJobAck(client *redis.Client, connectCode, id string) error {
+	deadline := time.Now().Add(ReplayJobTimeout)
+	for time.Now().Before(deadline) {
+		// go-redis's XInfoGroups only understands the reply of older Redis versions, so parse it by hand
+		res, err := client.Do(ctx, "XINFO", "GROUPS", rediskey.JobStream(connectCode)).Result()
+		if err != nil && !strings.HasPrefix(err.Error(), "NOGROUP") {
+			return err
+		}
+		reply, _ := res.([]interface{})
+		for _, g := range reply {
+			fields, _ := g.([]interface{})
+			info := make(map[string]interface{})
+			for i := 0; i+1 < len(fields); i += 2 {
+				if k, ok := fields[i].(string); ok {
+					info[k] = fields[i+1]
+				}
+			}
+			if info["name"] == task.JobConsumerGroup && info["last-delivered-id"] == id && info["pending"] == int64(0) {
+				return nil
+			}
+		}
+		time.Sleep(time.Millisecond * 5)
+	}
+	return errors.New("timed out waiting for the job to be processed")
+}
+
+// replayTransport stands in for the Discord API. Mutes/deafens are recorded, members that weren't in the snapshot
+// don't exist, and every other request simply succeeds
+type replayTransport struct {
+	sync.Mutex
+	modifies []task.UserModify
+	nextID   int
+}
+
+func (rt *replayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rt.Lock()
+	defer rt.Unlock()
+
+	// .../guilds/{guildID}/members/{userID}
+	parts := strings.Split(strings.TrimSuffix(req.URL.Path, "/"), "/")
+	isMember := len(parts) > 2 && parts[len(parts)-2] == "members"
+
+	switch {
+	case isMember && req.Method == http.MethodPatch:
+		var params task.PatchParams
+		if req.Body != nil {
+			err := json.NewDecoder(req.Body).Decode(&params)
+			if err != nil {
+				return nil, err
+			}
+		}
+		uid, _ := strconv.ParseUint(parts[len(parts)-1], 10, 64)
+		rt.modifies = append(rt.modifies, task.UserModify{
+			UserID: uid,
+			Mute:   params.Mute,
+			Deaf:   params.Deaf,
+		})
+		return replayResponse(req, http.StatusOK, "{}"), nil
+	case isMember && req.Method == http.MethodGet:
+		return replayResponse(req, http.StatusNotFound, `{"message":"Unknown Member","code":10007}`), nil
+	}
+	rt.nextID++
+	return replayResponse(req, http.StatusOK, fmt.Sprintf(`{"id":"%d"}`, rt.nextID)), nil
+}
+
+// drain returns the mutes/deafens issued since the last call. Requests within a batch are sent concurrently,
+// so they're sorted to keep replays comparable
+func (rt *replayTransport) drain() []task.UserModify {
+	rt.Lock()
+	defer rt.Unlock()
+	modifies := rt.modifies
+	rt.modifies = nil
+	sort.SliceStable(modifies, func(i, j int) bool {
+		return modifies[i].UserID < modifies[j].UserID
+	})
+	return modifies
+}
+
+func replayResponse(req *http.Request, status int, body string) *http.Response {
+	return &http.Response{
+		Status:     http.StatusText(status),
+		StatusCode: status,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(bytes.NewBufferString(body)),
+		Request:    req,
+	}
+}
@@ -0,0 +1,97 @@
+package bot
+
+import (
+	"encoding/json"
+	"path"
+	"testing"
+
+	"github.com/automuteus/automuteus/v8/pkg/settings"
+	"github.com/automuteus/automuteus/v8/pkg/task"
+	"github.com/bwmarrin/discordgo"
+)
+
+func recordedJob(jobType task.JobType, payload string) *task.Job {
+	return &task.Job{
+		Version: task.JobSchemaVersion,
+		JobType: jobType,
+		Payload: json.RawMessage(payload),
+	}
+}
+
+func TestReplayRecording(t *testing.T) {
+	const guildID = "100"
+	const voiceChannelID = "200"
+
+	dgs := NewDiscordGameState(guildID)
+	dgs.ConnectCode = "ABCD1234"
+	dgs.VoiceChannel = voiceChannelID
+	dgs.Running = true
+	dgs.GameStateMsg.MessageChannelID = "300"
+
+	member := &discordgo.Member{
+		User: &discordgo.User{ID: "1", Username: "red"},
+		Nick: "Red",
+	}
+
+	snapshot := &RecordSnapshot{
+		GameState:   dgs,
+		Settings:    settings.MakeGuildSettings(),
+		VoiceStates: []*discordgo.VoiceState{{GuildID: guildID, ChannelID: voiceChannelID, UserID: "1"}},
+		Members:     []*discordgo.Member{member},
+	}
+
+	// write the recording to disk and read it back, like the replay command would
+	dir := t.TempDir()
+	recorder, err := NewCaptureRecorder(RecordToFile, dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs := []*task.Job{
+		recordedJob(task.ConnectionJob, "true"),
+		recordedJob(task.PlayerJob, `{"Action":0,"Name":"Red","Color":0,"IsDead":false,"Disconnected":false}`),
+		recordedJob(task.StateJob, "1"),
+		recordedJob(task.StateJob, "2"),
+	}
+	for _, job := range jobs {
+		err = recorder.record(dgs.ConnectCode, *job, func() *RecordSnapshot { return snapshot })
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	name := recorder.sessions[dgs.ConnectCode].sink.(*fileSink).file.Name()
+	err = recorder.finish(dgs.ConnectCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path.Dir(name) != dir {
+		t.Fatalf("expected the recording in %s, got %s", dir, name)
+	}
+
+	entries, err := ReadRecordingFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(jobs)+1 || entries[0].Snapshot == nil {
+		t.Fatalf("expected a snapshot and %d jobs, got %d entries", len(jobs), len(entries))
+	}
+
+	decisions, err := ReplayRecording(entries, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []ReplayDecision{
+		// tasks: alive players are muted and deafened
+		{JobIndex: 2, UserModify: task.UserModify{UserID: 1, Mute: true, Deaf: true}},
+		// discussion: alive players can talk
+		{JobIndex: 3, UserModify: task.UserModify{UserID: 1, Mute: false, Deaf: false}},
+	}
+	if len(decisions) != len(expected) {
+		t.Fatalf("expected %d decisions, got %d: %+v", len(expected), len(decisions), decisions)
+	}
+	for i, v := range expected {
+		if decisions[i].JobIndex != v.JobIndex || decisions[i].UserModify != v.UserModify {
+			t.Errorf("decision %d: expected %+v, got %+v", i, v, decisions[i])
+		}
+	}
+}
@@ -39,7 +39,7 @@ func settingResponse(settingsList []setting.Setting, sett *settings.GuildSetting
 	fields := make([]*discordgo.MessageEmbedField, 0)
 	for _, v := range settingsList {
 		if !v.Premium {
-			name := v.Name
+			name := v.CommandName()
 			fields = append(fields, &discordgo.MessageEmbedField{
 				Name:   name,
 				Value:  sett.LocalizeMessage(&i18n.Message{Other: v.ShortDesc}),
@@ -71,7 +71,7 @@ func settingResponse(settingsList []setting.Setting, sett *settings.GuildSetting
 	})
 	for _, v := range settingsList {
 		if v.Premium {
-			name := v.Name
+			name := v.CommandName()
 			fields = append(fields, &discordgo.MessageEmbedField{
 				Name:   name,
 				Value:  sett.LocalizeMessage(&i18n.Message{Other: v.ShortDesc}),
@@ -346,6 +346,11 @@ func (dgs *GameState) descriptionAndColor(sett *settings.GuildSettings) (string,
 			ID:    "responses.notLinked.Description",
 			Other: "❌**オートミュートキャプチャーと未接続**❌",
 		}), discord.RED // red
+	} else if dgs.CaptureLost {
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "responses.makeDescription.CaptureLost",
+			Other: "\n⚠ **Capture lost!** Everyone has been unmuted until the capture reconnects ⚠\n\n",
+		}), discord.DARK_ORANGE
 	} else if !dgs.Running {
 		return sett.LocalizeMessage(&i18n.Message{
 			ID:    "responses.makeDescription.GameNotRunning",
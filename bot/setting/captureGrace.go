@@ -0,0 +1,52 @@
+package setting
+
+import (
+	"fmt"
+	"github.com/automuteus/automuteus/v8/pkg/settings"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+	"log"
+	"strconv"
+)
+
+func FnCaptureGrace(sett *settings.GuildSettings, args []string) (interface{}, bool) {
+	s := GetSettingByName(CaptureGrace)
+	if sett == nil {
+		return nil, false
+	}
+	if len(args) == 0 {
+		return ConstructEmbedForSetting(fmt.Sprintf("%d", sett.GetCaptureGraceSeconds()), s, sett), false
+	}
+
+	num, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		log.Println("error for parseint in CaptureGrace: ", err)
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingCaptureGrace.Unrecognized",
+			Other: "{{.Seconds}} is not a valid number. See `/settings game capture-grace` for usage",
+		},
+			map[string]interface{}{
+				"Seconds": args[0],
+			}), false
+	}
+	if num > int64(MaxCaptureGrace) || num < int64(MinCaptureGrace) || num == 0 {
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingCaptureGrace.OutOfRange",
+			Other: "You provided a number too high or too low. Please specify a number between [1-600], or -1 to never unmute when the capture goes silent",
+		}), false
+	}
+
+	sett.SetCaptureGraceSeconds(int(num))
+	if num == -1 {
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingCaptureGrace.Success-1",
+			Other: "From now on, I'll leave everyone muted if the capture goes silent mid-game.",
+		}), true
+	}
+	return sett.LocalizeMessage(&i18n.Message{
+		ID:    "settings.SettingCaptureGrace.Success",
+		Other: "From now on, I'll unmute everyone if I don't hear from the capture for {{.Seconds}} seconds during Tasks or Discussion.",
+	},
+		map[string]interface{}{
+			"Seconds": num,
+		}), true
+}
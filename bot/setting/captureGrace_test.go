@@ -0,0 +1,40 @@
+package setting
+
+import (
+	"testing"
+
+	"github.com/automuteus/automuteus/v8/pkg/settings"
+)
+
+func TestFnCaptureGrace(t *testing.T) {
+	sett, err := testSettingsFn(FnCaptureGrace)
+	if err != nil {
+		t.Error(err)
+	}
+	if sett.GetCaptureGraceSeconds() != settings.DefaultCaptureGraceSeconds {
+		t.Error("Capture grace should default to DefaultCaptureGraceSeconds")
+	}
+
+	for _, v := range []string{"notanumber", "0", "-2", "601"} {
+		_, valid := FnCaptureGrace(sett, []string{v})
+		if valid {
+			t.Errorf("Invalid capture grace (\"%s\") should never result in a valid settings change", v)
+		}
+	}
+
+	_, valid := FnCaptureGrace(sett, []string{"-1"})
+	if !valid {
+		t.Error("Valid capture grace should result in a valid settings change")
+	}
+	if sett.GetCaptureGraceSeconds() != -1 {
+		t.Error("Valid capture grace (\"-1\") was not set correctly")
+	}
+
+	_, valid = FnCaptureGrace(sett, []string{"45"})
+	if !valid {
+		t.Error("Valid capture grace should result in a valid settings change")
+	}
+	if sett.GetCaptureGraceSeconds() != 45 {
+		t.Error("Valid capture grace (\"45\") was not set correctly")
+	}
+}
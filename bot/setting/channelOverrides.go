@@ -0,0 +1,130 @@
+package setting
+
+import (
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/automuteus/automuteus/v8/pkg/discord"
+	"github.com/automuteus/automuteus/v8/pkg/settings"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// the settings a channel can override are changed by their own Fn, run against the channel's settings
+var channelOverrideFns = map[string]func(*settings.GuildSettings, []string) (interface{}, bool){
+	settings.LanguageOverride:            FnLanguage,
+	settings.DelaysOverride:              FnDelays,
+	settings.VoiceRulesOverride:          FnVoiceRules,
+	settings.MatchSummaryChannelOverride: FnMatchSummaryChannel,
+	settings.MuteSpectatorsOverride:      FnMuteSpectators,
+}
+
+func overriddenNames(co settings.ChannelOverrides) string {
+	return "`" + strings.Join(co.Names(), "`, `") + "`"
+}
+
+// FnChannelOverrides takes [channel, view], [channel, clear] or [channel, setting, value...], where the value is what
+// would follow `/settings <setting>`
+func FnChannelOverrides(sett *settings.GuildSettings, args []string) (interface{}, bool) {
+	s := GetSettingByName(ChannelOverrides)
+	if sett == nil {
+		return nil, false
+	}
+	if len(args) < 2 {
+		var channelIDs []string
+		for channelID := range sett.ChannelOverrides {
+			channelIDs = append(channelIDs, channelID)
+		}
+		sort.Strings(channelIDs)
+		lines := make([]string, len(channelIDs))
+		for i, channelID := range channelIDs {
+			lines[i] = discord.MentionByChannelID(channelID) + ": " + overriddenNames(sett.GetChannelOverrides(channelID))
+		}
+		return ConstructEmbedForSetting(strings.Join(lines, "\n"), s, sett), false
+	}
+
+	channelID, err := discord.ExtractChannelIDFromText(args[0])
+	if err != nil {
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingChannelOverrides.invalidChannelID",
+			Other: "{{.channelID}} is not a valid voice channel ID or mention!",
+		},
+			map[string]interface{}{
+				"channelID": args[0],
+			}), false
+	}
+	channel := discord.MentionByChannelID(channelID)
+
+	switch args[1] {
+	case View:
+		co := sett.GetChannelOverrides(channelID)
+		if len(co.Names()) == 0 {
+			return sett.LocalizeMessage(&i18n.Message{
+				ID:    "settings.SettingChannelOverrides.noOverrides",
+				Other: "Games in {{.Channel}} use the guild's settings",
+			},
+				map[string]interface{}{
+					"Channel": channel,
+				}), false
+		}
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingChannelOverrides.view",
+			Other: "Games in {{.Channel}} have their own {{.Settings}}",
+		},
+			map[string]interface{}{
+				"Channel":  channel,
+				"Settings": overriddenNames(co),
+			}), false
+	case Clear:
+		if !sett.ClearChannelOverrides(channelID) {
+			return sett.LocalizeMessage(&i18n.Message{
+				ID:    "settings.SettingChannelOverrides.noOverrides",
+				Other: "Games in {{.Channel}} use the guild's settings",
+			},
+				map[string]interface{}{
+					"Channel": channel,
+				}), false
+		}
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingChannelOverrides.cleared",
+			Other: "Games in {{.Channel}} will use the guild's settings from now on",
+		},
+			map[string]interface{}{
+				"Channel": channel,
+			}), true
+	}
+
+	fn, ok := channelOverrideFns[args[1]]
+	if !ok {
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingChannelOverrides.notOverridable",
+			Other: "`{{.Setting}}` can't be changed for a channel. These can: `{{.Settings}}`",
+		},
+			map[string]interface{}{
+				"Setting":  args[1],
+				"Settings": strings.Join(settings.OverridableSettings, "`, `"),
+			}), false
+	}
+
+	scoped, err := sett.ScopedTo(channelID)
+	if err != nil {
+		log.Println(err)
+		return nil, false
+	}
+	msg, valid := fn(scoped, strings.Fields(strings.Join(args[2:], " ")))
+	if !valid {
+		return msg, false
+	}
+	sett.SetChannelOverride(channelID, args[1], scoped)
+	if str, ok := msg.(string); ok {
+		msg = scoped.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingChannelOverrides.set",
+			Other: "For games in {{.Channel}}: {{.Message}}",
+		},
+			map[string]interface{}{
+				"Channel": channel,
+				"Message": str,
+			})
+	}
+	return msg, true
+}
@@ -0,0 +1,67 @@
+package setting
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/automuteus/automuteus/v8/pkg/game"
+	"github.com/automuteus/automuteus/v8/pkg/settings"
+)
+
+func TestFnChannelOverrides(t *testing.T) {
+	sett, err := testSettingsFn(FnChannelOverrides)
+	if err != nil {
+		t.Error(err)
+	}
+	const (
+		channelID = "754465589958803548"
+		channel   = "<#" + channelID + ">"
+	)
+
+	for _, v := range [][]string{{channel, View}, {channel, Clear}, {"not a channel", Clear}, {channel, AdminUserIDs, "<@5678>"}, {channel, Delays, "lobby", "sometime", "3"}, {channel, Delays, "lobby", "tasks"}} {
+		_, valid := FnChannelOverrides(sett, v)
+		if valid {
+			t.Errorf("Channel override args %v shouldn't result in a valid settings change", v)
+		}
+	}
+
+	// the value can come as one option, or already split
+	_, valid := FnChannelOverrides(sett, []string{channel, Delays, "lobby tasks 9"})
+	if !valid {
+		t.Error("Overriding a channel's delays should result in a valid settings change")
+	}
+	_, valid = FnChannelOverrides(sett, []string{channel, MuteSpectators, "true"})
+	if !valid {
+		t.Error("Overriding a channel's mute-spectators should result in a valid settings change")
+	}
+	if sett.GetDelay(game.LOBBY, game.TASKS) == 9 || sett.GetMuteSpectator() {
+		t.Error("A channel's overrides shouldn't change the guild's settings")
+	}
+
+	co := sett.GetChannelOverrides(channelID)
+	if !reflect.DeepEqual(co.Names(), []string{settings.DelaysOverride, settings.MuteSpectatorsOverride}) {
+		t.Errorf("Expected delays and mute-spectators to be overridden, got %v", co.Names())
+	}
+
+	scoped, err := sett.ScopedTo(channelID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scoped.GetDelay(game.LOBBY, game.TASKS) != 9 || !scoped.GetMuteSpectator() {
+		t.Error("Expected the channel's settings to have its overrides")
+	}
+	if scoped.GetDelay(game.TASKS, game.DISCUSS) != sett.GetDelay(game.TASKS, game.DISCUSS) {
+		t.Error("Expected the channel's other delays to be the guild's")
+	}
+	if other, _ := sett.ScopedTo("754465589958803549"); other.GetDelay(game.LOBBY, game.TASKS) == 9 {
+		t.Error("Another channel shouldn't have the overrides")
+	}
+
+	_, valid = FnChannelOverrides(sett, []string{channel, Clear})
+	if !valid {
+		t.Error("Clearing a channel's overrides should result in a valid settings change")
+	}
+	if len(sett.GetChannelOverrides(channelID).Names()) != 0 {
+		t.Error("Expected the channel's overrides to be cleared")
+	}
+}
@@ -0,0 +1,50 @@
+package setting
+
+import (
+	"github.com/automuteus/automuteus/v8/pkg/discord"
+	"github.com/automuteus/automuteus/v8/pkg/settings"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// FnConflictLogChannel takes [view], [clear] or [channel]
+func FnConflictLogChannel(sett *settings.GuildSettings, args []string) (interface{}, bool) {
+	s := GetSettingByName(ConflictLogChannel)
+	if sett == nil {
+		return nil, false
+	}
+	if len(args) == 0 || args[0] == View {
+		value := ""
+		if sett.GetConflictLogChannelID() != "" {
+			value = discord.MentionByChannelID(sett.GetConflictLogChannelID())
+		}
+		return ConstructEmbedForSetting(value, s, sett), false
+	}
+
+	if args[0] == Clear {
+		sett.SetConflictLogChannelID("")
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingConflictLogChannel.cleared",
+			Other: "Mutes by others will still be left in place, but I won't say anything about them",
+		}), true
+	}
+
+	channelID, err := discord.ExtractChannelIDFromText(args[0])
+	if err != nil {
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingConflictLogChannel.invalidChannelID",
+			Other: "{{.channelID}} is not a valid text channel ID or mention!",
+		},
+			map[string]interface{}{
+				"channelID": args[0],
+			}), false
+	}
+
+	sett.SetConflictLogChannelID(channelID)
+	return sett.LocalizeMessage(&i18n.Message{
+		ID:    "settings.SettingConflictLogChannel.withChannelID",
+		Other: "I'll post in {{.channelID}} whenever someone else server mutes or deafens a player mid-game!",
+	},
+		map[string]interface{}{
+			"channelID": discord.MentionByChannelID(channelID),
+		}), true
+}
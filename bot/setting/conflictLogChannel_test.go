@@ -0,0 +1,35 @@
+package setting
+
+import (
+	"testing"
+)
+
+func TestFnConflictLogChannel(t *testing.T) {
+	sett, err := testSettingsFn(FnConflictLogChannel)
+	if err != nil {
+		t.Error(err)
+	}
+
+	_, valid := FnConflictLogChannel(sett, []string{View})
+	if valid {
+		t.Error("View shouldn't result in valid settings change")
+	}
+
+	_, valid = FnConflictLogChannel(sett, []string{"notanumber"})
+	if valid {
+		t.Error("Invalid conflict log channel should never result in a valid settings change")
+	}
+
+	_, valid = FnConflictLogChannel(sett, []string{"<#754788173384777943>"})
+	if !valid {
+		t.Error("Valid conflict log channel should result in a valid settings change")
+	}
+	if sett.GetConflictLogChannelID() != "754788173384777943" {
+		t.Error("Valid conflict log channel (\"754788173384777943\") was not set correctly")
+	}
+
+	_, valid = FnConflictLogChannel(sett, []string{Clear})
+	if !valid || sett.GetConflictLogChannelID() != "" {
+		t.Error("Expected the conflict log channel to be cleared")
+	}
+}
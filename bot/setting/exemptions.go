@@ -0,0 +1,88 @@
+package setting
+
+import (
+	"strings"
+
+	"github.com/automuteus/automuteus/v8/pkg/discord"
+	"github.com/automuteus/automuteus/v8/pkg/settings"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// FnExemptions takes [view], [clear], or a user or role mention to exempt, or stop exempting if it already is
+func FnExemptions(sett *settings.GuildSettings, args []string) (interface{}, bool) {
+	s := GetSettingByName(Exemptions)
+	if sett == nil {
+		return nil, false
+	}
+	if len(args) == 0 || args[0] == View {
+		var mentions []string
+		for _, v := range sett.GetExemptUserIDs() {
+			mentions = append(mentions, discord.MentionByUserID(v))
+		}
+		for _, v := range sett.GetExemptRoleIDs() {
+			mentions = append(mentions, "<@&"+v+">")
+		}
+		if len(mentions) == 0 {
+			return ConstructEmbedForSetting(sett.LocalizeMessage(&i18n.Message{
+				ID:    "settings.SettingExemptions.noExemptions",
+				Other: "Nobody is exempt",
+			}), s, sett), false
+		}
+		return ConstructEmbedForSetting(strings.Join(mentions, ", "), s, sett), false
+	}
+
+	if args[0] == Clear {
+		sett.ClearExemptions()
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingExemptions.cleared",
+			Other: "Nobody is exempt from being muted anymore",
+		}), true
+	}
+
+	var mention string
+	var exempt bool
+	if strings.HasPrefix(args[0], "<@&") {
+		roleID, err := discord.ExtractRoleIDFromText(args[0])
+		if err != nil {
+			return sett.LocalizeMessage(&i18n.Message{
+				ID:    "settings.SettingExemptions.notFound",
+				Other: "Sorry, I don't know who `{{.Mention}}` is. You can pass in a user or role @mention",
+			},
+				map[string]interface{}{
+					"Mention": args[0],
+				}), false
+		}
+		mention = "<@&" + roleID + ">"
+		exempt = sett.ToggleExemptRole(roleID)
+	} else {
+		userID, err := discord.ExtractUserIDFromText(args[0])
+		if err != nil {
+			return sett.LocalizeMessage(&i18n.Message{
+				ID:    "settings.SettingExemptions.notFound",
+				Other: "Sorry, I don't know who `{{.Mention}}` is. You can pass in a user or role @mention",
+			},
+				map[string]interface{}{
+					"Mention": args[0],
+				}), false
+		}
+		mention = discord.MentionByUserID(userID)
+		exempt = sett.ToggleExemptUser(userID)
+	}
+
+	if exempt {
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingExemptions.added",
+			Other: "I'll never mute or deafen {{.Mention}}",
+		},
+			map[string]interface{}{
+				"Mention": mention,
+			}), true
+	}
+	return sett.LocalizeMessage(&i18n.Message{
+		ID:    "settings.SettingExemptions.removed",
+		Other: "{{.Mention}} is no longer exempt, and will be muted like everyone else",
+	},
+		map[string]interface{}{
+			"Mention": mention,
+		}), true
+}
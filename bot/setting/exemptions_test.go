@@ -0,0 +1,42 @@
+package setting
+
+import (
+	"testing"
+)
+
+func TestFnExemptions(t *testing.T) {
+	sett, err := testSettingsFn(FnExemptions)
+	if err != nil {
+		t.Error(err)
+	}
+
+	for _, v := range [][]string{{View}, {"someone"}, {"<@&someone>"}} {
+		_, valid := FnExemptions(sett, v)
+		if valid {
+			t.Errorf("Exemption args %v shouldn't result in a valid settings change", v)
+		}
+	}
+
+	_, valid := FnExemptions(sett, []string{"<@140581581461463040>"})
+	if !valid || !sett.IsExempt("140581581461463040", nil) {
+		t.Error("Expected the user to be exempt")
+	}
+	_, valid = FnExemptions(sett, []string{"<@&754465589958803548>"})
+	if !valid || !sett.IsExempt("1", []string{"2", "754465589958803548"}) {
+		t.Error("Expected a member with the role to be exempt")
+	}
+	if sett.IsExempt("1", []string{"2"}) {
+		t.Error("Expected a member without the role not to be exempt")
+	}
+
+	// exempting them again takes the exemption back
+	_, valid = FnExemptions(sett, []string{"<@140581581461463040>"})
+	if !valid || sett.IsExempt("140581581461463040", nil) {
+		t.Error("Expected the user to no longer be exempt")
+	}
+
+	_, valid = FnExemptions(sett, []string{Clear})
+	if !valid || len(sett.GetExemptRoleIDs()) != 0 {
+		t.Error("Expected all exemptions to be cleared")
+	}
+}
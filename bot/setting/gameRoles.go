@@ -0,0 +1,84 @@
+package setting
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/automuteus/automuteus/v8/pkg/discord"
+	"github.com/automuteus/automuteus/v8/pkg/settings"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+var gameRoleStates = []string{settings.AliveGameRole, settings.DeadGameRole, settings.SpectatorGameRole}
+
+// FnGameRoles takes [view], [clear], [state] to stop giving a role for the state, or [state, role]
+func FnGameRoles(sett *settings.GuildSettings, args []string) (interface{}, bool) {
+	s := GetSettingByName(GameRoles)
+	if sett == nil {
+		return nil, false
+	}
+	if len(args) == 0 || args[0] == View {
+		var list []string
+		for _, state := range gameRoleStates {
+			if roleID := sett.GetGameRoleID(state); roleID != "" {
+				list = append(list, fmt.Sprintf("%s: <@&%s>", state, roleID))
+			}
+		}
+		if len(list) == 0 {
+			return ConstructEmbedForSetting(sett.LocalizeMessage(&i18n.Message{
+				ID:    "settings.SettingGameRoles.noRoles",
+				Other: "No Roles",
+			}), s, sett), false
+		}
+		return ConstructEmbedForSetting(strings.Join(list, "\n"), s, sett), false
+	}
+
+	if args[0] == Clear {
+		for _, state := range gameRoleStates {
+			sett.SetGameRoleID(state, "")
+		}
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingGameRoles.cleared",
+			Other: "Players won't be given any roles for their state in the game",
+		}), true
+	}
+
+	roleID := ""
+	if len(args) > 1 {
+		var err error
+		roleID, err = discord.ExtractRoleIDFromText(args[1])
+		if err != nil {
+			return sett.LocalizeMessage(&i18n.Message{
+				ID:    "settings.SettingGameRoles.notFound",
+				Other: "Sorry, I didn't recognize the role you provided",
+			}), false
+		}
+	}
+	if !sett.SetGameRoleID(args[0], roleID) {
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingGameRoles.unknownState",
+			Other: "`{{.State}}` isn't `alive`, `dead` or `spectator`!",
+		},
+			map[string]interface{}{
+				"State": args[0],
+			}), false
+	}
+
+	if roleID == "" {
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingGameRoles.removed",
+			Other: "{{.State}} players won't be given a role anymore",
+		},
+			map[string]interface{}{
+				"State": args[0],
+			}), true
+	}
+	return sett.LocalizeMessage(&i18n.Message{
+		ID:    "settings.SettingGameRoles.set",
+		Other: "{{.State}} players will be given the <@&{{.RoleID}}> role during games",
+	},
+		map[string]interface{}{
+			"State":  args[0],
+			"RoleID": roleID,
+		}), true
+}
@@ -0,0 +1,45 @@
+package setting
+
+import (
+	"testing"
+
+	"github.com/automuteus/automuteus/v8/pkg/settings"
+)
+
+func TestFnGameRoles(t *testing.T) {
+	sett, err := testSettingsFn(FnGameRoles)
+	if err != nil {
+		t.Error(err)
+	}
+
+	_, valid := FnGameRoles(sett, []string{View})
+	if valid {
+		t.Error("View shouldn't result in valid settings change")
+	}
+
+	for _, v := range [][]string{{"zombie", "<@&754788173384777943>"}, {settings.DeadGameRole, "notarole"}} {
+		_, valid = FnGameRoles(sett, v)
+		if valid {
+			t.Errorf("Invalid game role args %v shouldn't result in a valid settings change", v)
+		}
+	}
+
+	_, valid = FnGameRoles(sett, []string{settings.DeadGameRole, "<@&754788173384777943>"})
+	if !valid || sett.GetGameRoleID(settings.DeadGameRole) != "754788173384777943" {
+		t.Error("Valid dead role should result in a valid settings change")
+	}
+	_, valid = FnGameRoles(sett, []string{settings.AliveGameRole, "754788173384777944"})
+	if !valid || sett.GetGameRoleID(settings.AliveGameRole) != "754788173384777944" {
+		t.Error("Valid alive role should result in a valid settings change")
+	}
+
+	_, valid = FnGameRoles(sett, []string{settings.DeadGameRole})
+	if !valid || sett.GetGameRoleID(settings.DeadGameRole) != "" || sett.GetGameRoleID(settings.AliveGameRole) == "" {
+		t.Error("A state without a role should only remove that state's role")
+	}
+
+	_, valid = FnGameRoles(sett, []string{Clear})
+	if !valid || sett.GetGameRoleID(settings.AliveGameRole) != "" {
+		t.Error("Clear should remove every game role")
+	}
+}
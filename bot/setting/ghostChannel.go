@@ -0,0 +1,50 @@
+package setting
+
+import (
+	"github.com/automuteus/automuteus/v8/pkg/discord"
+	"github.com/automuteus/automuteus/v8/pkg/settings"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// FnGhostChannel takes [view], [clear] or [channel]
+func FnGhostChannel(sett *settings.GuildSettings, args []string) (interface{}, bool) {
+	s := GetSettingByName(GhostChannel)
+	if sett == nil {
+		return nil, false
+	}
+	if len(args) == 0 || args[0] == View {
+		value := ""
+		if sett.GetGhostChannelID() != "" {
+			value = discord.MentionByChannelID(sett.GetGhostChannelID())
+		}
+		return ConstructEmbedForSetting(value, s, sett), false
+	}
+
+	if args[0] == Clear {
+		sett.SetGhostChannelID("")
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingGhostChannel.cleared",
+			Other: "Dead players will be muted instead of moved to a ghost channel",
+		}), true
+	}
+
+	channelID, err := discord.ExtractChannelIDFromText(args[0])
+	if err != nil {
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingGhostChannel.invalidChannelID",
+			Other: "{{.channelID}} is not a valid voice channel ID or mention!",
+		},
+			map[string]interface{}{
+				"channelID": args[0],
+			}), false
+	}
+
+	sett.SetGhostChannelID(channelID)
+	return sett.LocalizeMessage(&i18n.Message{
+		ID:    "settings.SettingGhostChannel.withChannelID",
+		Other: "Dead players will be moved to {{.channelID}} during tasks, and back for discussions!",
+	},
+		map[string]interface{}{
+			"channelID": discord.MentionByChannelID(channelID),
+		}), true
+}
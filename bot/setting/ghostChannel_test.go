@@ -0,0 +1,35 @@
+package setting
+
+import (
+	"testing"
+)
+
+func TestFnGhostChannel(t *testing.T) {
+	sett, err := testSettingsFn(FnGhostChannel)
+	if err != nil {
+		t.Error(err)
+	}
+
+	_, valid := FnGhostChannel(sett, []string{View})
+	if valid {
+		t.Error("View shouldn't result in valid settings change")
+	}
+
+	_, valid = FnGhostChannel(sett, []string{"notanumber"})
+	if valid {
+		t.Error("Invalid ghost channel should never result in a valid settings change")
+	}
+
+	_, valid = FnGhostChannel(sett, []string{"<#754788173384777943>"})
+	if !valid {
+		t.Error("Valid ghost channel should result in a valid settings change")
+	}
+	if sett.GetGhostChannelID() != "754788173384777943" {
+		t.Error("Valid ghost channel (\"754788173384777943\") was not set correctly")
+	}
+
+	_, valid = FnGhostChannel(sett, []string{Clear})
+	if !valid || sett.GetGhostChannelID() != "" {
+		t.Error("Clear should turn the ghost channel off")
+	}
+}
@@ -0,0 +1,131 @@
+package setting
+
+import (
+	"strings"
+
+	"github.com/automuteus/automuteus/v8/pkg/discord"
+	"github.com/automuteus/automuteus/v8/pkg/settings"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// FnLateJoiners takes [], [policy] or [policy, channel], the channel being where to wait for settings.LateJoinerWait
+func FnLateJoiners(sett *settings.GuildSettings, args []string) (interface{}, bool) {
+	s := GetSettingByName(LateJoiners)
+	if sett == nil {
+		return nil, false
+	}
+	if len(args) == 0 {
+		value := sett.GetLateJoinerPolicy()
+		if sett.GetWaitingChannelID() != "" {
+			value += " (" + discord.MentionByChannelID(sett.GetWaitingChannelID()) + ")"
+		}
+		return ConstructEmbedForSetting(value, s, sett), false
+	}
+
+	policy := strings.ToLower(args[0])
+	valid := map[string]bool{
+		settings.LateJoinerIgnore:   true,
+		settings.LateJoinerSpectate: true,
+		settings.LateJoinerWait:     true,
+		settings.LateJoinerPing:     true,
+	}
+	if !valid[policy] {
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingLateJoiners.Unrecognized",
+			Other: "{{.Arg}} is not an expected value. See `/settings game late-joiners` for usage",
+		},
+			map[string]interface{}{
+				"Arg": policy,
+			}), false
+	}
+
+	channelID := sett.GetWaitingChannelID()
+	if len(args) > 1 {
+		var err error
+		channelID, err = discord.ExtractChannelIDFromText(args[1])
+		if err != nil {
+			return sett.LocalizeMessage(&i18n.Message{
+				ID:    "settings.SettingLateJoiners.invalidChannelID",
+				Other: "{{.channelID}} is not a valid voice channel ID or mention!",
+			},
+				map[string]interface{}{
+					"channelID": args[1],
+				}), false
+		}
+	}
+	if policy == settings.LateJoinerWait && channelID == "" {
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingLateJoiners.noWaitingChannel",
+			Other: "Late joiners need a voice channel to wait in. Pass one in along with `wait`",
+		}), false
+	}
+
+	sett.SetLateJoinerPolicy(policy)
+	sett.SetWaitingChannelID(channelID)
+	switch policy {
+	case settings.LateJoinerSpectate:
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingLateJoiners.spectate",
+			Other: "Unlinked members joining mid-round will be muted like spectators, until they're linked or the round is over",
+		}), true
+	case settings.LateJoinerWait:
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingLateJoiners.wait",
+			Other: "Unlinked members joining mid-round will be moved to {{.channelID}} to wait",
+		},
+			map[string]interface{}{
+				"channelID": discord.MentionByChannelID(channelID),
+			}), true
+	case settings.LateJoinerPing:
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingLateJoiners.ping",
+			Other: "I'll ping the host to link anyone joining mid-round without being linked",
+		}), true
+	default:
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingLateJoiners.ignore",
+			Other: "Unlinked members joining mid-round will be left alone until they're linked",
+		}), true
+	}
+}
+
+// FnLeavers takes [] or [policy]
+func FnLeavers(sett *settings.GuildSettings, args []string) (interface{}, bool) {
+	s := GetSettingByName(Leavers)
+	if sett == nil {
+		return nil, false
+	}
+	if len(args) == 0 {
+		return ConstructEmbedForSetting(sett.GetLeaverPolicy(), s, sett), false
+	}
+
+	policy := strings.ToLower(args[0])
+	switch policy {
+	case settings.LeaverIgnore:
+		sett.SetLeaverPolicy(policy)
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingLeavers.ignore",
+			Other: "Players leaving mid-round will stay linked",
+		}), true
+	case settings.LeaverUnlink:
+		sett.SetLeaverPolicy(policy)
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingLeavers.unlink",
+			Other: "Players leaving mid-round will be unlinked, freeing up their color",
+		}), true
+	case settings.LeaverPing:
+		sett.SetLeaverPolicy(policy)
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingLeavers.ping",
+			Other: "I'll ping the host whenever a linked player leaves mid-round",
+		}), true
+	default:
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingLeavers.Unrecognized",
+			Other: "{{.Arg}} is not an expected value. See `/settings game leavers` for usage",
+		},
+			map[string]interface{}{
+				"Arg": policy,
+			}), false
+	}
+}
@@ -0,0 +1,53 @@
+package setting
+
+import (
+	"testing"
+
+	"github.com/automuteus/automuteus/v8/pkg/settings"
+)
+
+func TestFnLateJoiners(t *testing.T) {
+	sett, err := testSettingsFn(FnLateJoiners)
+	if err != nil {
+		t.Error(err)
+	}
+
+	_, valid := FnLateJoiners(sett, []string{"kick"})
+	if valid {
+		t.Error("Unrecognized policy shouldn't result in a valid settings change")
+	}
+	_, valid = FnLateJoiners(sett, []string{settings.LateJoinerWait})
+	if valid {
+		t.Error("Waiting without a waiting channel shouldn't result in a valid settings change")
+	}
+	_, valid = FnLateJoiners(sett, []string{settings.LateJoinerWait, "notanumber"})
+	if valid {
+		t.Error("Invalid waiting channel should never result in a valid settings change")
+	}
+
+	_, valid = FnLateJoiners(sett, []string{settings.LateJoinerWait, "<#754788173384777943>"})
+	if !valid || sett.GetLateJoinerPolicy() != settings.LateJoinerWait || sett.GetWaitingChannelID() != "754788173384777943" {
+		t.Error("Expected late joiners to wait in the channel")
+	}
+	// the waiting channel is kept for next time
+	_, valid = FnLateJoiners(sett, []string{settings.LateJoinerSpectate})
+	if !valid || sett.GetLateJoinerPolicy() != settings.LateJoinerSpectate || sett.GetWaitingChannelID() != "754788173384777943" {
+		t.Error("Expected late joiners to be muted as spectators")
+	}
+}
+
+func TestFnLeavers(t *testing.T) {
+	sett, err := testSettingsFn(FnLeavers)
+	if err != nil {
+		t.Error(err)
+	}
+
+	_, valid := FnLeavers(sett, []string{"kick"})
+	if valid {
+		t.Error("Unrecognized policy shouldn't result in a valid settings change")
+	}
+	_, valid = FnLeavers(sett, []string{settings.LeaverUnlink})
+	if !valid || sett.GetLeaverPolicy() != settings.LeaverUnlink {
+		t.Error("Expected leavers to be unlinked")
+	}
+}
@@ -0,0 +1,87 @@
+package setting
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/automuteus/automuteus/v8/pkg/settings"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// FnPresets takes [view], [save, name] to save the current settings as a preset, or [remove, name]
+func FnPresets(sett *settings.GuildSettings, args []string) (interface{}, bool) {
+	s := GetSettingByName(Presets)
+	if sett == nil {
+		return nil, false
+	}
+	if len(args) == 0 || args[0] == View {
+		var list []string
+		for _, name := range sett.GetPresetNames() {
+			if _, builtin := settings.BuiltinPresets[name]; builtin {
+				list = append(list, fmt.Sprintf("`%s` (built-in)", name))
+			} else {
+				list = append(list, fmt.Sprintf("`%s`", name))
+			}
+		}
+		return ConstructEmbedForSetting(strings.Join(list, "\n"), s, sett), false
+	}
+
+	if len(args) < 2 || (args[0] != Save && args[0] != Remove) {
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingPresets.enoughArgs",
+			Other: "Correct syntax is: `presets [view/save/remove] [name]`",
+		}), false
+	}
+	name := strings.ToLower(strings.TrimSpace(args[1]))
+	if _, builtin := settings.BuiltinPresets[name]; builtin {
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingPresets.builtin",
+			Other: "`{{.Name}}` is a built-in preset, and can't be changed",
+		},
+			map[string]interface{}{
+				"Name": name,
+			}), false
+	}
+
+	if args[0] == Remove {
+		if !sett.DeletePreset(name) {
+			return sett.LocalizeMessage(&i18n.Message{
+				ID:    "settings.SettingPresets.notFound",
+				Other: "There's no preset called `{{.Name}}`",
+			},
+				map[string]interface{}{
+					"Name": name,
+				}), false
+		}
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingPresets.removed",
+			Other: "Removed the `{{.Name}}` preset",
+		},
+			map[string]interface{}{
+				"Name": name,
+			}), true
+	}
+
+	if !settings.IsValidPresetName(name) {
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingPresets.invalidName",
+			Other: "Preset names can only have up to 32 lowercase letters, numbers and dashes",
+		}), false
+	}
+	if !sett.SavePreset(name) {
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingPresets.tooMany",
+			Other: "You can't have more than {{.Max}} presets of your own. Remove one first",
+		},
+			map[string]interface{}{
+				"Max": settings.MaxPresets,
+			}), false
+	}
+	return sett.LocalizeMessage(&i18n.Message{
+		ID:    "settings.SettingPresets.saved",
+		Other: "Saved the current voice rules, delays, `unmute-dead` and `mute-spectators` as `{{.Name}}`. Use `/start preset:{{.Name}}` to play with them",
+	},
+		map[string]interface{}{
+			"Name": name,
+		}), true
+}
@@ -0,0 +1,64 @@
+package setting
+
+import (
+	"testing"
+
+	"github.com/automuteus/automuteus/v8/pkg/game"
+	"github.com/automuteus/automuteus/v8/pkg/settings"
+)
+
+func TestFnPresets(t *testing.T) {
+	sett, err := testSettingsFn(FnPresets)
+	if err != nil {
+		t.Error(err)
+	}
+
+	_, valid := FnPresets(sett, []string{View})
+	if valid {
+		t.Error("View shouldn't result in valid settings change")
+	}
+
+	for _, v := range [][]string{{Save}, {"load", "casual"}, {Save, settings.ClassicPreset}, {Remove, settings.GhostTalkPreset}, {Save, "no spaces"}, {Remove, "missing"}} {
+		_, valid = FnPresets(sett, v)
+		if valid {
+			t.Errorf("Invalid preset args %v shouldn't result in a valid settings change", v)
+		}
+	}
+
+	sett.SetVoiceRule(game.NormalMode, true, game.TASKS, "alive", false)
+	_, valid = FnPresets(sett, []string{Save, "Casual"})
+	if !valid {
+		t.Error("Saving a preset should result in a valid settings change")
+	}
+	sett.SetVoiceRule(game.NormalMode, true, game.TASKS, "alive", true)
+
+	preset, ok := sett.GetPreset("casual")
+	if !ok {
+		t.Fatal("Saved preset wasn't found")
+	}
+	if preset.VoiceRules.GetRule(true, game.TASKS, "alive") {
+		t.Error("Changing the guild's rules shouldn't change a saved preset")
+	}
+
+	// the preset only changes the settings it's applied to
+	sett.ApplyPreset(preset)
+	if mute, _ := sett.GetVoiceState(game.NormalMode, game.PlayerCategory, true, true, game.TASKS); mute {
+		t.Error("Applied preset's voice rules weren't used")
+	}
+
+	for i := 0; i < settings.MaxPresets-1; i++ {
+		sett.SavePreset(string(rune('a' + i)))
+	}
+	_, valid = FnPresets(sett, []string{Save, "onetoomany"})
+	if valid {
+		t.Errorf("Saving more than %d presets shouldn't result in a valid settings change", settings.MaxPresets)
+	}
+
+	_, valid = FnPresets(sett, []string{Remove, "casual"})
+	if !valid {
+		t.Error("Removing a preset should result in a valid settings change")
+	}
+	if _, ok := sett.GetPreset("casual"); ok {
+		t.Error("Removed preset was still found")
+	}
+}
@@ -18,10 +18,16 @@ const (
 
 	MaxMatchSummaryDelete float64 = 60
 
-	View  = "view"
-	Clear = "clear"
-	User  = "user"
-	Role  = "role"
+	MaxCaptureGrace float64 = 600
+
+	View   = "view"
+	Clear  = "clear"
+	User   = "user"
+	Role   = "role"
+	Add    = "add"
+	Remove = "remove"
+	Set    = "set"
+	Save   = "save"
 )
 
 var (
@@ -32,29 +38,53 @@ var (
 	MinLeaderBoardMin float64 = 1
 
 	MinMatchSummaryDelete float64 = -1
+
+	MinCaptureGrace float64 = -1
+)
+
+const (
+	Language              = "language"
+	VoiceRules            = "voice-rules"
+	HideAndSeekVoiceRules = "hide-and-seek-voice-rules"
+	AdminUserIDs          = "admin-user-ids"
+	RoleIDs               = "operator-roles"
+	UnmuteDead            = "unmute-dead"
+	MapVersion            = "map-version"
+	Delays                = "delays"
+	MatchSummary          = "match-summary-duration"
+	MatchSummaryChannel   = "match-summary-channel"
+	AutoRefresh           = "auto-refresh"
+	LeaderboardMention    = "leaderboard-mention"
+	LeaderboardSize       = "leaderboard-size"
+	LeaderboardMin        = "leaderboard-min"
+	MuteSpectators        = "mute-spectators"
+	GhostChannel          = "ghost-channel"
+	GameRoles             = "game-roles"
+	Exemptions            = "exemptions"
+	ConflictLogChannel    = "conflict-log-channel"
+	LateJoiners           = "late-joiners"
+	Leavers               = "leavers"
+	SyncNicknames         = "sync-nicknames"
+	Presets               = "presets"
+	DisplayRoomCode       = "display-room-code"
+	CaptureGrace          = "capture-grace"
+	Webhooks              = "webhooks"
+	ChannelOverrides      = "channel-overrides"
+	Simulate              = "simulate"
+	Show                  = "show"
+	List                  = "list"
+	Reset                 = "reset"
 )
 
+// settings in a group are a subcommand of the group, rather than one of the 25 options /settings can have
 const (
-	Language            = "language"
-	VoiceRules          = "voice-rules"
-	AdminUserIDs        = "admin-user-ids"
-	RoleIDs             = "operator-roles"
-	UnmuteDead          = "unmute-dead"
-	MapVersion          = "map-version"
-	Delays              = "delays"
-	MatchSummary        = "match-summary-duration"
-	MatchSummaryChannel = "match-summary-channel"
-	AutoRefresh         = "auto-refresh"
-	LeaderboardMention  = "leaderboard-mention"
-	LeaderboardSize     = "leaderboard-size"
-	LeaderboardMin      = "leaderboard-min"
-	MuteSpectators      = "mute-spectators"
-	DisplayRoomCode     = "display-room-code"
-	Show                = "show"
-	List                = "list"
-	Reset               = "reset"
+	GameGroup = "game"
 )
 
+var SettingGroups = map[string]string{
+	GameGroup: "How games are run",
+}
+
 func GetSettingByName(name string) *Setting {
 	for _, v := range AllSettings {
 		if v.Name == name {
@@ -64,6 +94,16 @@ func GetSettingByName(name string) *Setting {
 	return nil
 }
 
+// GetGroupedSetting finds the setting behind the subcommand of a group
+func GetGroupedSetting(group, name string) *Setting {
+	for _, v := range AllSettings {
+		if v.Group == group && v.Name == name {
+			return &v
+		}
+	}
+	return nil
+}
+
 func ToString(option *discordgo.ApplicationCommandInteractionDataOption) string {
 	switch option.Type {
 	case discordgo.ApplicationCommandOptionBoolean:
@@ -90,6 +130,16 @@ type Setting struct {
 	ShortDesc string
 	Arguments []*discordgo.ApplicationCommandOption
 	Premium   bool
+	// Group is one of SettingGroups, or empty for a setting of its own
+	Group string
+}
+
+// CommandName is what follows /settings to get to the setting
+func (s *Setting) CommandName() string {
+	if s.Group == "" {
+		return s.Name
+	}
+	return s.Group + " " + s.Name
 }
 
 var phaseChoices = []*discordgo.ApplicationCommandOptionChoice{
@@ -107,6 +157,77 @@ var phaseChoices = []*discordgo.ApplicationCommandOptionChoice{
 	},
 }
 
+func voiceRuleKeyChoices() []*discordgo.ApplicationCommandOptionChoice {
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, len(game.VoiceRuleKeys))
+	for i, v := range game.VoiceRuleKeys {
+		choices[i] = &discordgo.ApplicationCommandOptionChoice{
+			Name:  v,
+			Value: v,
+		}
+	}
+	return choices
+}
+
+func channelOverrideChoices() []*discordgo.ApplicationCommandOptionChoice {
+	choices := []*discordgo.ApplicationCommandOptionChoice{
+		{
+			Name:  View,
+			Value: View,
+		},
+		{
+			Name:  Clear,
+			Value: Clear,
+		},
+	}
+	for _, v := range settings.OverridableSettings {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{
+			Name:  v,
+			Value: v,
+		})
+	}
+	return choices
+}
+
+func voiceRulesArguments(phases []*discordgo.ApplicationCommandOptionChoice) []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "deaf-or-muted",
+			Description: "deaf-or-muted",
+			Choices: []*discordgo.ApplicationCommandOptionChoice{
+				{
+					Name:  "deafened",
+					Value: "deafened",
+				},
+				{
+					Name:  "muted",
+					Value: "muted",
+				},
+			},
+			Required: true,
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "phase",
+			Description: "phase",
+			Choices:     phases,
+			Required:    true,
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "alive",
+			Description: "alive, dead, or another group of players",
+			Choices:     voiceRuleKeyChoices(),
+			Required:    true,
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionBoolean,
+			Name:        "value",
+			Description: "value",
+		},
+	}
+}
+
 var AllSettings = []Setting{
 	{
 		Name:      List,
@@ -129,53 +250,48 @@ var AllSettings = []Setting{
 	{
 		Name:      VoiceRules,
 		ShortDesc: "Bot round behavior",
+		Arguments: voiceRulesArguments(phaseChoices),
+		Premium:   false,
+	},
+	{
+		Name:      HideAndSeekVoiceRules,
+		ShortDesc: "Bot round behavior in Hide and Seek",
+		// Hide and Seek has no meetings
+		Arguments: voiceRulesArguments(phaseChoices[:2]),
+		Premium:   false,
+	},
+	{
+		Name:      Presets,
+		ShortDesc: "Voice Rule Presets to start games with",
 		Arguments: []*discordgo.ApplicationCommandOption{
 			{
 				Type:        discordgo.ApplicationCommandOptionString,
-				Name:        "deaf-or-muted",
-				Description: "deaf-or-muted",
+				Name:        "action",
+				Description: "action",
 				Choices: []*discordgo.ApplicationCommandOptionChoice{
 					{
-						Name:  "deafened",
-						Value: "deafened",
-					},
-					{
-						Name:  "muted",
-						Value: "muted",
+						Name:  View,
+						Value: View,
 					},
-				},
-				Required: true,
-			},
-			{
-				Type:        discordgo.ApplicationCommandOptionString,
-				Name:        "phase",
-				Description: "phase",
-				Choices:     phaseChoices,
-				Required:    true,
-			},
-			{
-				Type:        discordgo.ApplicationCommandOptionString,
-				Name:        "alive",
-				Description: "alive",
-				Choices: []*discordgo.ApplicationCommandOptionChoice{
 					{
-						Name:  "alive",
-						Value: "alive",
+						Name:  Save,
+						Value: Save,
 					},
 					{
-						Name:  "dead",
-						Value: "dead",
+						Name:  Remove,
+						Value: Remove,
 					},
 				},
 				Required: true,
 			},
 			{
-				Type:        discordgo.ApplicationCommandOptionBoolean,
-				Name:        "value",
-				Description: "value",
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "name",
+				Description: "Name of the preset",
 			},
 		},
 		Premium: false,
+		Group:   GameGroup,
 	},
 	{
 		Name:      AdminUserIDs,
@@ -380,6 +496,233 @@ var AllSettings = []Setting{
 		},
 		Premium: true,
 	},
+	{
+		Name:      GhostChannel,
+		ShortDesc: "Voice Channel for Dead Players",
+		Arguments: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        View,
+				Description: "View the Ghost Channel",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+			},
+			{
+				Name:        Clear,
+				Description: "Mute dead players instead",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+			},
+			{
+				Name:        Set,
+				Description: "Move dead players to a voice channel during tasks",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:         discordgo.ApplicationCommandOptionChannel,
+						Name:         "channel",
+						Description:  "channel",
+						ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildVoice},
+						Required:     true,
+					},
+				},
+			},
+		},
+		Premium: false,
+	},
+	{
+		Name:      GameRoles,
+		ShortDesc: "Roles for Alive, Dead and Spectating Players",
+		Arguments: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        View,
+				Description: "View the Roles",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+			},
+			{
+				Name:        Clear,
+				Description: "Stop giving Roles",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+			},
+			{
+				Name:        Set,
+				Description: "Give players a Role for their state during games",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Type:        discordgo.ApplicationCommandOptionString,
+						Name:        "state",
+						Description: "state",
+						Choices: []*discordgo.ApplicationCommandOptionChoice{
+							{
+								Name:  settings.AliveGameRole,
+								Value: settings.AliveGameRole,
+							},
+							{
+								Name:  settings.DeadGameRole,
+								Value: settings.DeadGameRole,
+							},
+							{
+								Name:  settings.SpectatorGameRole,
+								Value: settings.SpectatorGameRole,
+							},
+						},
+						Required: true,
+					},
+					{
+						Type:        discordgo.ApplicationCommandOptionRole,
+						Name:        "role",
+						Description: "Role to give, or none to stop giving one",
+					},
+				},
+			},
+		},
+		Premium: false,
+	},
+	{
+		Name:      Exemptions,
+		ShortDesc: "Users and Roles never to Mute",
+		Arguments: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "action",
+				Description: "View or clear who's exempt",
+				Type:        discordgo.ApplicationCommandOptionString,
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{
+						Name:  View,
+						Value: View,
+					},
+					{
+						Name:  Clear,
+						Value: Clear,
+					},
+				},
+				Required: false,
+			},
+			{
+				Name:        User,
+				Description: "Exempt a user, or stop exempting them",
+				Type:        discordgo.ApplicationCommandOptionUser,
+				Required:    false,
+			},
+			{
+				Name:        Role,
+				Description: "Exempt a role, or stop exempting it",
+				Type:        discordgo.ApplicationCommandOptionRole,
+				Required:    false,
+			},
+		},
+		Premium: false,
+		Group:   GameGroup,
+	},
+	{
+		Name:      ConflictLogChannel,
+		ShortDesc: "Channel told about Mutes by Others",
+		Arguments: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "action",
+				Description: "View or clear the channel",
+				Type:        discordgo.ApplicationCommandOptionString,
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{
+						Name:  View,
+						Value: View,
+					},
+					{
+						Name:  Clear,
+						Value: Clear,
+					},
+				},
+				Required: false,
+			},
+			{
+				Type:         discordgo.ApplicationCommandOptionChannel,
+				Name:         "channel",
+				Description:  "channel",
+				ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildText},
+				Required:     false,
+			},
+		},
+		Premium: false,
+		Group:   GameGroup,
+	},
+	{
+		Name:      LateJoiners,
+		ShortDesc: "What to do about Unlinked Members joining Mid-Round",
+		Arguments: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "policy",
+				Description: "What to do about them",
+				Type:        discordgo.ApplicationCommandOptionString,
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{
+						Name:  settings.LateJoinerIgnore,
+						Value: settings.LateJoinerIgnore,
+					},
+					{
+						Name:  settings.LateJoinerSpectate,
+						Value: settings.LateJoinerSpectate,
+					},
+					{
+						Name:  settings.LateJoinerWait,
+						Value: settings.LateJoinerWait,
+					},
+					{
+						Name:  settings.LateJoinerPing,
+						Value: settings.LateJoinerPing,
+					},
+				},
+				Required: false,
+			},
+			{
+				Type:         discordgo.ApplicationCommandOptionChannel,
+				Name:         "channel",
+				Description:  "Voice channel for them to wait in",
+				ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildVoice},
+				Required:     false,
+			},
+		},
+		Premium: false,
+		Group:   GameGroup,
+	},
+	{
+		Name:      Leavers,
+		ShortDesc: "What to do about Players leaving Mid-Round",
+		Arguments: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        "policy",
+				Description: "What to do about them",
+				Type:        discordgo.ApplicationCommandOptionString,
+				Choices: []*discordgo.ApplicationCommandOptionChoice{
+					{
+						Name:  settings.LeaverIgnore,
+						Value: settings.LeaverIgnore,
+					},
+					{
+						Name:  settings.LeaverUnlink,
+						Value: settings.LeaverUnlink,
+					},
+					{
+						Name:  settings.LeaverPing,
+						Value: settings.LeaverPing,
+					},
+				},
+				Required: false,
+			},
+		},
+		Premium: false,
+		Group:   GameGroup,
+	},
+	{
+		Name:      SyncNicknames,
+		ShortDesc: "Rename Players after their In-Game Name",
+		Arguments: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionBoolean,
+				Name:        "sync",
+				Description: "sync",
+			},
+		},
+		Premium: false,
+		Group:   GameGroup,
+	},
 	{
 		Name:      DisplayRoomCode,
 		ShortDesc: "Visibility for the ROOM CODE",
@@ -406,6 +749,108 @@ var AllSettings = []Setting{
 		},
 		Premium: true,
 	},
+	{
+		Name:      CaptureGrace,
+		ShortDesc: "Unmute All When Capture Goes Silent",
+		Arguments: []*discordgo.ApplicationCommandOption{
+			{
+				Type:        discordgo.ApplicationCommandOptionInteger,
+				Name:        "seconds",
+				Description: "seconds",
+				MinValue:    &MinCaptureGrace,
+				MaxValue:    MaxCaptureGrace,
+			},
+		},
+		Premium: false,
+		Group:   GameGroup,
+	},
+	{
+		Name:      Webhooks,
+		ShortDesc: "Webhooks for Game Events",
+		Arguments: []*discordgo.ApplicationCommandOption{
+			{
+				Name:        View,
+				Description: "View Webhooks",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+			},
+			{
+				Name:        Clear,
+				Description: "Remove all Webhooks",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+			},
+			{
+				Name:        Add,
+				Description: "Add a Webhook",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Name:        "url",
+						Description: "URL to POST game events to",
+						Type:        discordgo.ApplicationCommandOptionString,
+						Required:    true,
+					},
+					{
+						Name:        "events",
+						Description: "Comma-separated events to send, or all of them if empty",
+						Type:        discordgo.ApplicationCommandOptionString,
+					},
+				},
+			},
+			{
+				Name:        Remove,
+				Description: "Remove a Webhook",
+				Type:        discordgo.ApplicationCommandOptionSubCommand,
+				Options: []*discordgo.ApplicationCommandOption{
+					{
+						Name:        "id",
+						Description: "ID of the Webhook to remove",
+						Type:        discordgo.ApplicationCommandOptionString,
+						Required:    true,
+					},
+				},
+			},
+		},
+		Premium: false,
+	},
+	{
+		Name:      ChannelOverrides,
+		ShortDesc: "Settings of a Voice Channel's own",
+		Arguments: []*discordgo.ApplicationCommandOption{
+			{
+				Type:         discordgo.ApplicationCommandOptionChannel,
+				Name:         "channel",
+				Description:  "channel",
+				ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildVoice, discordgo.ChannelTypeGuildStageVoice},
+				Required:     true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "setting",
+				Description: "Setting to change for games in the channel, or view/clear all of them",
+				Choices:     channelOverrideChoices(),
+				Required:    true,
+			},
+			{
+				Type:        discordgo.ApplicationCommandOptionString,
+				Name:        "value",
+				Description: "What you'd type after /settings <setting>, or nothing to see the channel's value",
+			},
+		},
+		Premium: false,
+	},
+	{
+		Name:      Simulate,
+		ShortDesc: "Try the Voice Rules and Delays on a Game",
+		Arguments: []*discordgo.ApplicationCommandOption{
+			{
+				Type:         discordgo.ApplicationCommandOptionChannel,
+				Name:         "channel",
+				Description:  "Voice channel whose settings to use, if it has its own",
+				ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildVoice, discordgo.ChannelTypeGuildStageVoice},
+			},
+		},
+		Premium: false,
+	},
 	{
 		Name:      Show,
 		ShortDesc: "Show All Current Settings",
@@ -436,7 +881,7 @@ func ConstructEmbedForSetting(value string, setting *Setting, sett *settings.Gui
 		ID:    "settings.ConstructEmbedForSetting.StarterDesc",
 		Other: "Type `/settings {{.Command}}` to view or change this setting.\n\n",
 	}, map[string]interface{}{
-		"Command": setting.Name,
+		"Command": setting.CommandName(),
 	})
 	return discordgo.MessageEmbed{
 		URL:         "",
@@ -0,0 +1,83 @@
+package setting
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/automuteus/automuteus/v8/pkg/discord"
+	"github.com/automuteus/automuteus/v8/pkg/settings"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+func simulatedStateString(state settings.SimulatedVoiceState) string {
+	switch {
+	case state.Mute && state.Deaf:
+		return "mute+deaf"
+	case state.Mute:
+		return "mute"
+	case state.Deaf:
+		return "deaf"
+	default:
+		return "-"
+	}
+}
+
+// simulationTable lays the steps out one per line, with the delay and everyone's voice state after it
+func simulationTable(steps []settings.SimulationStep) string {
+	header := fmt.Sprintf("%-12s%-7s", "step", "delay")
+	for _, player := range settings.SimulatedPlayers {
+		header += fmt.Sprintf("%-11s", player)
+	}
+	lines := []string{strings.TrimSpace(header)}
+	for _, step := range steps {
+		delay := "-"
+		if step.Changed {
+			delay = fmt.Sprintf("%ds", step.Delay)
+		}
+		line := fmt.Sprintf("%-12s%-7s", step.Step, delay)
+		for _, player := range settings.SimulatedPlayers {
+			line += fmt.Sprintf("%-11s", simulatedStateString(step.States[player]))
+		}
+		lines = append(lines, strings.TrimSpace(line))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// FnSimulate takes [] or [channel], to simulate a match in a voice channel with settings of its own
+func FnSimulate(sett *settings.GuildSettings, args []string) (interface{}, bool) {
+	if sett == nil {
+		return nil, false
+	}
+	scoped := sett
+	intro := sett.LocalizeMessage(&i18n.Message{
+		ID:    "settings.SettingSimulate.intro",
+		Other: "Here's who I'd mute or deafen during a game, and how many seconds after each step:",
+	})
+	if len(args) > 0 {
+		channelID, err := discord.ExtractChannelIDFromText(args[0])
+		if err != nil {
+			return sett.LocalizeMessage(&i18n.Message{
+				ID:    "settings.SettingSimulate.invalidChannelID",
+				Other: "{{.channelID}} is not a valid voice channel ID or mention!",
+			},
+				map[string]interface{}{
+					"channelID": args[0],
+				}), false
+		}
+		scoped, err = sett.ScopedTo(channelID)
+		if err != nil {
+			log.Println(err)
+			return nil, false
+		}
+		intro = scoped.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingSimulate.introChannel",
+			Other: "Here's who I'd mute or deafen during a game in {{.Channel}}, and how many seconds after each step:",
+		},
+			map[string]interface{}{
+				"Channel": discord.MentionByChannelID(channelID),
+			})
+	}
+
+	return fmt.Sprintf("%s\n```\n%s\n```", intro, simulationTable(scoped.SimulateVoiceRules())), false
+}
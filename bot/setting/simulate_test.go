@@ -0,0 +1,66 @@
+package setting
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/automuteus/automuteus/v8/pkg/game"
+	"github.com/automuteus/automuteus/v8/pkg/settings"
+)
+
+func TestFnSimulate(t *testing.T) {
+	sett, err := testSettingsFn(FnSimulate)
+	if err != nil {
+		t.Error(err)
+	}
+
+	_, valid := FnSimulate(sett, []string{"not a channel"})
+	if valid {
+		t.Error("Simulating should never result in a valid settings change")
+	}
+
+	sett.SetDelay(game.TASKS, game.DISCUSS, 4)
+	steps := sett.SimulateVoiceRules()
+	names := make([]string, len(steps))
+	for i, v := range steps {
+		names[i] = v.Step
+	}
+	expected := []string{settings.LobbyStep, settings.TasksStep, settings.KillStep, settings.DiscussionStep, settings.ExileStep, settings.TasksStep, settings.GameOverStep}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Fatalf("Expected the steps %v, got %v", expected, names)
+	}
+	if steps[3].Delay != 4 {
+		t.Errorf("Expected the discussion to be 4 seconds after the meeting is called, got %d", steps[3].Delay)
+	}
+
+	// the killed player keeps the alive players' state until the meeting
+	if kill := steps[2]; kill.Changed || !kill.States[settings.SimulatedKilled].Mute {
+		t.Errorf("Expected the kill not to change anything, got %+v", kill)
+	}
+	if meeting := steps[3].States; !meeting[settings.SimulatedKilled].Mute || meeting[settings.SimulatedExiled].Mute {
+		t.Errorf("Expected the killed player to be muted in the meeting and the exiled one not, got %+v", meeting)
+	}
+	if tasks := steps[5].States; tasks[settings.SimulatedExiled].Mute || !tasks[settings.SimulatedAlive].Deaf {
+		t.Errorf("Expected the exiled player to be free to talk during tasks, got %+v", tasks)
+	}
+	for _, v := range steps {
+		if v.States[settings.SimulatedSpectator] != (settings.SimulatedVoiceState{}) {
+			t.Errorf("Spectators shouldn't be touched unless they're muted like the dead, got %+v", v)
+		}
+	}
+
+	sett.SetUnmuteDeadDuringTasks(true)
+	sett.SetMuteSpectator(true)
+	steps = sett.SimulateVoiceRules()
+	if kill := steps[2]; !kill.Changed || kill.States[settings.SimulatedKilled].Mute {
+		t.Errorf("Expected the killed player to be unmuted right away, got %+v", kill)
+	}
+	if meeting := steps[3].States[settings.SimulatedSpectator]; !meeting.Mute {
+		t.Error("Expected spectators to be muted in the meeting like the dead")
+	}
+
+	msg, _ := FnSimulate(sett, []string{})
+	if str, ok := msg.(string); !ok || !strings.Contains(str, settings.SimulatedSpectator) {
+		t.Errorf("Expected a table of the simulated players, got %v", msg)
+	}
+}
@@ -0,0 +1,55 @@
+package setting
+
+import (
+	"github.com/automuteus/automuteus/v8/pkg/settings"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+func FnSyncNicknames(sett *settings.GuildSettings, args []string) (interface{}, bool) {
+	s := GetSettingByName(SyncNicknames)
+	if sett == nil {
+		return nil, false
+	}
+	syncNicks := sett.GetSyncNicknames()
+	if len(args) == 0 {
+		current := "false"
+		if syncNicks {
+			current = "true"
+		}
+		return ConstructEmbedForSetting(current, s, sett), false
+	}
+	switch args[0] {
+	case "true":
+		if syncNicks {
+			return sett.LocalizeMessage(&i18n.Message{
+				ID:    "settings.already_true",
+				Other: "It's already true!",
+			}), false
+		}
+		sett.SetSyncNicknames(true)
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingSyncNicknames.true",
+			Other: "Linked players will be renamed after their in-game name and color while a game is running, and get their nickname back after.\n**Note, I can't rename the server owner, or anyone with a role above mine!**",
+		}), true
+	case "false":
+		if !syncNicks {
+			return sett.LocalizeMessage(&i18n.Message{
+				ID:    "settings.already_false",
+				Other: "It's already false!",
+			}), false
+		}
+		sett.SetSyncNicknames(false)
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingSyncNicknames.false",
+			Other: "I will no longer rename linked players",
+		}), true
+	default:
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingUnmuteDeadDuringTasks.wrongArg",
+			Other: "Sorry, `{{.Arg}}` is neither `true` nor `false`.",
+		},
+			map[string]interface{}{
+				"Arg": args[0],
+			}), false
+	}
+}
@@ -0,0 +1,30 @@
+package setting
+
+import "testing"
+
+func TestFnSyncNicknames(t *testing.T) {
+	sett, err := testSettingsFn(FnSyncNicknames)
+	if err != nil {
+		t.Error(err)
+	}
+
+	_, valid := FnSyncNicknames(sett, []string{"nottrueorfalse"})
+	if valid {
+		t.Error("Invalid sync nicknames arg should never result in a valid settings change")
+	}
+
+	_, valid = FnSyncNicknames(sett, []string{"false"})
+	if valid {
+		t.Error("Identical sync nicknames arg to default should never result in a valid settings change")
+	}
+
+	_, valid = FnSyncNicknames(sett, []string{"true"})
+	if !valid || !sett.GetSyncNicknames() {
+		t.Error("Valid sync nicknames arg (\"true\") was not set correctly")
+	}
+
+	_, valid = FnSyncNicknames(sett, []string{"false"})
+	if !valid || sett.GetSyncNicknames() {
+		t.Error("Valid sync nicknames arg (\"false\") was not set correctly")
+	}
+}
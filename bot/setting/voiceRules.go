@@ -1,12 +1,22 @@
 package setting
 
 import (
+	"strings"
+
 	"github.com/automuteus/automuteus/v8/pkg/game"
 	"github.com/automuteus/automuteus/v8/pkg/settings"
 	"github.com/nicksnyder/go-i18n/v2/i18n"
 )
 
 func FnVoiceRules(sett *settings.GuildSettings, args []string) (interface{}, bool) {
+	return fnVoiceRulesForMode(sett, game.NormalMode, args)
+}
+
+func FnHideAndSeekVoiceRules(sett *settings.GuildSettings, args []string) (interface{}, bool) {
+	return fnVoiceRulesForMode(sett, game.HideAndSeekMode, args)
+}
+
+func fnVoiceRulesForMode(sett *settings.GuildSettings, mode game.GameMode, args []string) (interface{}, bool) {
 	if sett == nil {
 		return nil, false
 	}
@@ -16,7 +26,7 @@ func FnVoiceRules(sett *settings.GuildSettings, args []string) (interface{}, boo
 		// User didn't pass enough args
 		return sett.LocalizeMessage(&i18n.Message{
 			ID:    "settings.SettingVoiceRules.enoughArgs",
-			Other: "You didn't pass enough arguments! Correct syntax is: `voiceRules [muted/deafened] [game phase] [alive/dead] [true/false]`",
+			Other: "You didn't pass enough arguments! Correct syntax is: `voiceRules [muted/deafened] [game phase] [alive/dead/spectator/impostor-alive/impostor-dead/crewmate-alive/crewmate-dead] [true/false]`",
 		}), false
 	}
 
@@ -31,17 +41,23 @@ func FnVoiceRules(sett *settings.GuildSettings, args []string) (interface{}, boo
 			}), false
 	}
 
-	if args[2] != "alive" && args[2] != "dead" {
+	if !game.IsVoiceRuleKey(args[2]) {
 		return sett.LocalizeMessage(&i18n.Message{
-			ID:    "settings.SettingVoiceRules.neitherAliveDead",
-			Other: "`{{.Arg}}` is neither `alive` or `dead`!",
+			ID:    "settings.SettingVoiceRules.unknownPlayers",
+			Other: "`{{.Arg}}` isn't one of {{.Players}}!",
 		},
 			map[string]interface{}{
-				"Arg": args[2],
+				"Arg":     args[2],
+				"Players": "`" + strings.Join(game.VoiceRuleKeys, "`, `") + "`",
 			}), false
 	}
+	// e.g. "dead impostor" for "impostor-dead"
+	who := args[2]
+	if category, alive, found := strings.Cut(who, "-"); found {
+		who = alive + " " + category
+	}
 
-	oldValue := sett.GetVoiceRule(args[0] == "muted", gamePhase, args[2])
+	oldValue := sett.GetVoiceRule(mode, args[0] == "muted", gamePhase, args[2])
 
 	if len(args) == 3 {
 		// User was only querying
@@ -52,7 +68,7 @@ func FnVoiceRules(sett *settings.GuildSettings, args []string) (interface{}, boo
 			},
 				map[string]interface{}{
 					"PhaseName":          args[1],
-					"PlayerGameState":    args[2],
+					"PlayerGameState":    who,
 					"PlayerDiscordState": args[0],
 				}), false
 		} else {
@@ -62,7 +78,7 @@ func FnVoiceRules(sett *settings.GuildSettings, args []string) (interface{}, boo
 			},
 				map[string]interface{}{
 					"PhaseName":          args[1],
-					"PlayerGameState":    args[2],
+					"PlayerGameState":    who,
 					"PlayerDiscordState": args[0],
 				}), false
 		}
@@ -77,7 +93,7 @@ func FnVoiceRules(sett *settings.GuildSettings, args []string) (interface{}, boo
 			},
 				map[string]interface{}{
 					"PhaseName":          args[1],
-					"PlayerGameState":    args[2],
+					"PlayerGameState":    who,
 					"PlayerDiscordState": args[0],
 				}), false
 		} else {
@@ -87,16 +103,16 @@ func FnVoiceRules(sett *settings.GuildSettings, args []string) (interface{}, boo
 			},
 				map[string]interface{}{
 					"PhaseName":          args[1],
-					"PlayerGameState":    args[2],
+					"PlayerGameState":    who,
 					"PlayerDiscordState": args[0],
 				}), false
 		}
 	}
 
 	if args[0] == "muted" {
-		sett.SetVoiceRule(true, gamePhase, args[2], newValue)
+		sett.SetVoiceRule(mode, true, gamePhase, args[2], newValue)
 	} else {
-		sett.SetVoiceRule(false, gamePhase, args[2], newValue)
+		sett.SetVoiceRule(mode, false, gamePhase, args[2], newValue)
 	}
 
 	if newValue {
@@ -106,7 +122,7 @@ func FnVoiceRules(sett *settings.GuildSettings, args []string) (interface{}, boo
 		},
 			map[string]interface{}{
 				"PhaseName":          args[1],
-				"PlayerGameState":    args[2],
+				"PlayerGameState":    who,
 				"PlayerDiscordState": args[0],
 			}), true
 	} else {
@@ -116,7 +132,7 @@ func FnVoiceRules(sett *settings.GuildSettings, args []string) (interface{}, boo
 		},
 			map[string]interface{}{
 				"PhaseName":          args[1],
-				"PlayerGameState":    args[2],
+				"PlayerGameState":    who,
 				"PlayerDiscordState": args[0],
 			}), true
 	}
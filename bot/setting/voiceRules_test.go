@@ -56,3 +56,92 @@ func TestFnVoiceRules(t *testing.T) {
 		t.Error("Valid VR rule change was not changed successfully!")
 	}
 }
+
+func TestFnHideAndSeekVoiceRules(t *testing.T) {
+	sett, err := testSettingsFn(FnHideAndSeekVoiceRules)
+	if err != nil {
+		t.Error(err)
+	}
+
+	// settings saved before Hide and Seek was supported
+	sett.HideAndSeekVoiceRules = game.VoiceRules{}
+	_, valid := FnHideAndSeekVoiceRules(sett, []string{"muted", "tasks", "dead"})
+	if valid {
+		t.Error("Querying VR args should never result in a valid settings change")
+	}
+
+	_, valid = FnHideAndSeekVoiceRules(sett, []string{"muted", "tasks", "alive", "true"})
+	if !valid {
+		t.Error("Valid VR rules should result in a valid settings change")
+	}
+	if !sett.HideAndSeekVoiceRules.MuteRules[game.PhaseNames[game.TASKS]]["alive"] {
+		t.Error("Valid VR rule change was not changed successfully!")
+	}
+	if sett.VoiceRules.MuteRules[game.PhaseNames[game.TASKS]]["alive"] != true || sett.VoiceRules.DeafRules[game.PhaseNames[game.TASKS]]["alive"] != true {
+		t.Error("Hide and Seek rules shouldn't change the normal rules")
+	}
+	if mute, _ := sett.GetVoiceState(game.HideAndSeekMode, game.PlayerCategory, false, true, game.TASKS); !mute {
+		t.Error("Caught players should be muted during Hide and Seek by default")
+	}
+}
+
+func TestFnVoiceRulesCategories(t *testing.T) {
+	sett, err := testSettingsFn(FnVoiceRules)
+	if err != nil {
+		t.Error(err)
+	}
+
+	// settings saved before spectators had rules of their own
+	for _, rules := range []map[game.PhaseNameString]map[string]bool{sett.VoiceRules.MuteRules, sett.VoiceRules.DeafRules} {
+		for _, v := range rules {
+			delete(v, "spectator")
+		}
+	}
+	if mute, _ := sett.GetVoiceState(game.NormalMode, game.SpectatorCategory, false, true, game.DISCUSS); !mute {
+		t.Error("Spectators should follow the dead players' rules when theirs aren't set")
+	}
+	_, valid := FnVoiceRules(sett, []string{"muted", "tasks", "dead", "true"})
+	if !valid {
+		t.Error("Valid dead VR rules should result in a valid settings change")
+	}
+	if mute, _ := sett.GetVoiceState(game.NormalMode, game.SpectatorCategory, false, true, game.TASKS); !mute {
+		t.Error("Spectators should keep following later changes to the dead players' rules")
+	}
+	_, _ = FnVoiceRules(sett, []string{"muted", "tasks", "dead", "false"})
+
+	// spectators can talk during tasks, but not in discussion
+	_, valid = FnVoiceRules(sett, []string{"muted", "tasks", "spectator", "false"})
+	if valid {
+		t.Error("Setting VR rules to the existing values should never result in a valid settings change")
+	}
+	_, valid = FnVoiceRules(sett, []string{"muted", "discussion", "spectator", "false"})
+	if !valid {
+		t.Error("Valid spectator VR rules should result in a valid settings change")
+	}
+	if mute, _ := sett.GetVoiceState(game.NormalMode, game.SpectatorCategory, false, true, game.DISCUSS); mute {
+		t.Error("Spectator VR rule change was not changed successfully!")
+	}
+	if mute, _ := sett.GetVoiceState(game.NormalMode, game.PlayerCategory, false, true, game.DISCUSS); !mute {
+		t.Error("Spectator VR rules shouldn't change the dead players' rules")
+	}
+
+	// revealed roles follow the player rules until they're set
+	if mute, deaf := sett.GetVoiceState(game.NormalMode, game.ImpostorCategory, true, true, game.LOBBY); mute || deaf {
+		t.Error("Impostors should follow the alive players' rules when theirs aren't set")
+	}
+	_, valid = FnVoiceRules(sett, []string{"muted", "lobby", "impostor-alive", "true"})
+	if !valid {
+		t.Error("Valid impostor VR rules should result in a valid settings change")
+	}
+	if mute, _ := sett.GetVoiceState(game.NormalMode, game.ImpostorCategory, true, true, game.LOBBY); !mute {
+		t.Error("Impostor VR rule change was not changed successfully!")
+	}
+	if mute, _ := sett.GetVoiceState(game.NormalMode, game.CrewmateCategory, true, true, game.LOBBY); mute {
+		t.Error("Impostor VR rules shouldn't change the crewmates' rules")
+	}
+
+	_, valid = FnVoiceRules(sett, []string{"muted", "lobby", "ghost-alive", "true"})
+	if valid {
+		t.Error("Invalid VR args should never result in a valid settings change")
+	}
+}
@@ -0,0 +1,125 @@
+package setting
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/automuteus/automuteus/v8/pkg/settings"
+	"github.com/automuteus/automuteus/v8/pkg/webhook"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// FnWebhooks takes [view], [clear], [url] or [url, events] to add a webhook, or [id] to remove one
+func FnWebhooks(sett *settings.GuildSettings, args []string) (interface{}, bool) {
+	s := GetSettingByName(Webhooks)
+	if sett == nil {
+		return nil, false
+	}
+	if len(args) == 0 || args[0] == View {
+		subs := sett.GetWebhooks()
+		if len(subs) == 0 {
+			return ConstructEmbedForSetting(sett.LocalizeMessage(&i18n.Message{
+				ID:    "settings.SettingWebhooks.noWebhooks",
+				Other: "No Webhooks",
+			}), s, sett), false
+		}
+		var list []string
+		for _, v := range subs {
+			list = append(list, fmt.Sprintf("`%s` %s (%s)", v.ID, v.URL, eventsString(v.Events)))
+		}
+		return ConstructEmbedForSetting(strings.Join(list, "\n"), s, sett), false
+	}
+
+	if args[0] == Clear {
+		sett.ClearWebhooks()
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingWebhooks.cleared",
+			Other: "Removed all webhooks!",
+		}), true
+	}
+
+	if !strings.HasPrefix(args[0], "http://") && !strings.HasPrefix(args[0], "https://") {
+		if !sett.RemoveWebhook(args[0]) {
+			return sett.LocalizeMessage(&i18n.Message{
+				ID:    "settings.SettingWebhooks.notFound",
+				Other: "There's no webhook with the ID `{{.ID}}`. Use `/settings webhooks view` to see them all",
+			},
+				map[string]interface{}{
+					"ID": args[0],
+				}), false
+		}
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingWebhooks.removed",
+			Other: "Removed webhook `{{.ID}}`",
+		},
+			map[string]interface{}{
+				"ID": args[0],
+			}), true
+	}
+
+	u, err := webhook.ValidateURL(args[0])
+	if errors.Is(err, webhook.ErrForbiddenHost) {
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingWebhooks.forbiddenHost",
+			Other: "`{{.URL}}` points at a local or private network address; webhooks have to be reachable over the internet",
+		},
+			map[string]interface{}{
+				"URL": args[0],
+			}), false
+	} else if err != nil {
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingWebhooks.badURL",
+			Other: "`{{.URL}}` is not a valid URL",
+		},
+			map[string]interface{}{
+				"URL": args[0],
+			}), false
+	}
+	eventsArg := ""
+	if len(args) > 1 {
+		eventsArg = args[1]
+	}
+	events, ok := webhook.ParseEvents(eventsArg)
+	if !ok {
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingWebhooks.badEvents",
+			Other: "I don't know all of `{{.Events}}`. Pass a comma-separated list of {{.AllEvents}}, or leave it empty for all of them",
+		},
+			map[string]interface{}{
+				"Events":    eventsArg,
+				"AllEvents": eventsString(webhook.AllEvents),
+			}), false
+	}
+
+	sub := webhook.NewSubscription(u.String(), events)
+	if !sett.AddWebhook(sub) {
+		return sett.LocalizeMessage(&i18n.Message{
+			ID:    "settings.SettingWebhooks.tooMany",
+			Other: "You already have {{.Max}} webhooks. Remove one before adding another",
+		},
+			map[string]interface{}{
+				"Max": webhook.MaxSubscriptions,
+			}), false
+	}
+	return sett.LocalizeMessage(&i18n.Message{
+		ID:    "settings.SettingWebhooks.added",
+		Other: "Added webhook `{{.ID}}` for {{.Events}}. Every request is signed with `{{.Secret}}`; keep it somewhere safe, I won't show it again",
+	},
+		map[string]interface{}{
+			"ID":     sub.ID,
+			"Events": eventsString(events),
+			"Secret": sub.Secret,
+		}), true
+}
+
+func eventsString(events []webhook.Event) string {
+	if len(events) == 0 {
+		return "all"
+	}
+	strs := make([]string, len(events))
+	for i, v := range events {
+		strs[i] = string(v)
+	}
+	return strings.Join(strs, ", ")
+}
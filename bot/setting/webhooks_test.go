@@ -0,0 +1,53 @@
+package setting
+
+import (
+	"testing"
+
+	"github.com/automuteus/automuteus/v8/pkg/webhook"
+)
+
+func TestFnWebhooks(t *testing.T) {
+	sett, err := testSettingsFn(FnWebhooks)
+	if err != nil {
+		t.Error(err)
+	}
+
+	_, valid := FnWebhooks(sett, []string{View})
+	if valid {
+		t.Error("View shouldn't result in valid settings change")
+	}
+
+	for _, v := range [][]string{{"http://"}, {"https://example.com", "game.start,nope"}, {"nosuchid"}, {"http://127.0.0.1:8080/hook"}, {"http://169.254.169.254/latest"}} {
+		_, valid = FnWebhooks(sett, v)
+		if valid {
+			t.Errorf("Invalid webhook args %v shouldn't result in a valid settings change", v)
+		}
+	}
+
+	_, valid = FnWebhooks(sett, []string{"https://example.com/hook", "game.start,game.over"})
+	if !valid {
+		t.Error("Adding a webhook should result in a valid settings change")
+	}
+	subs := sett.GetWebhooks()
+	if len(subs) != 1 || subs[0].URL != "https://example.com/hook" || subs[0].Secret == "" || len(subs[0].Events) != 2 {
+		t.Fatalf("Webhook was not added correctly: %+v", subs)
+	}
+
+	for i := 1; i < webhook.MaxSubscriptions; i++ {
+		FnWebhooks(sett, []string{"https://example.com/hook"})
+	}
+	_, valid = FnWebhooks(sett, []string{"https://example.com/hook"})
+	if valid || len(sett.GetWebhooks()) != webhook.MaxSubscriptions {
+		t.Error("Shouldn't be able to add more than MaxSubscriptions webhooks")
+	}
+
+	_, valid = FnWebhooks(sett, []string{subs[0].ID})
+	if !valid || len(sett.GetWebhooks()) != webhook.MaxSubscriptions-1 {
+		t.Error("Removing a webhook by ID should result in a valid settings change")
+	}
+
+	_, valid = FnWebhooks(sett, []string{Clear})
+	if !valid || len(sett.GetWebhooks()) != 0 {
+		t.Error("Clear should remove every webhook")
+	}
+}
@@ -28,6 +28,8 @@ func (bot *Bot) HandleSettingsCommand(guildID string, sett *settings.GuildSettin
 		sendMsg, isValid = setting.FnDelays(sett, args)
 	case setting.VoiceRules:
 		sendMsg, isValid = setting.FnVoiceRules(sett, args)
+	case setting.HideAndSeekVoiceRules:
+		sendMsg, isValid = setting.FnHideAndSeekVoiceRules(sett, args)
 	case setting.MatchSummary:
 		if !prem {
 			return nonPremiumSettingResponse(sett)
@@ -63,13 +65,48 @@ func (bot *Bot) HandleSettingsCommand(guildID string, sett *settings.GuildSettin
 			return nonPremiumSettingResponse(sett)
 		}
 		sendMsg, isValid = setting.FnMuteSpectators(sett, args)
+	case setting.GhostChannel:
+		sendMsg, isValid = setting.FnGhostChannel(sett, args)
+	case setting.GameRoles:
+		sendMsg, isValid = setting.FnGameRoles(sett, args)
+	case setting.Exemptions:
+		sendMsg, isValid = setting.FnExemptions(sett, args)
+	case setting.ConflictLogChannel:
+		sendMsg, isValid = setting.FnConflictLogChannel(sett, args)
+	case setting.LateJoiners:
+		sendMsg, isValid = setting.FnLateJoiners(sett, args)
+	case setting.Leavers:
+		sendMsg, isValid = setting.FnLeavers(sett, args)
+	case setting.SyncNicknames:
+		sendMsg, isValid = setting.FnSyncNicknames(sett, args)
+	case setting.Presets:
+		sendMsg, isValid = setting.FnPresets(sett, args)
 	case setting.DisplayRoomCode:
 		if !prem {
 			return nonPremiumSettingResponse(sett)
 		}
 		sendMsg, isValid = setting.FnDisplayRoomCode(sett, args)
+	case setting.CaptureGrace:
+		sendMsg, isValid = setting.FnCaptureGrace(sett, args)
+	case setting.Webhooks:
+		sendMsg, isValid = setting.FnWebhooks(sett, args)
+	case setting.ChannelOverrides:
+		// a premium setting stays premium in a channel of its own
+		if len(args) > 1 && !prem {
+			if s := setting.GetSettingByName(args[1]); s != nil && s.Premium {
+				return nonPremiumSettingResponse(sett)
+			}
+		}
+		sendMsg, isValid = setting.FnChannelOverrides(sett, args)
+	case setting.Simulate:
+		sendMsg, isValid = setting.FnSimulate(sett, args)
 	case setting.Show:
-		jBytes, err := json.MarshalIndent(sett, "", "  ")
+		redacted, err := sett.WithoutSecrets()
+		if err != nil {
+			log.Println(err)
+			return err
+		}
+		jBytes, err := json.MarshalIndent(redacted, "", "  ")
 		if err != nil {
 			log.Println(err)
 			return err
@@ -18,6 +18,7 @@ import (
     "github.com/automuteus/automuteus/v8/pkg/discord"
     "github.com/automuteus/automuteus/v8/pkg/premium"
     "github.com/automuteus/automuteus/v8/pkg/settings"
+    "github.com/automuteus/automuteus/v8/pkg/webhook"
     "github.com/bwmarrin/discordgo"
     "github.com/nicksnyder/go-i18n/v2/i18n"
 )
@@ -273,6 +274,19 @@ func (bot *Bot) slashCommandHandler(s *discordgo.Session, i *discordgo.Interacti
         TextChannel: i.ChannelID,
     }
 
+    // the game in this channel renders (and mutes) with its voice channel's settings. /settings edits and saves the
+    // guild's own, and /new picks its voice channel itself
+    gameSettings := true
+    if i.Type == discordgo.InteractionApplicationCommand {
+        name := i.ApplicationCommandData().Name
+        gameSettings = name != command.Settings.Name && name != command.New.Name
+    }
+    if gameSettings {
+        if dgs := bot.RedisInterface.getDiscordGameState(gsr, false); dgs != nil {
+            dgs.applyGameSettings(sett)
+        }
+    }
+
     if i.Type == discordgo.InteractionApplicationCommand {
         if redis_common.IsUserRateLimitedSpecific(bot.RedisInterface.client, i.Member.User.ID, i.ApplicationCommandData().Name) {
             banned := redis_common.IncrementRateLimitExceed(bot.RedisInterface.client, i.Member.User.ID)
@@ -342,9 +356,14 @@ func (bot *Bot) slashCommandHandler(s *discordgo.Session, i *discordgo.Interacti
             if err != nil {
                 log.Println("Err in /settings get premium:", err)
             }
-            setting, args := command.GetSettingsParams(i.ApplicationCommandData().Options)
-            msg := bot.HandleSettingsCommand(i.GuildID, sett, setting, args, !premium.IsExpired(premStatus, days))
-            return command.SettingsResponse(msg)
+            settingName, args := command.GetSettingsParams(i.ApplicationCommandData().Options)
+            msg := bot.HandleSettingsCommand(i.GuildID, sett, settingName, args, !premium.IsExpired(premStatus, days))
+            resp := command.SettingsResponse(msg)
+            if settingName == setting.Webhooks {
+                // a newly added webhook's secret is in the response
+                resp.Data.Flags = 1 << 6
+            }
+            return resp
 
         case command.New.Name:
             if !isPermissioned {
@@ -361,6 +380,18 @@ func (bot *Bot) slashCommandHandler(s *discordgo.Session, i *discordgo.Interacti
             if missingPerms > 0 {
                 return command.ReinviteMeResponse(missingPerms, voiceChannelID, sett)
             }
+            // the game's messages follow its voice channel's settings
+            sett.ApplyChannelOverrides(voiceChannelID)
+
+            presetName := command.GetNewParams(i.ApplicationCommandData().Options)
+            var preset *settings.Preset
+            if presetName != "" {
+                p, ok := sett.GetPreset(presetName)
+                if !ok {
+                    return command.NewResponse(command.NewUnknownPreset, command.NewInfo{Preset: presetName}, sett)
+                }
+                preset = &p
+            }
 
             lock, dgs := bot.RedisInterface.GetDiscordGameStateAndLockRetries(gsr, 5)
             if lock == nil {
@@ -370,6 +401,8 @@ func (bot *Bot) slashCommandHandler(s *discordgo.Session, i *discordgo.Interacti
 
             status, activeGames := bot.newGame(dgs)
             if status == command.NewSuccess {
+                dgs.PresetName, dgs.Preset = presetName, preset
+
                 // release the lock
                 bot.RedisInterface.SetDiscordGameState(dgs, lock)
 
@@ -1239,9 +1272,29 @@ func (bot *Bot) linkOrUnlinkAndRespond(dgs *GameState, userID, testValue string,
         if err != nil {
             log.Println(err)
         }
+        if status == command.LinkSuccess {
+            bot.RedisInterface.LogLink(dgs, userID)
+            bot.syncNicknamesAfterLink(dgs, sett)
+            if data, ok := dgs.GameData.GetByColor(testValue); ok {
+                bot.fireWebhook(sett, dgs, webhook.PlayerLink, webhookPlayer(dgs, data))
+            }
+        }
         return command.LinkResponse(status, userID, testValue, sett), status == command.LinkSuccess
     } else {
+        var unlinked webhook.PlayerData
+        if user, ok := dgs.UserData[userID]; ok {
+            if data, found := dgs.GameData.GetByName(user.GetPlayerName()); found {
+                unlinked = webhookPlayer(dgs, data)
+            }
+        }
         status := unlinkPlayer(dgs, userID)
+        if status == command.UnlinkSuccess {
+            bot.RedisInterface.LogLink(dgs, userID)
+            bot.syncNicknamesAfterLink(dgs, sett)
+        }
+        if status == command.UnlinkSuccess && unlinked.UserID != "" {
+            bot.fireWebhook(sett, dgs, webhook.PlayerUnlink, unlinked)
+        }
         return command.UnlinkResponse(status, userID, sett), status == command.UnlinkSuccess
     }
 }
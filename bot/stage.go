@@ -0,0 +1,51 @@
+package bot
+
+import (
+	"log"
+	"strconv"
+
+	"github.com/automuteus/automuteus/v8/internal/server"
+	"github.com/automuteus/automuteus/v8/pkg/task"
+	"github.com/bsm/redislock"
+	"github.com/bwmarrin/discordgo"
+)
+
+// stageChannelID returns the channel if it's a stage channel, where players are moved between the speakers and the
+// audience instead of being muted, or "" if it isn't
+func stageChannelID(sess *discordgo.Session, channelID string) string {
+	if channelID == "" {
+		return ""
+	}
+	channel, err := sess.State.Channel(channelID)
+	if err != nil {
+		channel, err = sess.Channel(channelID)
+		if err != nil {
+			log.Println(err)
+			return ""
+		}
+	}
+	if channel.Type != discordgo.ChannelTypeGuildStageVoice {
+		return ""
+	}
+	return channelID
+}
+
+// applySuppress moves everyone who should be muted to the stage's audience, and everyone else up to speak. There's no
+// deafening on a stage; the audience hears the speakers either way
+func (bot *Bot) applySuppress(guildID, channelID string, req task.UserModifyRequest, voiceLock *redislock.Lock) error {
+	if voiceLock != nil {
+		defer voiceLock.Release(ctx)
+	}
+
+	// only the bot itself can move people on and off the stage; capture clients and worker bots only mute/deafen
+	var latestErr error
+	for _, user := range req.Users {
+		err := task.ApplySuppress(bot.PrimarySession, guildID, channelID, strconv.FormatUint(user.UserID, 10), user.Mute)
+		if err != nil {
+			log.Printf("Couldn't set suppress=%v for %d on stage %s: %s\n", user.Mute, user.UserID, channelID, err)
+			latestErr = err
+		}
+	}
+	server.RecordDiscordRequests(bot.RedisInterface.client, server.MemberSuppress, int64(len(req.Users)))
+	return latestErr
+}
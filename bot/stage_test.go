@@ -0,0 +1,119 @@
+package bot
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/automuteus/automuteus/v8/pkg/game"
+	"github.com/automuteus/automuteus/v8/pkg/settings"
+	"github.com/automuteus/automuteus/v8/pkg/task"
+	"github.com/bwmarrin/discordgo"
+)
+
+// stageTransport records who was moved to a stage's audience (true) or up to speak (false)
+type stageTransport struct {
+	replayTransport
+	suppressed map[string]bool
+}
+
+func (st *stageTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	parts := strings.Split(strings.TrimSuffix(req.URL.Path, "/"), "/")
+	if req.Method == http.MethodPatch && len(parts) > 2 && parts[len(parts)-2] == "voice-states" {
+		var params task.SuppressParams
+		err := json.NewDecoder(req.Body).Decode(&params)
+		if err != nil {
+			return nil, err
+		}
+		st.replayTransport.Lock()
+		st.suppressed[parts[len(parts)-1]] = params.Suppress
+		st.replayTransport.Unlock()
+		return replayResponse(req, http.StatusNoContent, ""), nil
+	}
+	return st.replayTransport.RoundTrip(req)
+}
+
+func TestStageSuppress(t *testing.T) {
+	const guildID = "100"
+	const stageChannelID = "200"
+
+	mr := miniredis.RunT(t)
+	stopClock := runReplayClock(mr)
+	defer stopClock()
+
+	dgs := NewDiscordGameState(guildID)
+	dgs.ConnectCode = "ABCD1234"
+	dgs.VoiceChannel = stageChannelID
+	dgs.Running = true
+	dgs.GameData.UpdatePhase(game.LOBBY)
+	dgs.GameData.UpdatePhase(game.TASKS)
+
+	var members []*discordgo.Member
+	var voiceStates []*discordgo.VoiceState
+	for i, name := range []string{"Red", "Blue"} {
+		userID := string(rune('1' + i))
+		dgs.GameData.UpdatePlayer(game.Player{Action: game.JOINED, Name: name, Color: i})
+		if i > 0 {
+			dgs.GameData.UpdatePlayer(game.Player{Action: game.DIED, Name: name, Color: i, IsDead: true})
+		}
+		member := &discordgo.Member{User: &discordgo.User{ID: userID, Username: name}}
+		userData := MakeUserDataFromDiscordUser(member.User, "")
+		player, _ := dgs.GameData.GetByName(name)
+		userData.Link(player)
+		dgs.UpdateUserData(userID, userData)
+		members = append(members, member)
+		voiceStates = append(voiceStates, &discordgo.VoiceState{GuildID: guildID, UserID: userID, ChannelID: stageChannelID})
+	}
+
+	transport := &stageTransport{suppressed: make(map[string]bool)}
+	bot, err := newReplayBot(mr.Addr(), transport, &RecordSnapshot{
+		GameState:   dgs,
+		VoiceStates: voiceStates,
+		Members:     members,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bot.RedisInterface.Close()
+	defer bot.StorageInterface.Close()
+	err = bot.PrimarySession.State.ChannelAdd(&discordgo.Channel{ID: stageChannelID, GuildID: guildID, Type: discordgo.ChannelTypeGuildStageVoice})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sett := settings.MakeGuildSettings()
+	sett.SetVoiceRule(game.NormalMode, true, game.TASKS, "dead", true)
+	// a ghost channel would take players off the stage; it's ignored
+	sett.SetGhostChannelID("300")
+	bot.RedisInterface.SetDiscordGameState(dgs, nil)
+	gsr := GameStateRequest{GuildID: guildID, ConnectCode: dgs.ConnectCode}
+
+	bot.handleTrackedMembers(bot.PrimarySession, sett, 0, NoPriority, gsr)
+
+	if modifies := transport.drain(); len(modifies) != 0 {
+		t.Errorf("expected nobody to be server muted on a stage, got %+v", modifies)
+	}
+	// Red is alive and Blue dead during tasks; both are muted by the voice rules, so both go to the audience
+	expected := map[string]bool{"1": true, "2": true}
+	if !reflect.DeepEqual(transport.suppressed, expected) {
+		t.Errorf("expected suppressions %v, got %v", expected, transport.suppressed)
+	}
+
+	// up to speak for the discussion, except for the dead
+	transport.suppressed = make(map[string]bool)
+	lock, state := bot.RedisInterface.GetDiscordGameStateAndLock(gsr)
+	for lock == nil {
+		lock, state = bot.RedisInterface.GetDiscordGameStateAndLock(gsr)
+	}
+	state.GameData.UpdatePhase(game.DISCUSS)
+	bot.RedisInterface.SetDiscordGameState(state, lock)
+
+	bot.handleTrackedMembers(bot.PrimarySession, sett, 0, NoPriority, gsr)
+
+	if expected := map[string]bool{"1": false}; !reflect.DeepEqual(transport.suppressed, expected) {
+		t.Errorf("expected suppressions %v, got %v", expected, transport.suppressed)
+	}
+}
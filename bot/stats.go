@@ -188,17 +188,97 @@ func (bot *Bot) UserStatsEmbed(userID, guildID string, sett *settings.GuildSetti
 				Inline: true,
 			})
 		}
-		fields = append(fields, &discordgo.MessageEmbedField{
-			Name:   "\u200b",
-			Value:  "\u200b",
-			Inline: true,
-		})
+		totalNeutralGames := bot.PostgresInterface.NumGamesAsRoleOnServer(userID, guildID, int16(game.NeutralRole))
+		if totalNeutralGames > 0 {
+			neutralWins := bot.PostgresInterface.NumWinsAsRoleOnServer(userID, guildID, int16(game.NeutralRole))
+			fields = append(fields, &discordgo.MessageEmbedField{
+				Name: sett.LocalizeMessage(&i18n.Message{
+					ID:    "responses.userStatsEmbed.NeutralWins",
+					Other: "Neutral Wins",
+				}),
+				Value: fmt.Sprintf("%d/%d %s | %.0f%%", neutralWins, totalNeutralGames,
+					sett.LocalizeMessage(&i18n.Message{
+						ID:    "responses.stats.Games",
+						Other: "Games",
+					}),
+					100.0*float64(neutralWins)/float64(totalNeutralGames)),
+				Inline: true,
+			})
+		} else {
+			fields = append(fields, &discordgo.MessageEmbedField{
+				Name:   "\u200b",
+				Value:  "\u200b",
+				Inline: true,
+			})
+		}
 		fields = append(fields, &discordgo.MessageEmbedField{
 			Name:   "\u200b",
 			Value:  "\u200b",
 			Inline: false,
 		})
 
+		roleRankings := bot.PostgresInterface.RoleRankingForPlayerOnServer(userID, guildID)
+		if len(roleRankings) > 0 {
+			buf := bytes.NewBuffer([]byte{})
+			for i := 0; i < len(roleRankings) && i < leaderBoardSize; i++ {
+				elem := roleRankings[i]
+				buf.WriteString(fmt.Sprintf("%s | %d/%d %s | %.0f%%", elem.RoleName, elem.Wins, elem.Count,
+					sett.LocalizeMessage(&i18n.Message{
+						ID:    "responses.stats.Games",
+						Other: "Games",
+					}),
+					100.0*float64(elem.Wins)/float64(elem.Count)))
+				if i < len(roleRankings)-1 && i < leaderBoardSize-1 {
+					buf.WriteByte('\n')
+				}
+			}
+			fields = append(fields, &discordgo.MessageEmbedField{
+				Name: sett.LocalizeMessage(&i18n.Message{
+					ID:    "responses.userStatsEmbed.Roles",
+					Other: "Roles",
+				}),
+				Value:  buf.String(),
+				Inline: false,
+			})
+		}
+
+		hideAndSeek := bytes.NewBuffer([]byte{})
+		for _, role := range []game.GameRole{game.HiderRole, game.SeekerRole} {
+			total := bot.PostgresInterface.NumGamesAsRoleOnServer(userID, guildID, int16(role))
+			if total > 0 {
+				wins := bot.PostgresInterface.NumWinsAsRoleOnServer(userID, guildID, int16(role))
+				roleStr := sett.LocalizeMessage(&i18n.Message{
+					ID:    "responses.userStatsEmbed.Hider",
+					Other: "Hider",
+				})
+				if role == game.SeekerRole {
+					roleStr = sett.LocalizeMessage(&i18n.Message{
+						ID:    "responses.userStatsEmbed.Seeker",
+						Other: "Seeker",
+					})
+				}
+				if hideAndSeek.Len() > 0 {
+					hideAndSeek.WriteByte('\n')
+				}
+				hideAndSeek.WriteString(fmt.Sprintf("%s | %d/%d %s | %.0f%%", roleStr, wins, total,
+					sett.LocalizeMessage(&i18n.Message{
+						ID:    "responses.stats.Games",
+						Other: "Games",
+					}),
+					100.0*float64(wins)/float64(total)))
+			}
+		}
+		if hideAndSeek.Len() > 0 {
+			fields = append(fields, &discordgo.MessageEmbedField{
+				Name: sett.LocalizeMessage(&i18n.Message{
+					ID:    "responses.userStatsEmbed.HideAndSeek",
+					Other: "Hide and Seek",
+				}),
+				Value:  hideAndSeek.String(),
+				Inline: false,
+			})
+		}
+
 		playerRankings := bot.PostgresInterface.OtherPlayersRankingForPlayerOnServer(userID, guildID)
 		if len(playerRankings) > 0 {
 			buf := bytes.NewBuffer([]byte{})
@@ -561,25 +641,53 @@ func (bot *Bot) GuildStatsEmbed(guildID string, sett *settings.GuildSettings, is
 	}
 
 	if gamesPlayed > 0 {
-		crewmateWins := bot.PostgresInterface.NumGamesWonAsRoleOnServer(guildID, game.CrewmateRole)
-		imposterWins := bot.PostgresInterface.NumGamesWonAsRoleOnServer(guildID, game.ImposterRole)
+		// Hide and Seek games don't have a crewmate or imposter winner, so they'd skew the winrates
+		normalGames := gamesPlayed
+		hideAndSeekGames := bot.PostgresInterface.NumGamesPlayedOnGuildInMode(guildID, game.HideAndSeekMode)
+		if hideAndSeekGames > 0 {
+			normalGames -= hideAndSeekGames
+		}
+		if normalGames > 0 {
+			crewmateWins := bot.PostgresInterface.NumGamesWonAsRoleOnServer(guildID, game.CrewmateRole)
+			imposterWins := bot.PostgresInterface.NumGamesWonAsRoleOnServer(guildID, game.ImposterRole)
 
-		fields = append(fields, &discordgo.MessageEmbedField{
-			Name: sett.LocalizeMessage(&i18n.Message{
-				ID:    "responses.guildStatsEmbed.GamesWonCrewmate",
-				Other: "Crewmate Winrate",
-			}),
-			Value:  fmt.Sprintf("%.0f%%", 100.0*(float64(crewmateWins)/float64(gamesPlayed))),
-			Inline: true,
-		})
-		fields = append(fields, &discordgo.MessageEmbedField{
-			Name: sett.LocalizeMessage(&i18n.Message{
-				ID:    "responses.guildStatsEmbed.GamesWonImposter",
-				Other: "Imposter Winrate",
-			}),
-			Value:  fmt.Sprintf("%.0f%%", 100.0*(float64(imposterWins)/float64(gamesPlayed))),
-			Inline: true,
-		})
+			fields = append(fields, &discordgo.MessageEmbedField{
+				Name: sett.LocalizeMessage(&i18n.Message{
+					ID:    "responses.guildStatsEmbed.GamesWonCrewmate",
+					Other: "Crewmate Winrate",
+				}),
+				Value:  fmt.Sprintf("%.0f%%", 100.0*(float64(crewmateWins)/float64(normalGames))),
+				Inline: true,
+			})
+			fields = append(fields, &discordgo.MessageEmbedField{
+				Name: sett.LocalizeMessage(&i18n.Message{
+					ID:    "responses.guildStatsEmbed.GamesWonImposter",
+					Other: "Imposter Winrate",
+				}),
+				Value:  fmt.Sprintf("%.0f%%", 100.0*(float64(imposterWins)/float64(normalGames))),
+				Inline: true,
+			})
+			if neutralWins := bot.PostgresInterface.NumGamesWonAsRoleOnServer(guildID, game.NeutralRole); neutralWins > 0 {
+				fields = append(fields, &discordgo.MessageEmbedField{
+					Name: sett.LocalizeMessage(&i18n.Message{
+						ID:    "responses.guildStatsEmbed.GamesWonNeutral",
+						Other: "Neutral Winrate",
+					}),
+					Value:  fmt.Sprintf("%.0f%%", 100.0*(float64(neutralWins)/float64(normalGames))),
+					Inline: true,
+				})
+			}
+		}
+		if hideAndSeekGames > 0 {
+			fields = append(fields, &discordgo.MessageEmbedField{
+				Name: sett.LocalizeMessage(&i18n.Message{
+					ID:    "responses.guildStatsEmbed.HideAndSeekGames",
+					Other: "Hide and Seek Games",
+				}),
+				Value:  fmt.Sprintf("%d", hideAndSeekGames),
+				Inline: true,
+			})
+		}
 	}
 
 	extraDesc := sett.LocalizeMessage(&i18n.Message{
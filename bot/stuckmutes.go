@@ -0,0 +1,192 @@
+package bot
+
+import (
+	"errors"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/automuteus/automuteus/v8/pkg/discord"
+	"github.com/automuteus/automuteus/v8/pkg/premium"
+	"github.com/automuteus/automuteus/v8/pkg/rediskey"
+	"github.com/automuteus/automuteus/v8/pkg/task"
+	"github.com/go-redis/redis/v8"
+	"github.com/nicksnyder/go-i18n/v2/i18n"
+)
+
+// StuckMuteSweepInterval is how often every guild is checked for players the bot left muted after their game was gone
+const StuckMuteSweepInterval = time.Minute * 10
+
+// StuckMuteStartupDelay is how long after startup the first sweep waits, for Discord to send over every guild
+const StuckMuteStartupDelay = time.Second * 30
+
+// MutedUsersTimeoutSeconds is how long the bot remembers who it muted, if nobody's muted or unmuted in the guild since.
+// Anyone still muted by then hasn't been in voice for a week
+const MutedUsersTimeoutSeconds = 60 * 60 * 24 * 7
+
+// RecordMutedUsers notes who the bot has muted/deafened for the game, and who it no longer has
+func (redisInterface *RedisInterface) RecordMutedUsers(guildID, connectCode string, muted, unmuted []string) {
+	key := rediskey.MutedUsers(guildID)
+	pipe := redisInterface.client.TxPipeline()
+	if len(muted) > 0 {
+		values := make([]interface{}, 0, len(muted)*2)
+		for _, v := range muted {
+			values = append(values, v, connectCode)
+		}
+		pipe.HSet(ctx, key, values...)
+	}
+	if len(unmuted) > 0 {
+		pipe.HDel(ctx, key, unmuted...)
+	}
+	pipe.Expire(ctx, key, MutedUsersTimeoutSeconds*time.Second)
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// GetMutedUsers returns the connect code of the game each user was muted/deafened in, by user ID
+func (redisInterface *RedisInterface) GetMutedUsers(guildID string) map[string]string {
+	muted, err := redisInterface.client.HGetAll(ctx, rediskey.MutedUsers(guildID)).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		log.Println(err)
+	}
+	return muted
+}
+
+// GameExists is whether there's anything left of the game; either it's active, or its state hasn't expired yet
+func (redisInterface *RedisInterface) GameExists(guildID, connectCode string, activeGames []string) bool {
+	for _, v := range activeGames {
+		if v == connectCode {
+			return true
+		}
+	}
+	n, err := redisInterface.client.Exists(ctx, rediskey.ConnectCodeData(guildID, connectCode)).Result()
+	if err != nil {
+		log.Println(err)
+		// better to leave someone muted than to unmute a game in progress
+		return true
+	}
+	return n > 0
+}
+
+// recordMutes remembers who the request leaves muted or deafened by the bot, so they can be unmuted if the game ends
+// without it; see sweepStuckMutes. Mutes and deafens by anyone else aren't the bot's to lift, so they're left out.
+// Unless the whole request succeeded, anyone it was meant to unmute is still remembered, in case theirs failed
+func (bot *Bot) recordMutes(dgs *GameState, req task.UserModifyRequest, succeeded bool) {
+	var muted, unmuted []string
+	for _, v := range req.Users {
+		userID := strconv.FormatUint(v.UserID, 10)
+		userData, _ := dgs.GetUser(userID)
+		if (v.Mute && !userData.ForeignMute) || (v.Deaf && !userData.ForeignDeaf) {
+			muted = append(muted, userID)
+		} else if succeeded {
+			unmuted = append(unmuted, userID)
+		}
+	}
+	bot.RedisInterface.RecordMutedUsers(dgs.GuildID, dgs.ConnectCode, muted, unmuted)
+}
+
+// sweepStuckMutes unmutes and undeafens anyone in voice the bot muted for a game that's gone, like when the bot was
+// stopped mid-game, and tells the guild's match summary channel who it fixed
+func (bot *Bot) sweepStuckMutes(guildID string) {
+	muted := bot.RedisInterface.GetMutedUsers(guildID)
+	if len(muted) == 0 {
+		return
+	}
+	g, err := bot.PrimarySession.State.Guild(guildID)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	activeGames := bot.RedisInterface.LoadAllActiveGames(guildID)
+
+	var users []task.UserModify
+	var fixed, undone []string
+	for _, voiceState := range g.VoiceStates {
+		connectCode, ok := muted[voiceState.UserID]
+		if !ok || bot.RedisInterface.GameExists(guildID, connectCode, activeGames) {
+			continue
+		}
+		if !voiceState.Mute && !voiceState.Deaf {
+			undone = append(undone, voiceState.UserID)
+			continue
+		}
+		uid, _ := strconv.ParseUint(voiceState.UserID, 10, 64)
+		users = append(users, task.UserModify{
+			UserID: uid,
+			Mute:   false,
+			Deaf:   false,
+		})
+		fixed = append(fixed, voiceState.UserID)
+	}
+	// someone else already unmuted them. Anyone who isn't in voice can't be unmuted yet; they're kept for when they're back
+	if len(undone) > 0 {
+		bot.RedisInterface.RecordMutedUsers(guildID, "", nil, undone)
+	}
+	if len(users) == 0 {
+		return
+	}
+	log.Printf("Unmuting %d player(s) left muted after their game was gone in guild %s\n", len(users), guildID)
+
+	prem, days, _ := bot.PostgresInterface.GetGuildOrUserPremiumStatus(bot.official, nil, guildID, "")
+	premTier := premium.FreeTier
+	if !premium.IsExpired(prem, days) {
+		premTier = prem
+	}
+	req := task.UserModifyRequest{
+		Premium: premTier,
+		Users:   users,
+	}
+	// no connect code; there's no game, and so no capture, to mute with
+	err = bot.TokenProvider.ModifyUsers(guildID, "", req, nil)
+	if err != nil {
+		// they'll be tried again next time
+		log.Println(err)
+		return
+	}
+	bot.RedisInterface.RecordMutedUsers(guildID, "", nil, fixed)
+
+	sett := bot.StorageInterface.GetGuildSettings(guildID)
+	channelID := sett.GetMatchSummaryChannelID()
+	if channelID == "" {
+		return
+	}
+	sort.Strings(fixed)
+	var mentions []string
+	for _, v := range fixed {
+		mentions = append(mentions, discord.MentionByUserID(v))
+	}
+	_, err = bot.PrimarySession.ChannelMessageSend(channelID, sett.LocalizeMessage(&i18n.Message{
+		ID:    "stuckMutes.fixed",
+		Other: "I unmuted and undeafened {{.Users}}, who were left that way by a game that ended without me",
+	},
+		map[string]interface{}{
+			"Users": strings.Join(mentions, ", "),
+		}))
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// StartStuckMuteSweeper sweeps every guild for stuck mutes (and nicknames) once the guilds have had time to load after startup, and
+// every StuckMuteSweepInterval after that
+func (bot *Bot) StartStuckMuteSweeper() {
+	time.Sleep(StuckMuteStartupDelay)
+	for {
+		bot.PrimarySession.State.RLock()
+		guildIDs := make([]string, 0, len(bot.PrimarySession.State.Guilds))
+		for _, g := range bot.PrimarySession.State.Guilds {
+			guildIDs = append(guildIDs, g.ID)
+		}
+		bot.PrimarySession.State.RUnlock()
+
+		for _, guildID := range guildIDs {
+			bot.sweepStuckMutes(guildID)
+			bot.sweepStuckNicknames(guildID)
+		}
+		time.Sleep(StuckMuteSweepInterval)
+	}
+}
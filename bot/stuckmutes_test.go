@@ -0,0 +1,64 @@
+package bot
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/automuteus/automuteus/v8/pkg/rediskey"
+	"github.com/automuteus/automuteus/v8/pkg/task"
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestSweepStuckMutes(t *testing.T) {
+	const guildID = "100"
+	const voiceChannelID = "200"
+
+	mr := miniredis.RunT(t)
+	stopClock := runReplayClock(mr)
+	defer stopClock()
+
+	// a game that's still going; everyone else was muted in one that's gone
+	live := NewDiscordGameState(guildID)
+	live.ConnectCode = "LIVE1234"
+	live.VoiceChannel = voiceChannelID
+
+	transport := &replayTransport{}
+	bot, err := newReplayBot(mr.Addr(), transport, &RecordSnapshot{
+		GameState: live,
+		VoiceStates: []*discordgo.VoiceState{
+			// left muted and deafened when the bot was stopped
+			{GuildID: guildID, ChannelID: voiceChannelID, UserID: "1", Mute: true, Deaf: true},
+			// muted in the game that's still going
+			{GuildID: guildID, ChannelID: voiceChannelID, UserID: "2", Mute: true},
+			// already unmuted by someone else
+			{GuildID: guildID, ChannelID: voiceChannelID, UserID: "3"},
+			// muted, but not by the bot
+			{GuildID: guildID, ChannelID: voiceChannelID, UserID: "4", Mute: true},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bot.RedisInterface.Close()
+	defer bot.StorageInterface.Close()
+	bot.RedisInterface.SetDiscordGameState(live, nil)
+
+	bot.RedisInterface.RecordMutedUsers(guildID, "DEAD1234", []string{"1", "3", "5"}, nil)
+	bot.RedisInterface.RecordMutedUsers(guildID, live.ConnectCode, []string{"2"}, nil)
+
+	bot.sweepStuckMutes(guildID)
+
+	expected := []task.UserModify{{UserID: 1, Mute: false, Deaf: false}}
+	if modifies := transport.drain(); !reflect.DeepEqual(modifies, expected) {
+		t.Errorf("expected only the first user to be unmuted, got %+v", modifies)
+	}
+	// the user who isn't in voice is kept until they're back
+	remaining := bot.RedisInterface.GetMutedUsers(guildID)
+	if !reflect.DeepEqual(remaining, map[string]string{"2": live.ConnectCode, "5": "DEAD1234"}) {
+		t.Errorf("expected the fixed users to be forgotten, got %v", remaining)
+	}
+	if ttl := mr.TTL(rediskey.MutedUsers(guildID)); ttl <= 0 {
+		t.Errorf("expected the muted users to expire, got a TTL of %s", ttl)
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"github.com/automuteus/automuteus/v8/pkg/task"
 	"github.com/go-redis/redis/v8"
 	"log"
+	"sync"
+	"sync/atomic"
 )
 
 func RecordDiscordRequestsByCounts(client *redis.Client, counts task.MuteDeafenSuccessCounts) {
@@ -44,42 +46,190 @@ func (tokenProvider *TokenProvider) attemptOnSecondaryTokens(guildID, userID str
 	return ""
 }
 
-func (tokenProvider *TokenProvider) attemptOnCaptureBot(guildID, connectCode string, gid uint64, request task.UserModify) bool {
-	// this is cheeky, but use the connect code as part of the lock; don't issue too many requests on the capture client w/ this code
-	if tokenProvider.IncrAndTestGuildTokenComboLock(guildID, connectCode) {
-		// if the secondary token didn't work, then next we try the client-side capture request
-		taskObj := task.NewModifyTask(gid, request.UserID, task.PatchParams{
-			Deaf: request.Deaf,
-			Mute: request.Mute,
-		})
-		jBytes, err := json.Marshal(taskObj)
-		if err != nil {
-			log.Println(err)
-			return false
-		}
-		acked := make(chan bool)
-		// now we wait for an ack with respect to actually performing the mute
-		pubsub := tokenProvider.client.Subscribe(context.Background(), rediskey.CompleteTask(taskObj.TaskID))
-		err = tokenProvider.client.Publish(context.Background(), rediskey.TasksList(connectCode), jBytes).Err()
-		if err != nil {
-			log.Println("Error in publishing task to " + rediskey.TasksList(connectCode))
-			log.Println(err)
-		} else {
-			go tokenProvider.waitForAck(pubsub, acked)
-			res := <-acked
-			if res {
-				log.Println("Successful mute/deafen using client capture bot!")
+// attemptOnCaptureBot spreads the users across every linked capture client, as far as each client's rate limit allows,
+// and hands each client its share as a single batch. A client that nobody's listening for any more has its share
+// spread across the rest. It returns the users that still need to be modified some other way
+func (tokenProvider *TokenProvider) attemptOnCaptureBot(guildID, connectCode string, gid uint64, request task.UserModifyRequest) ([]task.UserModify, int64) {
+	clients, err := server.GetCaptureClients(context.Background(), tokenProvider.client, connectCode)
+	if err != nil {
+		log.Println(err)
+		return request.Users, 0
+	}
 
-				// hooray! we did the mute with a client token!
-				return true
+	var failed []task.UserModify
+	var captured int64
+	users := request.Users
+	for len(users) > 0 && len(clients) > 0 {
+		batches := make(map[string][]task.UserModify)
+		for _, user := range users {
+			// start with the next client in line, so the users are spread across all of them
+			start := int(atomic.AddUint64(&tokenProvider.captureRoundRobin, 1) % uint64(len(clients)))
+			assigned := false
+			for i := range clients {
+				clientID := clients[(start+i)%len(clients)]
+				// this is cheeky, but use the connect code and client as part of the lock; don't issue too many requests on any one capture client
+				if tokenProvider.IncrAndTestGuildTokenComboLock(guildID, captureClientLockID(connectCode, clientID)) {
+					batches[clientID] = append(batches[clientID], user)
+					assigned = true
+					break
+				}
 			}
-			err = tokenProvider.BlacklistTokenForDuration(guildID, connectCode, UnresponsiveCaptureBlacklistDuration)
-			if err == nil {
-				log.Printf("No ack from capture clients; blacklisting capture client for gamecode \"%s\" for %s\n", connectCode, UnresponsiveCaptureBlacklistDuration.String())
+			if !assigned {
+				log.Println("Capture clients are probably rate-limited. Deferring to main bot instead")
+				failed = append(failed, user)
 			}
 		}
-	} else {
+
+		var unreachable []task.UserModify
+		gone := make(map[string]bool)
+		lock := sync.Mutex{}
+		wg := sync.WaitGroup{}
+		for clientID, batch := range batches {
+			wg.Add(1)
+			go func(clientID string, batch []task.UserModify) {
+				defer wg.Done()
+				batchFailed, reached := tokenProvider.attemptOnCaptureClient(guildID, connectCode, clientID, gid, task.UserModifyRequest{
+					Premium: request.Premium,
+					Users:   batch,
+				})
+				lock.Lock()
+				defer lock.Unlock()
+				if !reached {
+					gone[clientID] = true
+					unreachable = append(unreachable, batch...)
+					return
+				}
+				failed = append(failed, batchFailed...)
+				captured += int64(len(batch) - len(batchFailed))
+			}(clientID, batch)
+		}
+		wg.Wait()
+
+		users = unreachable
+		var remaining []string
+		for _, clientID := range clients {
+			if !gone[clientID] {
+				remaining = append(remaining, clientID)
+			}
+		}
+		clients = remaining
+	}
+	if len(users) > 0 {
+		// nothing (left) linked to the built-in capture server, but an external broker may still be listening on the game's tasks
+		brokerFailed, brokerCaptured := tokenProvider.attemptOnBrokerCapture(guildID, connectCode, gid, users)
+		failed = append(failed, brokerFailed...)
+		captured += brokerCaptured
+	}
+	return failed, captured
+}
+
+// attemptOnCaptureClient returns the users of the batch that the capture client didn't modify, and false if nobody
+// was listening for the client's tasks, in which case it wasn't waited on
+func (tokenProvider *TokenProvider) attemptOnCaptureClient(guildID, connectCode, clientID string, gid uint64, request task.UserModifyRequest) ([]task.UserModify, bool) {
+	// if the secondary token didn't work, then next we try the client-side capture request
+	taskObj := task.NewBatchModifyTask(gid, request, tokenProvider.taskTimeoutMs)
+	jBytes, err := json.Marshal(taskObj)
+	if err != nil {
+		log.Println(err)
+		return request.Users, true
+	}
+	acked := make(chan *task.BatchModifyAck)
+	// now we wait for an ack with respect to actually performing the mutes
+	pubsub := tokenProvider.client.Subscribe(context.Background(), rediskey.CompleteTask(taskObj.TaskID))
+	channel := rediskey.CaptureClientTasks(connectCode, clientID)
+	receivers, err := tokenProvider.client.Publish(context.Background(), channel, jBytes).Result()
+	if err != nil {
+		pubsub.Close()
+		log.Println("Error in publishing task to " + channel)
+		log.Println(err)
+		return request.Users, true
+	}
+	if receivers == 0 {
+		// the capture server the client was linked to is gone; there's no ack to wait on
+		pubsub.Close()
+		log.Printf("Nothing listening for capture client %s; trying elsewhere\n", clientID)
+		return request.Users, false
+	}
+	go tokenProvider.waitForBatchAck(pubsub, acked)
+	ack := <-acked
+	if ack != nil {
+		failed := ack.Failed(request)
+		log.Printf("Capture client %s modified %d of %d users\n", clientID, len(request.Users)-len(failed), len(request.Users))
+		return failed, true
+	}
+	err = tokenProvider.BlacklistTokenForDuration(guildID, captureClientLockID(connectCode, clientID), UnresponsiveCaptureBlacklistDuration)
+	if err == nil {
+		log.Printf("No ack from capture client %s; blacklisting it for gamecode \"%s\" for %s\n", clientID, connectCode, UnresponsiveCaptureBlacklistDuration.String())
+	}
+	return request.Users, true
+}
+
+// captureClientLockID rate-limits and blacklists each capture client separately
+func captureClientLockID(connectCode, clientID string) string {
+	return connectCode + ":" + clientID
+}
+
+// attemptOnBrokerCapture hands the users, one task each, to whatever external broker is listening on the game's tasks
+// channel (see DISABLE_CAPTURE_SERVER). It returns the users that still need to be modified some other way
+func (tokenProvider *TokenProvider) attemptOnBrokerCapture(guildID, connectCode string, gid uint64, users []task.UserModify) ([]task.UserModify, int64) {
+	if connectCode == "" {
+		return users, 0
+	}
+	var failed []task.UserModify
+	var captured int64
+	lock := sync.Mutex{}
+	runWorkers(users, func(user task.UserModify) {
+		success := tokenProvider.attemptOnBroker(guildID, connectCode, gid, user)
+		lock.Lock()
+		defer lock.Unlock()
+		if success {
+			captured++
+		} else {
+			failed = append(failed, user)
+		}
+	})
+	return failed, captured
+}
+
+func (tokenProvider *TokenProvider) attemptOnBroker(guildID, connectCode string, gid uint64, request task.UserModify) bool {
+	// this is cheeky, but use the connect code as part of the lock; don't issue too many requests on the capture client w/ this code
+	if !tokenProvider.IncrAndTestGuildTokenComboLock(guildID, connectCode) {
 		log.Println("Capture client is probably rate-limited. Deferring to main bot instead")
+		return false
+	}
+	taskObj := task.NewModifyTask(gid, request.UserID, task.PatchParams{
+		Deaf: request.Deaf,
+		Mute: request.Mute,
+	})
+	jBytes, err := json.Marshal(taskObj)
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+	acked := make(chan bool)
+	// now we wait for an ack with respect to actually performing the mute
+	pubsub := tokenProvider.client.Subscribe(context.Background(), rediskey.CompleteTask(taskObj.TaskID))
+	receivers, err := tokenProvider.client.Publish(context.Background(), rediskey.TasksList(connectCode), jBytes).Result()
+	if err != nil {
+		pubsub.Close()
+		log.Println("Error in publishing task to " + rediskey.TasksList(connectCode))
+		log.Println(err)
+		return false
+	}
+	if receivers == 0 {
+		// no broker either; nobody to wait on
+		pubsub.Close()
+		log.Println("No capture clients linked to " + connectCode + ". Deferring to main bot instead")
+		return false
+	}
+	go tokenProvider.waitForAck(pubsub, acked)
+	if <-acked {
+		log.Println("Successful mute/deafen using client capture bot!")
+		return true
+	}
+	err = tokenProvider.BlacklistTokenForDuration(guildID, connectCode, UnresponsiveCaptureBlacklistDuration)
+	if err == nil {
+		log.Printf("No ack from capture clients; blacklisting capture client for gamecode \"%s\" for %s\n", connectCode, UnresponsiveCaptureBlacklistDuration.String())
 	}
 	return false
 }
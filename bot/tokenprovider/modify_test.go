@@ -0,0 +1,207 @@
+package tokenprovider
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/automuteus/automuteus/v8/internal/server"
+	"github.com/automuteus/automuteus/v8/pkg/rediskey"
+	"github.com/automuteus/automuteus/v8/pkg/task"
+	"github.com/bwmarrin/discordgo"
+	"github.com/go-redis/redis/v8"
+)
+
+// memberPatchTransport records which users the primary bot muted/deafened, and answers everything else with nothing
+type memberPatchTransport struct {
+	sync.Mutex
+	userIDs []uint64
+}
+
+func (mt *memberPatchTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	mt.Lock()
+	defer mt.Unlock()
+	// .../guilds/{guildID}/members/{userID}
+	parts := strings.Split(strings.TrimSuffix(req.URL.Path, "/"), "/")
+	if req.Method == http.MethodPatch && len(parts) > 2 && parts[len(parts)-2] == "members" {
+		uid, _ := strconv.ParseUint(parts[len(parts)-1], 10, 64)
+		mt.userIDs = append(mt.userIDs, uid)
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": []string{"application/json"}},
+		Body:       io.NopCloser(strings.NewReader("{}")),
+		Request:    req,
+	}, nil
+}
+
+func TestAttemptOnBrokerCapture(t *testing.T) {
+	const guildID = "100"
+	const connectCode = "ABCD1234"
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	tp := NewTokenProvider(client, nil, time.Second, 7)
+
+	users := []task.UserModify{{UserID: 1, Mute: true}, {UserID: 2, Deaf: true}}
+
+	// nobody's listening; the users go straight back, without waiting on an ack
+	start := time.Now()
+	failed, captured := tp.attemptOnCaptureBot(guildID, connectCode, 100, task.UserModifyRequest{Users: users})
+	if len(failed) != len(users) || captured != 0 {
+		t.Errorf("expected every user to be left for the primary bot, got %+v (%d captured)", failed, captured)
+	}
+	if time.Since(start) >= time.Second {
+		t.Error("expected not to wait on an ack with nobody listening")
+	}
+
+	// an external broker acks every task published on the game's tasks channel
+	pubsub := client.Subscribe(context.Background(), rediskey.TasksList(connectCode))
+	defer pubsub.Close()
+	if _, err := pubsub.Receive(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for msg := range pubsub.Channel() {
+			var modify task.ModifyTask
+			if err := json.Unmarshal([]byte(msg.Payload), &modify); err != nil {
+				continue
+			}
+			client.Publish(context.Background(), rediskey.CompleteTask(modify.TaskID), "true")
+		}
+	}()
+
+	failed, captured = tp.attemptOnCaptureBot(guildID, connectCode, 100, task.UserModifyRequest{Users: users})
+	if len(failed) != 0 || captured != int64(len(users)) {
+		t.Errorf("expected the broker to modify every user, got %+v left (%d captured)", failed, captured)
+	}
+}
+
+func TestModifyUsersPartialBatch(t *testing.T) {
+	const guildID = "100"
+	const connectCode = "ABCD1234"
+	const clientID = "client-1"
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	transport := &memberPatchTransport{}
+	sess, err := discordgo.New("Bot test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sess.Client = &http.Client{Transport: transport}
+	tp := NewTokenProvider(client, sess, time.Second, 7)
+
+	err = client.ZAdd(context.Background(), rediskey.CaptureClients(connectCode), &redis.Z{Score: float64(time.Now().Unix()), Member: clientID}).Err()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the capture client modifies user 1, fails user 2, and never gets to user 3
+	pubsub := client.Subscribe(context.Background(), rediskey.CaptureClientTasks(connectCode, clientID))
+	defer pubsub.Close()
+	if _, err := pubsub.Receive(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for msg := range pubsub.Channel() {
+			var batch task.BatchModifyTask
+			if err := json.Unmarshal([]byte(msg.Payload), &batch); err != nil {
+				continue
+			}
+			jBytes, _ := json.Marshal(task.BatchModifyAck{
+				TaskID: batch.TaskID,
+				Results: []task.UserModifyResult{
+					{UserID: 1, Success: true},
+					{UserID: 2, Success: false},
+				},
+			})
+			client.Publish(context.Background(), rediskey.CompleteTask(batch.TaskID), jBytes)
+		}
+	}()
+
+	err = tp.ModifyUsers(guildID, connectCode, task.UserModifyRequest{Users: []task.UserModify{
+		{UserID: 1, Mute: true},
+		{UserID: 2, Mute: true},
+		{UserID: 3, Deaf: true},
+	}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	transport.Lock()
+	userIDs := transport.userIDs
+	transport.Unlock()
+	sort.Slice(userIDs, func(i, j int) bool { return userIDs[i] < userIDs[j] })
+	if !reflect.DeepEqual(userIDs, []uint64{2, 3}) {
+		t.Errorf("expected only the users the capture client failed to fall through to the primary bot, got %v", userIDs)
+	}
+	counts := map[server.EventType]string{
+		server.MuteDeafenCapture:  "1",
+		server.MuteDeafenOfficial: "2",
+	}
+	for eventType, expected := range counts {
+		typeStr := server.MetricTypeStrings[eventType]
+		if v, _ := mr.Get(rediskey.RequestsByType(typeStr)); v != expected {
+			t.Errorf("expected %s %s requests to be recorded, got %q", expected, typeStr, v)
+		}
+	}
+}
+
+func TestAttemptOnCaptureBotUnreachableClient(t *testing.T) {
+	const guildID = "100"
+	const connectCode = "ABCD1234"
+
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	tp := NewTokenProvider(client, nil, time.Second, 7)
+
+	// client-0's capture server is gone without unregistering it; client-1 modifies everyone it's handed
+	for _, clientID := range []string{"client-0", "client-1"} {
+		err := client.ZAdd(context.Background(), rediskey.CaptureClients(connectCode), &redis.Z{Score: float64(time.Now().Unix()), Member: clientID}).Err()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	pubsub := client.Subscribe(context.Background(), rediskey.CaptureClientTasks(connectCode, "client-1"))
+	defer pubsub.Close()
+	if _, err := pubsub.Receive(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for msg := range pubsub.Channel() {
+			var batch task.BatchModifyTask
+			if err := json.Unmarshal([]byte(msg.Payload), &batch); err != nil {
+				continue
+			}
+			ack := task.BatchModifyAck{TaskID: batch.TaskID}
+			for _, user := range batch.Request.Users {
+				ack.Results = append(ack.Results, task.UserModifyResult{UserID: user.UserID, Success: true})
+			}
+			jBytes, _ := json.Marshal(ack)
+			client.Publish(context.Background(), rediskey.CompleteTask(batch.TaskID), jBytes)
+		}
+	}()
+
+	users := []task.UserModify{{UserID: 1, Mute: true}, {UserID: 2, Mute: true}, {UserID: 3, Deaf: true}}
+	start := time.Now()
+	failed, captured := tp.attemptOnCaptureBot(guildID, connectCode, 100, task.UserModifyRequest{Users: users})
+	if len(failed) != 0 || captured != int64(len(users)) {
+		t.Errorf("expected client-1 to modify every user, got %+v left (%d captured)", failed, captured)
+	}
+	if time.Since(start) >= time.Second {
+		t.Error("expected not to wait on an ack from a client nobody's listening for")
+	}
+}
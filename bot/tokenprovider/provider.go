@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
 	"github.com/automuteus/automuteus/v8/pkg/premium"
 	"github.com/automuteus/automuteus/v8/pkg/rediskey"
 	"github.com/automuteus/automuteus/v8/pkg/task"
@@ -28,6 +29,9 @@ var PremiumBotConstraints = map[premium.Tier]int{
 }
 
 type TokenProvider struct {
+	// rotates which capture client gets the next mute/deafen task. Kept first, so it's 64-bit aligned for atomics
+	captureRoundRobin uint64
+
 	client         *redis.Client
 	primarySession *discordgo.Session
 
@@ -163,9 +167,6 @@ func (tokenProvider *TokenProvider) ModifyUsers(guildID, connectCode string, req
 	}
 	limit := PremiumBotConstraints[request.Premium]
 
-	tasksChannel := make(chan task.UserModify, len(request.Users))
-	wg := sync.WaitGroup{}
-
 	mdsc := task.MuteDeafenSuccessCounts{
 		Worker:    0,
 		Capture:   0,
@@ -176,72 +177,92 @@ func (tokenProvider *TokenProvider) ModifyUsers(guildID, connectCode string, req
 	lock := sync.Mutex{}
 	tokenLock := sync.RWMutex{}
 
+	// premium worker bots go first, one user at a time
+	remaining := request.Users
+	if limit > 0 {
+		var leftover []task.UserModify
+		runWorkers(remaining, func(req task.UserModify) {
+			userIDStr := strconv.FormatUint(req.UserID, 10)
+			hToken := ""
+			tokenLock.RLock()
+			if len(uniqueTokensUsed) >= limit {
+				hToken = tokenProvider.attemptOnSecondaryTokens(guildID, userIDStr, uniqueTokensUsed, req)
+				tokenLock.RUnlock()
+			} else {
+				tokenLock.RUnlock()
+				hToken = tokenProvider.attemptOnSecondaryTokens(guildID, userIDStr, nil, req)
+			}
+
+			lock.Lock()
+			defer lock.Unlock()
+			if hToken != "" {
+				mdsc.Worker++
+
+				tokenLock.Lock()
+				uniqueTokensUsed[hToken] = struct{}{}
+				tokenLock.Unlock()
+			} else {
+				leftover = append(leftover, req)
+			}
+		})
+		remaining = leftover
+	}
+
+	// then the capture clients, with a single batch per client
+	if len(remaining) > 0 {
+		var captured int64
+		remaining, captured = tokenProvider.attemptOnCaptureBot(guildID, connectCode, gid, task.UserModifyRequest{
+			Premium: request.Premium,
+			Users:   remaining,
+		})
+		mdsc.Capture += captured
+	}
+
+	// and the primary bot for whoever is left
 	var latestErr error
-	// start a handful of workers to handle the tasks
-	for i := 0; i < DefaultMaxWorkers; i++ {
+	runWorkers(remaining, func(req task.UserModify) {
+		log.Printf("Applying mute=%v, deaf=%v using primary bot\n", req.Mute, req.Deaf)
+		err := task.ApplyMuteDeaf(tokenProvider.primarySession, guildID, strconv.FormatUint(req.UserID, 10), req.Mute, req.Deaf)
+		lock.Lock()
+		defer lock.Unlock()
+		if err != nil {
+			latestErr = err
+			log.Println("Error on primary bot:")
+			log.Println(err)
+		} else {
+			mdsc.Official++
+		}
+	})
+
+	RecordDiscordRequestsByCounts(tokenProvider.client, mdsc)
+
+	// note, this should probably be more systematic on startup, not when a mute/deafen task comes in. But this is a
+	// context in which we already have the guildID, successful tokens, AND the premium limit...
+	go tokenProvider.verifyBotMembership(guildID, limit, uniqueTokensUsed)
+
+	return latestErr
+}
+
+// runWorkers calls fn for every user on a handful of workers, and returns once they're all done
+func runWorkers(users []task.UserModify, fn func(task.UserModify)) {
+	tasksChannel := make(chan task.UserModify, len(users))
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < DefaultMaxWorkers && i < len(users); i++ {
 		go func() {
 			for req := range tasksChannel {
-				userIDStr := strconv.FormatUint(req.UserID, 10)
-				hToken := ""
-				if limit > 0 {
-					tokenLock.RLock()
-					if len(uniqueTokensUsed) >= limit {
-						hToken = tokenProvider.attemptOnSecondaryTokens(guildID, userIDStr, uniqueTokensUsed, req)
-						tokenLock.RUnlock()
-					} else {
-						tokenLock.RUnlock()
-						hToken = tokenProvider.attemptOnSecondaryTokens(guildID, userIDStr, nil, req)
-					}
-				}
-				if hToken != "" {
-					lock.Lock()
-					mdsc.Worker++
-					lock.Unlock()
-
-					tokenLock.Lock()
-					uniqueTokensUsed[hToken] = struct{}{}
-					tokenLock.Unlock()
-				} else {
-					success := tokenProvider.attemptOnCaptureBot(guildID, connectCode, gid, req)
-					if success {
-						lock.Lock()
-						mdsc.Capture++
-						lock.Unlock()
-					} else {
-						log.Printf("Applying mute=%v, deaf=%v using primary bot\n", req.Mute, req.Deaf)
-						err := task.ApplyMuteDeaf(tokenProvider.primarySession, guildID, userIDStr, req.Mute, req.Deaf)
-						if err != nil {
-							lock.Lock()
-							latestErr = err
-							lock.Unlock()
-							log.Println("Error on primary bot:")
-							log.Println(err)
-						} else {
-							lock.Lock()
-							mdsc.Official++
-							lock.Unlock()
-						}
-					}
-				}
+				fn(req)
 				wg.Done()
 			}
 		}()
 	}
 
-	for _, modifyReq := range request.Users {
+	for _, modifyReq := range users {
 		wg.Add(1)
 		tasksChannel <- modifyReq
 	}
 	wg.Wait()
 	close(tasksChannel)
-
-	RecordDiscordRequestsByCounts(tokenProvider.client, mdsc)
-
-	// note, this should probably be more systematic on startup, not when a mute/deafen task comes in. But this is a
-	// context in which we already have the guildID, successful tokens, AND the premium limit...
-	go tokenProvider.verifyBotMembership(guildID, limit, uniqueTokensUsed)
-
-	return latestErr
 }
 
 func (tokenProvider *TokenProvider) rateLimitEventCallback(sess *discordgo.Session, rl *discordgo.RateLimit) {
@@ -267,6 +288,36 @@ func (tokenProvider *TokenProvider) waitForAck(pubsub *redis.PubSub, result chan
 	}
 }
 
+// batchAckGrace is how much longer than the capture server we wait for a batch ack, so the capture server's own
+// timeout (and its partial results) always come first
+const batchAckGrace = time.Millisecond * 250
+
+func (tokenProvider *TokenProvider) waitForBatchAck(pubsub *redis.PubSub, result chan<- *task.BatchModifyAck) {
+	t := time.NewTimer(tokenProvider.taskTimeoutMs + batchAckGrace)
+	defer pubsub.Close()
+	channel := pubsub.Channel()
+
+	for {
+		select {
+		case <-t.C:
+			t.Stop()
+			result <- nil
+			return
+		case val := <-channel:
+			t.Stop()
+			var ack task.BatchModifyAck
+			err := json.Unmarshal([]byte(val.Payload), &ack)
+			if err != nil {
+				log.Println(err)
+				result <- nil
+			} else {
+				result <- &ack
+			}
+			return
+		}
+	}
+}
+
 func hashToken(token string) string {
 	h := sha256.New()
 	h.Write([]byte(token))
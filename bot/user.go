@@ -18,6 +18,21 @@ type UserData struct {
 	ShouldBeMute bool   `json:"ShouldBeMute"`
 	ShouldBeDeaf bool   `json:"ShouldBeDeaf"`
 	InGameName   string `json:"PlayerName"`
+	// GameRoleID is the role the bot gave the user for their state in the game, if any
+	GameRoleID string `json:"gameRoleID,omitempty"`
+	// SyncedNick is the nickname the bot gave the user for the game, and OriginalNick the one to give back
+	SyncedNick   string `json:"syncedNick,omitempty"`
+	OriginalNick string `json:"originalNick,omitempty"`
+	// NickUnmanageable is set once the bot fails to rename the user, so it doesn't keep trying for the rest of the game
+	NickUnmanageable bool `json:"nickUnmanageable,omitempty"`
+	// Exempt is set for a user the guild never wants muted, as of the last time they were seen in voice
+	Exempt bool `json:"exempt,omitempty"`
+	// ForeignMute and ForeignDeaf are set while someone other than the bot has the user server muted/deafened; the bot
+	// leaves those in place rather than lifting them
+	ForeignMute bool `json:"foreignMute,omitempty"`
+	ForeignDeaf bool `json:"foreignDeaf,omitempty"`
+	// Spectator is set for an unlinked member who joined mid-round, and is muted like a spectator until the next match starts
+	Spectator bool `json:"spectator,omitempty"`
 }
 
 func MakeUserDataFromDiscordUser(dUser *discordgo.User, nick string) UserData {
@@ -42,6 +57,21 @@ func (user *UserData) SetShouldBeMuteDeaf(mute, deaf bool) {
 	user.ShouldBeDeaf = deaf
 }
 
+// KeepForeignMuteDeaf adds any mute or deafen someone else put on the user to the one the bot would give them
+func (user *UserData) KeepForeignMuteDeaf(mute, deaf bool) (bool, bool) {
+	return mute || user.ForeignMute, deaf || user.ForeignDeaf
+}
+
+// KeepForeignMuteDeafOn is KeepForeignMuteDeaf for the kind of channel the game is in. On a stage, the mute stands for
+// the audience; a server mute someone else put on the player stays on them by itself, so it doesn't keep them off the
+// stage as well
+func (user *UserData) KeepForeignMuteDeafOn(stage, mute, deaf bool) (bool, bool) {
+	if stage {
+		return mute, deaf || user.ForeignDeaf
+	}
+	return user.KeepForeignMuteDeaf(mute, deaf)
+}
+
 func (user *UserData) GetUserName() string {
 	return user.User.UserName
 }
@@ -73,6 +73,7 @@ func (dgs *GameState) ClearPlayerDataByPlayerName(playerName string) {
 func (dgs *GameState) UnlinkAllUsers() {
 	for i, v := range dgs.UserData {
 		v.InGameName = amongus.UnlinkedPlayerName
+		v.Spectator = false
 		dgs.UserData[i] = v
 	}
 }
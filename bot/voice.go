@@ -2,6 +2,8 @@ package bot
 
 import (
 	"context"
+	"github.com/automuteus/automuteus/v8/internal/server"
+	"github.com/automuteus/automuteus/v8/pkg/game"
 	"github.com/automuteus/automuteus/v8/pkg/premium"
 	"github.com/automuteus/automuteus/v8/pkg/settings"
 	"github.com/automuteus/automuteus/v8/pkg/task"
@@ -26,6 +28,10 @@ func (bot *Bot) applyToSingle(dgs *GameState, userID string, mute, deaf bool) er
 	if !premium.IsExpired(prem, days) {
 		premTier = prem
 	}
+	stageID := stageChannelID(bot.PrimarySession, dgs.VoiceChannel)
+	if userData, err := dgs.GetUser(userID); err == nil {
+		mute, deaf = userData.KeepForeignMuteDeafOn(stageID != "", mute, deaf)
+	}
 	uid, _ := strconv.ParseUint(userID, 10, 64)
 	req := task.UserModifyRequest{
 		Premium: premTier,
@@ -38,7 +44,7 @@ func (bot *Bot) applyToSingle(dgs *GameState, userID string, mute, deaf bool) er
 		},
 	}
 	// nil lock because this is an override; we don't care about legitimately obtaining the lock
-	return bot.TokenProvider.ModifyUsers(dgs.GuildID, dgs.ConnectCode, req, nil)
+	return bot.issueMutesAndRecord(dgs, stageID, req, nil)
 }
 
 func (bot *Bot) applyToAll(dgs *GameState, mute, deaf bool) error {
@@ -48,6 +54,8 @@ func (bot *Bot) applyToAll(dgs *GameState, mute, deaf bool) error {
 	}
 
 	var users []task.UserModify
+	sett := bot.StorageInterface.GetGuildSettings(dgs.GuildID)
+	stageID := stageChannelID(bot.PrimarySession, dgs.VoiceChannel)
 
 	for _, voiceState := range g.VoiceStates {
 		userData, err := dgs.GetUser(voiceState.UserID)
@@ -63,15 +71,16 @@ func (bot *Bot) applyToAll(dgs *GameState, mute, deaf bool) error {
 		tracked := voiceState.ChannelID != "" && dgs.VoiceChannel == voiceState.ChannelID
 
 		_, linked := dgs.GameData.GetByName(userData.InGameName)
-		// only actually tracked if we're in a tracked channel AND linked to a player
-		tracked = tracked && linked
+		// only actually tracked if we're in a tracked channel AND linked to a player (or muted as a late joiner)
+		tracked = tracked && (linked || userData.Spectator)
 
-		if tracked {
+		if tracked && !memberIsExempt(bot.PrimarySession, sett, dgs.GuildID, voiceState.UserID) {
 			uid, _ := strconv.ParseUint(userData.User.UserID, 10, 64)
+			userMute, userDeaf := userData.KeepForeignMuteDeafOn(stageID != "", mute, deaf)
 			users = append(users, task.UserModify{
 				UserID: uid,
-				Mute:   mute,
-				Deaf:   deaf,
+				Mute:   userMute,
+				Deaf:   userDeaf,
 			})
 			log.Println("Forcibly applying mute/deaf to " + userData.User.UserID)
 		}
@@ -87,7 +96,7 @@ func (bot *Bot) applyToAll(dgs *GameState, mute, deaf bool) error {
 			Users:   users,
 		}
 		// nil lock because this is an override; we don't care about legitimately obtaining the lock
-		return bot.TokenProvider.ModifyUsers(dgs.GuildID, dgs.ConnectCode, req, nil)
+		return bot.issueMutesAndRecord(dgs, stageID, req, nil)
 	}
 	return nil
 }
@@ -100,6 +109,12 @@ func (bot *Bot) handleTrackedMembers(sess *discordgo.Session, sett *settings.Gui
 		lock, dgs = bot.RedisInterface.GetDiscordGameStateAndLock(gsr)
 	}
 
+	// everyone stays unmuted until the capture is heard from again; see restoreCapture
+	if dgs.CaptureLost {
+		lock.Release(ctx)
+		return
+	}
+
 	g, err := sess.State.Guild(dgs.GuildID)
 
 	if err != nil || g == nil {
@@ -107,9 +122,26 @@ func (bot *Bot) handleTrackedMembers(sess *discordgo.Session, sett *settings.Gui
 		return
 	}
 
+	before := dgs.userStates()
 	var users []task.UserModify
+	var moves []voiceMove
+	var conflicts []voiceConflict
+
+	// on a stage, players are moved to the audience instead of being muted
+	stageID := stageChannelID(sess, dgs.VoiceChannel)
+
+	// dead players are moved to the ghost channel during tasks instead of being muted, if the guild has one
+	ghostChannelID := sett.GetGhostChannelID()
+	// players leaving the stage for the ghost channel would lose their place on it, so it's not used for stages
+	if ghostChannelID == dgs.VoiceChannel || stageID != "" {
+		ghostChannelID = ""
+	}
+
+	// who's in the game's channels, for the spectator role
+	inVoice := make(map[string]bool)
 
 	priorityRequests := 0
+	priorityMoves := 0
 	for _, voiceState := range g.VoiceStates {
 		userData, err := dgs.GetUser(voiceState.UserID)
 		if err != nil {
@@ -119,16 +151,31 @@ func (bot *Bot) handleTrackedMembers(sess *discordgo.Session, sett *settings.Gui
 			if !added {
 				continue
 			}
+			// the bot hasn't muted them yet, so whatever they already have is someone else's
+			var foreignMute, foreignDeaf bool
+			userData, foreignMute, foreignDeaf = trackForeignMuteDeaf(userData, voiceState, nil)
+			dgs.UpdateUserData(voiceState.UserID, userData)
+			if foreignMute || foreignDeaf {
+				conflicts = append(conflicts, voiceConflict{UserID: voiceState.UserID, Mute: foreignMute, Deaf: foreignDeaf})
+			}
 		}
 
-		tracked := voiceState.ChannelID != "" && dgs.VoiceChannel == voiceState.ChannelID
+		inGhostChannel := ghostChannelID != "" && voiceState.ChannelID == ghostChannelID
+		tracked := voiceState.ChannelID != "" && (dgs.VoiceChannel == voiceState.ChannelID || inGhostChannel)
+		inVoice[voiceState.UserID] = tracked
+
+		// exempt members are left as they are, whatever the voice rules say
+		userData = dgs.updateExempt(userData, memberIsExempt(sess, sett, dgs.GuildID, voiceState.UserID))
+		if userData.Exempt {
+			continue
+		}
 
 		auData, found := dgs.GameData.GetByName(userData.InGameName)
 		// only actually tracked if we're in a tracked channel AND linked to a player
 		var isAlive bool
 
 		// only actually tracked if we're in a tracked channel AND linked to a player
-		if !sett.GetMuteSpectator() {
+		if !sett.GetMuteSpectator() && !userData.Spectator {
 			tracked = tracked && found
 			isAlive = auData.IsAlive
 		} else {
@@ -139,22 +186,63 @@ func (bot *Bot) handleTrackedMembers(sess *discordgo.Session, sett *settings.Gui
 				isAlive = auData.IsAlive
 			}
 		}
-		shouldMute, shouldDeaf := sett.GetVoiceState(isAlive, tracked, dgs.GameData.GetPhase())
+		shouldMute, shouldDeaf := sett.GetVoiceState(dgs.GameData.GetGameMode(), dgs.voiceCategory(auData, found), isAlive, tracked, dgs.GameData.GetPhase())
+		isPriority := handlePriority != NoPriority && ((handlePriority == AlivePriority && isAlive) || (handlePriority == DeadPriority && !isAlive))
+
+		if ghostChannelID != "" && tracked && found {
+			targetChannelID := dgs.VoiceChannel
+			if !isAlive && dgs.GameData.GetPhase() == game.TASKS {
+				targetChannelID = ghostChannelID
+			}
+			if targetChannelID != voiceState.ChannelID {
+				uid, _ := strconv.ParseUint(userData.User.UserID, 10, 64)
+				move := voiceMove{
+					ChannelID: targetChannelID,
+					// if the move fails, the voice rules apply to wherever the player is left
+					Fallback: task.UserModify{
+						UserID: uid,
+						Mute:   shouldMute,
+						Deaf:   shouldDeaf,
+					},
+				}
+				move.Moved = move.Fallback
+				if targetChannelID == ghostChannelID {
+					// the dead talk freely amongst themselves
+					move.Moved.Mute, move.Moved.Deaf = false, false
+				}
+				// before any mute/deafen by someone else is kept, for recordFailedMoves
+				move.FallbackRules = move.Fallback
+				userData.SetShouldBeMuteDeaf(move.Moved.Mute, move.Moved.Deaf)
+				move.Moved.Mute, move.Moved.Deaf = userData.KeepForeignMuteDeaf(move.Moved.Mute, move.Moved.Deaf)
+				move.Fallback.Mute, move.Fallback.Deaf = userData.KeepForeignMuteDeaf(move.Fallback.Mute, move.Fallback.Deaf)
+				if isPriority {
+					moves = append([]voiceMove{move}, moves...)
+					priorityMoves++
+				} else {
+					moves = append(moves, move)
+				}
+				dgs.UpdateUserData(userData.User.UserID, userData)
+				continue
+			}
+			if inGhostChannel {
+				shouldMute, shouldDeaf = false, false
+			}
+		}
 
 		incorrectMuteDeafenState := shouldMute != userData.ShouldBeMute || shouldDeaf != userData.ShouldBeDeaf
 
 		// only issue a change if the User isn't in the right state already
 		// nicksmatch can only be false if the in-game data is != nil, so the reference to .audata below is safe
 		// check the userdata is linked here to not accidentally undeafen music bots, for example
-		if incorrectMuteDeafenState && (found || sett.GetMuteSpectator()) {
+		if incorrectMuteDeafenState && (found || sett.GetMuteSpectator() || userData.Spectator) {
 			uid, _ := strconv.ParseUint(userData.User.UserID, 10, 64)
 			userModify := task.UserModify{
 				UserID: uid,
-				Mute:   shouldMute,
-				Deaf:   shouldDeaf,
 			}
+			// mutes and deafens by anyone else stay put
+			userModify.Mute, userModify.Deaf = userData.KeepForeignMuteDeafOn(stageID != "", shouldMute, shouldDeaf)
 
-			if handlePriority != NoPriority && ((handlePriority == AlivePriority && isAlive) || (handlePriority == DeadPriority && !isAlive)) {
+			if isPriority {
 				users = append([]task.UserModify{userModify}, users...)
 				priorityRequests++ // counter of how many elements on the front of the arr should be sent first
 			} else {
@@ -165,9 +253,18 @@ func (bot *Bot) handleTrackedMembers(sess *discordgo.Session, sett *settings.Gui
 		}
 	}
 
+	// roles and nicknames follow the same transitions and delays as the mutes
+	roleChanges := dgs.gameRoleChanges(sett, inVoice)
+	nickChanges := dgs.nicknameChanges(sett, g, bot.RedisInterface.GetSyncedNicknames(dgs.GuildID))
+	bot.RedisInterface.LogUserStates(dgs, before)
+
 	// we relinquish the lock while we wait
 	bot.RedisInterface.SetDiscordGameState(dgs, lock)
 
+	for _, v := range conflicts {
+		bot.logVoiceConflict(sett, v.UserID, v.Mute, v.Deaf)
+	}
+
 	voiceLock := bot.RedisInterface.LockVoiceChanges(dgs.ConnectCode, time.Second*time.Duration(delay+1))
 
 	if delay > 0 {
@@ -175,6 +272,26 @@ func (bot *Bot) handleTrackedMembers(sess *discordgo.Session, sett *settings.Gui
 		time.Sleep(time.Second * time.Duration(delay))
 	}
 
+	if dgs.Running && len(moves) > 0 {
+		// the mutes that go along with the moves are sent with everyone else's, keeping their priority
+		var movedUsers []task.UserModify
+		var failed []voiceMove
+		for i, v := range moves {
+			userModify, moved := bot.applyVoiceMove(sess, dgs.GuildID, v)
+			if !moved {
+				failed = append(failed, v)
+			}
+			if i < priorityMoves {
+				movedUsers = append(movedUsers, userModify)
+			} else {
+				users = append(users, userModify)
+			}
+		}
+		users = append(movedUsers, users...)
+		priorityRequests += len(movedUsers)
+		bot.recordFailedMoves(gsr, failed)
+	}
+
 	if dgs.Running && len(users) > 0 {
 		prem, days, _ := bot.PostgresInterface.GetGuildOrUserPremiumStatus(bot.official, nil, dgs.GuildID, "")
 		premTier := premium.FreeTier
@@ -188,7 +305,7 @@ func (bot *Bot) handleTrackedMembers(sess *discordgo.Session, sett *settings.Gui
 				Users:   users[:priorityRequests],
 			}
 			// no lock; we're not done yet
-			err := bot.issueMutesAndRecord(dgs.GuildID, dgs.ConnectCode, req, nil)
+			err := bot.issueMutesAndRecord(dgs, stageID, req, nil)
 			if err != nil {
 				log.Println(err)
 			} else {
@@ -200,7 +317,7 @@ func (bot *Bot) handleTrackedMembers(sess *discordgo.Session, sett *settings.Gui
 					Premium: premTier,
 					Users:   rem,
 				}
-				err := bot.issueMutesAndRecord(dgs.GuildID, dgs.ConnectCode, req, voiceLock)
+				err := bot.issueMutesAndRecord(dgs, stageID, req, voiceLock)
 				if err != nil {
 					log.Println(err)
 				}
@@ -214,14 +331,98 @@ func (bot *Bot) handleTrackedMembers(sess *discordgo.Session, sett *settings.Gui
 				Premium: premTier,
 				Users:   users,
 			}
-			err := bot.issueMutesAndRecord(dgs.GuildID, dgs.ConnectCode, req, voiceLock)
+			err := bot.issueMutesAndRecord(dgs, stageID, req, voiceLock)
 			if err != nil {
 				log.Println(err)
 			}
 		}
 	}
+
+	// mutes go first; they're the part players notice most
+	bot.applyGameRoleChanges(sess, dgs.GuildID, roleChanges)
+	bot.applyNicknameChanges(sess, gsr, nickChanges)
+}
+
+// voiceMove is a player to move between the game's voice channel and the ghost channel
+type voiceMove struct {
+	ChannelID string
+	// Moved is the mute/deafen to apply once the player has been moved, and Fallback the one to apply if they couldn't be
+	Moved    task.UserModify
+	Fallback task.UserModify
+	// FallbackRules is Fallback before any mute/deafen by someone else is kept; what the player's UserData should expect
+	FallbackRules task.UserModify
+}
+
+// applyVoiceMove moves the player, and returns the mute/deafen they should get after, and whether they were moved
+func (bot *Bot) applyVoiceMove(sess *discordgo.Session, guildID string, move voiceMove) (task.UserModify, bool) {
+	userID := strconv.FormatUint(move.Moved.UserID, 10)
+	channelID := move.ChannelID
+	err := sess.GuildMemberMove(guildID, userID, &channelID)
+	server.RecordDiscordRequests(bot.RedisInterface.client, server.MemberMove, 1)
+	if err != nil {
+		log.Printf("Couldn't move %s to voice channel %s, applying voice rules instead: %s\n", userID, channelID, err)
+		return move.Fallback, false
+	}
+	return move.Moved, true
+}
+
+// recordFailedMoves corrects what the players who couldn't be moved are expected to be left with; their UserData
+// assumed the move would go through
+func (bot *Bot) recordFailedMoves(gsr GameStateRequest, failed []voiceMove) {
+	if len(failed) == 0 {
+		return
+	}
+	lock, dgs := bot.RedisInterface.GetDiscordGameStateAndLock(gsr)
+	for lock == nil {
+		lock, dgs = bot.RedisInterface.GetDiscordGameStateAndLock(gsr)
+	}
+	for _, v := range failed {
+		userID := strconv.FormatUint(v.FallbackRules.UserID, 10)
+		userData, err := dgs.GetUser(userID)
+		if err != nil {
+			continue
+		}
+		userData.SetShouldBeMuteDeaf(v.FallbackRules.Mute, v.FallbackRules.Deaf)
+		dgs.UpdateUserData(userID, userData)
+	}
+	bot.RedisInterface.SetDiscordGameState(dgs, lock)
 }
 
-func (bot *Bot) issueMutesAndRecord(guildID, connectCode string, req task.UserModifyRequest, lock *redislock.Lock) error {
-	return bot.TokenProvider.ModifyUsers(guildID, connectCode, req, lock)
+// returnFromGhostChannel moves the game's players still in the ghost channel back to the game's voice channel
+func (bot *Bot) returnFromGhostChannel(sess *discordgo.Session, dgs *GameState, ghostChannelID string) {
+	if ghostChannelID == "" || dgs.VoiceChannel == "" || ghostChannelID == dgs.VoiceChannel {
+		return
+	}
+	g, err := sess.State.Guild(dgs.GuildID)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	var userIDs []string
+	for _, voiceState := range g.VoiceStates {
+		// only the game's own players; anyone else is there on their own
+		if _, err := dgs.GetUser(voiceState.UserID); err == nil && voiceState.ChannelID == ghostChannelID {
+			userIDs = append(userIDs, voiceState.UserID)
+		}
+	}
+	for _, userID := range userIDs {
+		channelID := dgs.VoiceChannel
+		err := sess.GuildMemberMove(dgs.GuildID, userID, &channelID)
+		server.RecordDiscordRequests(bot.RedisInterface.client, server.MemberMove, 1)
+		if err != nil {
+			log.Printf("Couldn't move %s back from the ghost channel: %s\n", userID, err)
+		}
+	}
+}
+
+// issueMutesAndRecord mutes/deafens the users, or, if the game is on a stage (see stageChannelID), moves them between
+// the speakers and the audience instead
+func (bot *Bot) issueMutesAndRecord(dgs *GameState, stageID string, req task.UserModifyRequest, lock *redislock.Lock) error {
+	if stageID != "" {
+		// leaving the stage undoes the suppress, so it's never left stuck; nothing to record
+		return bot.applySuppress(dgs.GuildID, stageID, req, lock)
+	}
+	err := bot.TokenProvider.ModifyUsers(dgs.GuildID, dgs.ConnectCode, req, lock)
+	bot.recordMutes(dgs, req, err == nil)
+	return err
 }
@@ -0,0 +1,171 @@
+package bot
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/automuteus/automuteus/v8/pkg/game"
+	"github.com/automuteus/automuteus/v8/pkg/settings"
+	"github.com/automuteus/automuteus/v8/pkg/task"
+	"github.com/bwmarrin/discordgo"
+)
+
+// ghostTransport records moves separately from mutes, and refuses to move the users in failMoves
+type ghostTransport struct {
+	replayTransport
+	moveLock  sync.Mutex
+	moves     map[string]string
+	failMoves map[string]bool
+}
+
+func (gt *ghostTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	parts := strings.Split(strings.TrimSuffix(req.URL.Path, "/"), "/")
+	if req.Method == http.MethodPatch && req.Body != nil {
+		var body map[string]interface{}
+		err := json.NewDecoder(req.Body).Decode(&body)
+		if err != nil {
+			return nil, err
+		}
+		userID := parts[len(parts)-1]
+		if channelID, ok := body["channel_id"].(string); ok {
+			if gt.failMoves[userID] {
+				return replayResponse(req, http.StatusForbidden, `{"message":"Missing Permissions","code":50013}`), nil
+			}
+			gt.moveLock.Lock()
+			gt.moves[userID] = channelID
+			gt.moveLock.Unlock()
+			return replayResponse(req, http.StatusOK, "{}"), nil
+		}
+		uid, _ := strconv.ParseUint(userID, 10, 64)
+		mute, _ := body["mute"].(bool)
+		deaf, _ := body["deaf"].(bool)
+		gt.replayTransport.Lock()
+		gt.modifies = append(gt.modifies, task.UserModify{UserID: uid, Mute: mute, Deaf: deaf})
+		gt.replayTransport.Unlock()
+		return replayResponse(req, http.StatusOK, "{}"), nil
+	}
+	return gt.replayTransport.RoundTrip(req)
+}
+
+func (gt *ghostTransport) drainMoves() map[string]string {
+	gt.moveLock.Lock()
+	defer gt.moveLock.Unlock()
+	moves := gt.moves
+	gt.moves = make(map[string]string)
+	return moves
+}
+
+func TestGhostChannelMoves(t *testing.T) {
+	const (
+		guildID        = "100"
+		voiceChannelID = "200"
+		ghostChannelID = "300"
+	)
+
+	mr := miniredis.RunT(t)
+	stopClock := runReplayClock(mr)
+	defer stopClock()
+
+	dgs := NewDiscordGameState(guildID)
+	dgs.ConnectCode = "ABCD1234"
+	dgs.VoiceChannel = voiceChannelID
+	dgs.Running = true
+	dgs.GameData.UpdatePhase(game.LOBBY)
+	dgs.GameData.UpdatePhase(game.TASKS)
+
+	var members []*discordgo.Member
+	var voiceStates []*discordgo.VoiceState
+	for i, name := range []string{"Red", "Blue", "Green"} {
+		userID := string(rune('1' + i))
+		dgs.GameData.UpdatePlayer(game.Player{Action: game.JOINED, Name: name, Color: i})
+		if i > 0 {
+			dgs.GameData.UpdatePlayer(game.Player{Action: game.DIED, Name: name, Color: i, IsDead: true})
+		}
+		member := &discordgo.Member{User: &discordgo.User{ID: userID, Username: name}}
+		userData := MakeUserDataFromDiscordUser(member.User, "")
+		player, _ := dgs.GameData.GetByName(name)
+		userData.Link(player)
+		dgs.UpdateUserData(userID, userData)
+		members = append(members, member)
+		voiceStates = append(voiceStates, &discordgo.VoiceState{GuildID: guildID, UserID: userID, ChannelID: voiceChannelID})
+	}
+
+	transport := &ghostTransport{moves: make(map[string]string), failMoves: map[string]bool{"3": true}}
+	bot, err := newReplayBot(mr.Addr(), transport, &RecordSnapshot{
+		GameState:   dgs,
+		VoiceStates: voiceStates,
+		Members:     members,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bot.RedisInterface.Close()
+	defer bot.StorageInterface.Close()
+
+	sett := settings.MakeGuildSettings()
+	sett.SetGhostChannelID(ghostChannelID)
+	// so the fallback for a failed move can be told apart from the move itself
+	sett.SetVoiceRule(game.NormalMode, true, game.TASKS, "dead", true)
+	bot.RedisInterface.SetDiscordGameState(dgs, nil)
+	gsr := GameStateRequest{GuildID: guildID, ConnectCode: dgs.ConnectCode}
+
+	bot.handleTrackedMembers(bot.PrimarySession, sett, 0, DeadPriority, gsr)
+
+	if moves := transport.drainMoves(); !reflect.DeepEqual(moves, map[string]string{"2": ghostChannelID}) {
+		t.Errorf("expected only Blue to be moved to the ghost channel, got %v", moves)
+	}
+	expected := []task.UserModify{
+		{UserID: 1, Mute: true, Deaf: true},
+		// moved, so free to talk
+		{UserID: 2, Mute: false, Deaf: false},
+		// couldn't be moved, so muted like any dead player
+		{UserID: 3, Mute: true, Deaf: false},
+	}
+	if modifies := transport.drain(); !reflect.DeepEqual(modifies, expected) {
+		t.Errorf("expected mutes %+v, got %+v", expected, modifies)
+	}
+
+	// Green stays in the main channel, muted like any dead player
+	state := bot.RedisInterface.GetReadOnlyDiscordGameState(gsr)
+	if userData, _ := state.GetUser("3"); !userData.ShouldBeMute || userData.ShouldBeDeaf {
+		t.Errorf("expected Green to be expected muted after the failed move, got %+v", userData)
+	}
+
+	// Blue is in the ghost channel now, and comes back for the discussion
+	g, _ := bot.PrimarySession.State.Guild(guildID)
+	g.VoiceStates[1].ChannelID = ghostChannelID
+	lock, state := bot.RedisInterface.GetDiscordGameStateAndLock(gsr)
+	for lock == nil {
+		lock, state = bot.RedisInterface.GetDiscordGameStateAndLock(gsr)
+	}
+	state.GameData.UpdatePhase(game.DISCUSS)
+	bot.RedisInterface.SetDiscordGameState(state, lock)
+
+	bot.handleTrackedMembers(bot.PrimarySession, sett, 0, NoPriority, gsr)
+
+	if moves := transport.drainMoves(); !reflect.DeepEqual(moves, map[string]string{"2": voiceChannelID}) {
+		t.Errorf("expected Blue to be moved back for the discussion, got %v", moves)
+	}
+	expected = []task.UserModify{
+		{UserID: 1, Mute: false, Deaf: false},
+		{UserID: 2, Mute: true, Deaf: false},
+	}
+	if modifies := transport.drain(); !reflect.DeepEqual(modifies, expected) {
+		t.Errorf("expected mutes %+v, got %+v", expected, modifies)
+	}
+
+	// the game ends with Blue back in the ghost channel
+	g.VoiceStates[1].ChannelID = ghostChannelID
+	bot.StorageInterface.SetGuildSettings(guildID, sett)
+	bot.forceEndGame(gsr)
+
+	if moves := transport.drainMoves(); !reflect.DeepEqual(moves, map[string]string{"2": voiceChannelID}) {
+		t.Errorf("expected Blue to be moved back when the game ended, got %v", moves)
+	}
+}
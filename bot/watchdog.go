@@ -0,0 +1,109 @@
+package bot
+
+import (
+	"log"
+	"time"
+
+	"github.com/automuteus/automuteus/v8/internal/server"
+	"github.com/automuteus/automuteus/v8/pkg/game"
+	"github.com/automuteus/automuteus/v8/pkg/settings"
+)
+
+// CaptureWatchdogInterval is how often a game's subscriber checks whether its capture has gone silent
+const CaptureWatchdogInterval = time.Second * 5
+
+// checkCaptureWatchdog unmutes everyone when a game's capture has been silent for longer than the guild's grace
+// period during Tasks or Discussion, so players aren't left muted until the game times out.
+// Once the capture is heard from again, the voice rules are re-applied
+func (bot *Bot) checkCaptureWatchdog(dgsRequest GameStateRequest, lastJob time.Time) {
+	dgs := bot.RedisInterface.GetReadOnlyDiscordGameState(dgsRequest)
+	if dgs == nil {
+		return
+	}
+	heartbeat, err := server.GetCaptureHeartbeat(ctx, bot.RedisInterface.client, dgsRequest.ConnectCode)
+	if err != nil {
+		log.Println(err)
+	}
+	if !heartbeat.IsZero() && !dgs.CaptureHeartbeatSeen {
+		bot.markCaptureHeartbeatSeen(dgsRequest)
+		dgs.CaptureHeartbeatSeen = true
+	}
+
+	sett := bot.StorageInterface.GetGuildSettings(dgsRequest.GuildID)
+	dgs.applyGameSettings(sett)
+	grace := sett.GetCaptureGraceSeconds()
+	// the heartbeat only says the socket is open, not that the capture is still reading the game, so silence is timed
+	// from the last job. It does say a capture server is in the loop; without one, nor the capture telling us it
+	// disconnected, the watchdog stays off
+	armed := dgs.CaptureHeartbeatSeen || dgs.CaptureDisconnected
+	silent := armed && grace > 0 && time.Since(lastJob) > time.Second*time.Duration(grace)
+
+	switch {
+	case dgs.CaptureLost && !silent:
+		bot.restoreCapture(dgsRequest, sett)
+	case !dgs.CaptureLost && silent && dgs.Running:
+		phase := dgs.GameData.GetPhase()
+		if phase == game.TASKS || phase == game.DISCUSS {
+			bot.markCaptureLost(dgsRequest, sett)
+		}
+	}
+}
+
+func (bot *Bot) markCaptureHeartbeatSeen(dgsRequest GameStateRequest) {
+	lock, dgs := bot.RedisInterface.GetDiscordGameStateAndLock(dgsRequest)
+	for lock == nil {
+		lock, dgs = bot.RedisInterface.GetDiscordGameStateAndLock(dgsRequest)
+	}
+	dgs.CaptureHeartbeatSeen = true
+	bot.RedisInterface.SetDiscordGameState(dgs, lock)
+}
+
+func (bot *Bot) markCaptureLost(dgsRequest GameStateRequest, sett *settings.GuildSettings) {
+	lock, dgs := bot.RedisInterface.GetDiscordGameStateAndLock(dgsRequest)
+	for lock == nil {
+		lock, dgs = bot.RedisInterface.GetDiscordGameStateAndLock(dgsRequest)
+	}
+	if dgs.CaptureLost {
+		lock.Release(ctx)
+		return
+	}
+	log.Printf("No capture activity for %s in %d seconds; unmuting everyone\n", dgsRequest.ConnectCode, sett.GetCaptureGraceSeconds())
+	dgs.CaptureLost = true
+	// forget what everyone should be, so the voice rules are re-applied in full once the capture is back
+	for userID, userData := range dgs.UserData {
+		userData.SetShouldBeMuteDeaf(false, false)
+		dgs.UpdateUserData(userID, userData)
+	}
+	bot.RedisInterface.LogCaptureLost(dgs)
+	bot.RedisInterface.SetDiscordGameState(dgs, lock)
+	server.RecordCaptureEvents(bot.RedisInterface.client, server.CaptureLost, 1)
+
+	err := bot.applyToAll(dgs, false, false)
+	if err != nil {
+		log.Println("Error in unmuting all users after losing the capture ", err)
+	}
+	bot.DispatchRefreshOrEdit(dgs, dgsRequest, sett)
+}
+
+// restoreCapture is a no-op unless the watchdog previously marked the capture as lost
+func (bot *Bot) restoreCapture(dgsRequest GameStateRequest, sett *settings.GuildSettings) {
+	// this runs for every job, so avoid taking the lock in the common case
+	if readOnlyDgs := bot.RedisInterface.GetReadOnlyDiscordGameState(dgsRequest); readOnlyDgs == nil || !readOnlyDgs.CaptureLost {
+		return
+	}
+	lock, dgs := bot.RedisInterface.GetDiscordGameStateAndLock(dgsRequest)
+	for lock == nil {
+		lock, dgs = bot.RedisInterface.GetDiscordGameStateAndLock(dgsRequest)
+	}
+	if !dgs.CaptureLost {
+		lock.Release(ctx)
+		return
+	}
+	log.Printf("Capture for %s is back; re-applying voice rules\n", dgsRequest.ConnectCode)
+	dgs.CaptureLost = false
+	bot.RedisInterface.LogCaptureLost(dgs)
+	bot.RedisInterface.SetDiscordGameState(dgs, lock)
+
+	bot.handleTrackedMembers(bot.PrimarySession, sett, 0, NoPriority, dgsRequest)
+	bot.DispatchRefreshOrEdit(dgs, dgsRequest, sett)
+}
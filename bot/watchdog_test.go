@@ -0,0 +1,109 @@
+package bot
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/automuteus/automuteus/v8/pkg/game"
+	"github.com/automuteus/automuteus/v8/pkg/rediskey"
+	"github.com/automuteus/automuteus/v8/pkg/settings"
+	"github.com/automuteus/automuteus/v8/pkg/task"
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestCaptureWatchdog(t *testing.T) {
+	const guildID = "100"
+	const voiceChannelID = "200"
+
+	mr := miniredis.RunT(t)
+	stopClock := runReplayClock(mr)
+	defer stopClock()
+
+	dgs := NewDiscordGameState(guildID)
+	dgs.ConnectCode = "ABCD1234"
+	dgs.VoiceChannel = voiceChannelID
+	dgs.Running = true
+	dgs.Linked = true
+	dgs.GameData.UpdatePlayer(game.Player{Action: game.JOINED, Name: "Red", Color: game.Red})
+	dgs.GameData.UpdatePhase(game.TASKS)
+
+	member := &discordgo.Member{User: &discordgo.User{ID: "1", Username: "red"}}
+	userData := MakeUserDataFromDiscordUser(member.User, "")
+	player, _ := dgs.GameData.GetByName("Red")
+	userData.Link(player)
+	userData.SetShouldBeMuteDeaf(true, true)
+	dgs.UpdateUserData("1", userData)
+
+	transport := &replayTransport{}
+	bot, err := newReplayBot(mr.Addr(), transport, &RecordSnapshot{
+		GameState:   dgs,
+		VoiceStates: []*discordgo.VoiceState{{GuildID: guildID, ChannelID: voiceChannelID, UserID: "1"}},
+		Members:     []*discordgo.Member{member},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bot.RedisInterface.Close()
+	defer bot.StorageInterface.Close()
+
+	sett := settings.MakeGuildSettings()
+	sett.SetCaptureGraceSeconds(30)
+	err = bot.StorageInterface.SetGuildSettings(guildID, sett)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bot.RedisInterface.SetDiscordGameState(dgs, nil)
+	gsr := GameStateRequest{GuildID: guildID, ConnectCode: dgs.ConnectCode}
+
+	expectModifies := func(expected []task.UserModify) {
+		t.Helper()
+		modifies := transport.drain()
+		if len(modifies) != len(expected) {
+			t.Fatalf("expected %+v, got %+v", expected, modifies)
+		}
+		for i, v := range expected {
+			if modifies[i] != v {
+				t.Errorf("expected %+v, got %+v", v, modifies[i])
+			}
+		}
+	}
+
+	// a capture without the capture server's heartbeat sends nothing during a quiet round; that's not being gone
+	bot.checkCaptureWatchdog(gsr, time.Now().Add(-time.Minute))
+	expectModifies(nil)
+
+	// once a heartbeat's been seen, the watchdog is armed. Within the grace period, nothing happens
+	mr.Set(rediskey.CaptureHeartbeat(dgs.ConnectCode), strconv.FormatInt(time.Now().Unix(), 10))
+	bot.checkCaptureWatchdog(gsr, time.Now().Add(-time.Second*10))
+	expectModifies(nil)
+
+	// past it, everyone is unmuted and the game is marked as lost, even while the capture's socket is still open
+	bot.checkCaptureWatchdog(gsr, time.Now().Add(-time.Minute))
+	expectModifies([]task.UserModify{{UserID: 1, Mute: false, Deaf: false}})
+	if !bot.RedisInterface.GetReadOnlyDiscordGameState(gsr).CaptureLost {
+		t.Error("expected the game to be marked as capture lost")
+	}
+
+	// and it stays that way
+	bot.checkCaptureWatchdog(gsr, time.Now().Add(-time.Minute))
+	expectModifies(nil)
+
+	// a job from the capture brings the voice rules back
+	bot.checkCaptureWatchdog(gsr, time.Now())
+	expectModifies([]task.UserModify{{UserID: 1, Mute: true, Deaf: true}})
+	if bot.RedisInterface.GetReadOnlyDiscordGameState(gsr).CaptureLost {
+		t.Error("expected the game to no longer be marked as capture lost")
+	}
+
+	// the watchdog only acts during Tasks and Discussion
+	lock, state := bot.RedisInterface.GetDiscordGameStateAndLock(gsr)
+	for lock == nil {
+		lock, state = bot.RedisInterface.GetDiscordGameStateAndLock(gsr)
+	}
+	state.GameData.UpdatePhase(game.LOBBY)
+	bot.RedisInterface.SetDiscordGameState(state, lock)
+	bot.checkCaptureWatchdog(gsr, time.Now().Add(-time.Minute))
+	expectModifies(nil)
+}
@@ -0,0 +1,82 @@
+package bot
+
+import (
+	"sort"
+
+	"github.com/automuteus/automuteus/v8/pkg/amongus"
+	"github.com/automuteus/automuteus/v8/pkg/game"
+	"github.com/automuteus/automuteus/v8/pkg/settings"
+	"github.com/automuteus/automuteus/v8/pkg/webhook"
+)
+
+// fireWebhook sends an event to the guild's webhooks in the background. It's a no-op for bots without a dispatcher,
+// like the one used for replays
+func (bot *Bot) fireWebhook(sett *settings.GuildSettings, dgs *GameState, event webhook.Event, data interface{}) {
+	if bot.webhooks == nil || sett == nil || dgs == nil {
+		return
+	}
+	subs := sett.GetWebhooks()
+	if len(subs) == 0 {
+		return
+	}
+	payload := webhook.Payload{
+		Event:       event,
+		GuildID:     dgs.GuildID,
+		ConnectCode: dgs.ConnectCode,
+		Data:        data,
+	}
+	if dgs.MatchID > 0 {
+		payload.MatchID = dgs.MatchID
+	}
+	bot.webhooks.Dispatch(subs, payload)
+}
+
+// webhookPlayer describes an in-game player, and the Discord user linked to them if there is one
+func webhookPlayer(dgs *GameState, player amongus.PlayerData) webhook.PlayerData {
+	data := webhook.PlayerData{
+		Name:  player.Name,
+		Color: game.GetColorStringForInt(player.Color),
+	}
+	for userID, v := range dgs.UserData {
+		if v.GetPlayerName() == player.Name {
+			data.UserID = userID
+			break
+		}
+	}
+	return data
+}
+
+func webhookPlayers(dgs *GameState) []webhook.PlayerData {
+	var players []webhook.PlayerData
+	for _, v := range dgs.GameData.PlayerData {
+		players = append(players, webhookPlayer(dgs, v))
+	}
+	sort.Slice(players, func(i, j int) bool {
+		return players[i].Name < players[j].Name
+	})
+	return players
+}
+
+func webhookGameOver(dgs *GameState, gameOver game.Gameover) webhook.GameOverData {
+	data := webhook.GameOverData{
+		Reason:       int16(gameOver.GameOverReason),
+		ImpostorsWon: gameOver.ImpostorsWon(),
+	}
+	for _, v := range gameOver.PlayerInfos {
+		player := webhook.GameOverPlayer{
+			Name:     v.Name,
+			Impostor: v.GetTeam() == game.ImpostorTeam,
+			Won:      gameOver.IsWinner(v),
+			Team:     string(v.GetTeam()),
+			Role:     v.Role,
+		}
+		for userID, u := range dgs.UserData {
+			if u.GetPlayerName() == v.Name {
+				player.UserID = userID
+				break
+			}
+		}
+		data.Players = append(data.Players, player)
+	}
+	return data
+}
@@ -0,0 +1,103 @@
+package bot
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/automuteus/automuteus/v8/pkg/game"
+	"github.com/automuteus/automuteus/v8/pkg/settings"
+	"github.com/automuteus/automuteus/v8/pkg/webhook"
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestTransitionWebhooks(t *testing.T) {
+	const guildID = "100"
+
+	mr := miniredis.RunT(t)
+	stopClock := runReplayClock(mr)
+	defer stopClock()
+
+	dgs := NewDiscordGameState(guildID)
+	dgs.ConnectCode = "ABCD1234"
+	dgs.VoiceChannel = "200"
+	dgs.Running = true
+	dgs.GameData.UpdatePhase(game.LOBBY)
+	dgs.GameData.UpdatePlayer(game.Player{Action: game.JOINED, Name: "Red", Color: game.Red})
+
+	member := &discordgo.Member{User: &discordgo.User{ID: "1", Username: "red"}}
+	userData := MakeUserDataFromDiscordUser(member.User, "")
+	player, _ := dgs.GameData.GetByName("Red")
+	userData.Link(player)
+	dgs.UpdateUserData("1", userData)
+
+	bot, err := newReplayBot(mr.Addr(), &replayTransport{}, &RecordSnapshot{
+		GameState: dgs,
+		Members:   []*discordgo.Member{member},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bot.RedisInterface.Close()
+	defer bot.StorageInterface.Close()
+
+	received := make(chan webhook.Payload, 10)
+	sub := webhook.NewSubscription("", []webhook.Event{webhook.GameStart, webhook.PhaseChange})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !webhook.Verify(sub.Secret, body, r.Header.Get(webhook.SignatureHeader)) {
+			t.Error("webhook signature doesn't match")
+		}
+		var payload webhook.Payload
+		err := json.Unmarshal(body, &payload)
+		if err != nil {
+			t.Error(err)
+		}
+		received <- payload
+	}))
+	defer srv.Close()
+	sub.URL = srv.URL
+	bot.webhooks = &webhook.Dispatcher{Client: srv.Client(), MaxAttempts: 1}
+
+	sett := settings.MakeGuildSettings()
+	sett.SetDelay(game.LOBBY, game.TASKS, 0)
+	sett.AddWebhook(sub)
+	err = bot.StorageInterface.SetGuildSettings(guildID, sett)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bot.RedisInterface.SetDiscordGameState(dgs, nil)
+
+	bot.processTransition(game.TASKS, GameStateRequest{GuildID: guildID, ConnectCode: dgs.ConnectCode})
+
+	events := make(map[webhook.Event]webhook.Payload)
+	for len(events) < 2 {
+		select {
+		case payload := <-received:
+			events[payload.Event] = payload
+		case <-time.After(time.Second * 5):
+			t.Fatalf("timed out waiting for webhooks, got %v", events)
+		}
+	}
+
+	start, ok := events[webhook.GameStart]
+	if !ok || start.GuildID != guildID || start.ConnectCode != dgs.ConnectCode {
+		t.Errorf("unexpected game start payload %+v", start)
+	}
+	players, _ := start.Data.([]interface{})
+	if len(players) != 1 {
+		t.Fatalf("expected one player in the game start payload, got %+v", start.Data)
+	}
+	if p, _ := players[0].(map[string]interface{}); p["userID"] != "1" || p["color"] != "red" {
+		t.Errorf("unexpected player %+v", p)
+	}
+
+	phase := events[webhook.PhaseChange]
+	if data, _ := phase.Data.(map[string]interface{}); data["from"] != "LOBBY" || data["to"] != "TASKS" {
+		t.Errorf("unexpected phase change payload %+v", phase.Data)
+	}
+}
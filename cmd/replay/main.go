@@ -0,0 +1,76 @@
+// Command replay feeds a recorded capture session back through the bot, against an in-memory Redis and a fake
+// Discord API, and prints the mutes/deafens the bot decided on. Diff the output across versions to chase mute bugs.
+//
+// Recordings are made by running the bot with CAPTURE_RECORDER=file (or redis).
+//
+//	go run ./cmd/replay -file recordings/ABCD1234-1690000000.jsonl
+//	go run ./cmd/replay -redis localhost:6379 -code ABCD1234
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/automuteus/automuteus/v8/bot"
+	"github.com/automuteus/automuteus/v8/pkg/task"
+	"github.com/go-redis/redis/v8"
+)
+
+var jobNames = map[task.JobType]string{
+	task.ConnectionJob: "connection",
+	task.LobbyJob:      "lobby",
+	task.StateJob:      "state",
+	task.PlayerJob:     "player",
+	task.GameOverJob:   "gameover",
+}
+
+func main() {
+	file := flag.String("file", "", "JSONL recording to replay")
+	redisAddr := flag.String("redis", "", "Redis address to read a recording from, instead of a file")
+	code := flag.String("code", "", "connect code of the recording in Redis")
+	keepDelays := flag.Bool("keep-delays", false, "sleep for the recorded guild's phase delays, like the bot would")
+	verbose := flag.Bool("v", false, "show the bot's logs")
+	flag.Parse()
+
+	if !*verbose {
+		log.SetOutput(io.Discard)
+	}
+
+	err := run(*file, *redisAddr, *code, *keepDelays)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(file, redisAddr, code string, keepDelays bool) error {
+	var entries []bot.RecordEntry
+	var err error
+	switch {
+	case file != "":
+		entries, err = bot.ReadRecordingFile(file)
+	case redisAddr != "" && code != "":
+		client := redis.NewClient(&redis.Options{Addr: redisAddr})
+		defer client.Close()
+		entries, err = bot.ReadRecordingStream(client, code)
+	default:
+		return errors.New("provide either -file, or -redis and -code")
+	}
+	if err != nil {
+		return err
+	}
+
+	decisions, err := bot.ReplayRecording(entries, keepDelays)
+	for _, d := range decisions {
+		if d.Job == nil {
+			fmt.Printf("end\t\t\tuser=%d mute=%v deaf=%v\n", d.UserID, d.Mute, d.Deaf)
+			continue
+		}
+		fmt.Printf("#%d\t%s(%s)\tuser=%d mute=%v deaf=%v\n", d.JobIndex, jobNames[d.Job.JobType], d.Job.RawPayload(), d.UserID, d.Mute, d.Deaf)
+	}
+	return err
+}
@@ -0,0 +1,454 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/automuteus/automuteus/v8/pkg/rediskey"
+	"github.com/automuteus/automuteus/v8/pkg/task"
+	"github.com/go-redis/redis/v8"
+	socketio "github.com/googollee/go-socket.io"
+	"github.com/gorilla/mux"
+)
+
+const DefaultCapturePort = "8123"
+
+const (
+	// CaptureHeartbeatInterval is how often a linked capture's heartbeat is refreshed while its socket is open
+	CaptureHeartbeatInterval = time.Second * 5
+
+	// captureHeartbeatTTL lets the heartbeat lapse on its own if this server goes away without releasing the code
+	captureHeartbeatTTL = CaptureHeartbeatInterval * 3
+)
+
+// ResendPlayersEvent asks the capture to send every player in the lobby again, after the bot noticed events went missing.
+// It needs a client that knows about it; see replayState for how the gap is recovered from without one
+const ResendPlayersEvent = "resendPlayers"
+
+// ErrInvalidConnectCode is returned when a capture client presents a code that doesn't belong to any active game
+var ErrInvalidConnectCode = errors.New("connect code is not associated with an active game")
+
+// CaptureServer is the socket endpoint that AmongUsCapture connects to (the HOST url handed out by /new).
+// It translates capture events into task.Jobs for the bot, and forwards mute/deafen tasks back to the capture
+type CaptureServer struct {
+	client   *redis.Client
+	io       *socketio.Server
+	instance string
+}
+
+// captureConn is the per-socket state; it is stored as the socket's context
+type captureConn struct {
+	sync.Mutex
+	connectCode string
+	clientID    string
+	leader      bool
+	cancel      context.CancelFunc
+	// acks routes the client's task acks for batches that are being fanned out by this server
+	acks map[string]chan bool
+}
+
+func (cc *captureConn) code() string {
+	cc.Lock()
+	defer cc.Unlock()
+	return cc.connectCode
+}
+
+func (cc *captureConn) isLeader() bool {
+	cc.Lock()
+	defer cc.Unlock()
+	return cc.leader
+}
+
+func (cc *captureConn) await(taskID string) <-chan bool {
+	cc.Lock()
+	defer cc.Unlock()
+	if cc.acks == nil {
+		cc.acks = make(map[string]chan bool)
+	}
+	ack := make(chan bool, 1)
+	cc.acks[taskID] = ack
+	return ack
+}
+
+func (cc *captureConn) forget(taskID string) {
+	cc.Lock()
+	defer cc.Unlock()
+	delete(cc.acks, taskID)
+}
+
+// resolve reports whether the ack belonged to a batch being fanned out by this server
+func (cc *captureConn) resolve(taskID string, success bool) bool {
+	cc.Lock()
+	defer cc.Unlock()
+	ack, ok := cc.acks[taskID]
+	if ok {
+		ack <- success
+		delete(cc.acks, taskID)
+	}
+	return ok
+}
+
+func NewCaptureServer(client *redis.Client) *CaptureServer {
+	cs := &CaptureServer{
+		client:   client,
+		io:       socketio.NewServer(nil),
+		instance: newInstanceID(),
+	}
+
+	cs.io.OnConnect("/", func(s socketio.Conn) error {
+		s.SetContext(&captureConn{})
+		log.Println("Capture client connected: " + s.ID())
+		return nil
+	})
+
+	cs.io.OnEvent("/", "connectCode", func(s socketio.Conn, msg string) {
+		cc, ok := s.Context().(*captureConn)
+		if !ok {
+			return
+		}
+		err := cs.authenticate(msg)
+		if err != nil {
+			log.Printf("Capture client %s sent connect code \"%s\": %s\n", s.ID(), msg, err)
+			s.Close()
+			return
+		}
+		cs.release(cc)
+
+		clientID := cs.instance + ":" + s.ID()
+		taskCtx, cancel := context.WithCancel(context.Background())
+		cc.Lock()
+		cc.connectCode = msg
+		cc.clientID = clientID
+		cc.cancel = cancel
+		cc.Unlock()
+
+		err = cs.registerClient(taskCtx, msg, clientID)
+		if err != nil {
+			log.Println(err)
+		}
+		log.Printf("Capture client %s linked to connect code %s\n", clientID, msg)
+		cs.campaign(taskCtx, cc)
+		go cs.forwardTasks(taskCtx, s, cc, msg, clientID)
+		go cs.heartbeat(taskCtx, cc, msg, clientID)
+	})
+
+	cs.io.OnEvent("/", "lobby", cs.jobHandler(task.LobbyJob))
+	cs.io.OnEvent("/", "state", cs.jobHandler(task.StateJob))
+	cs.io.OnEvent("/", "player", cs.jobHandler(task.PlayerJob))
+	cs.io.OnEvent("/", "gameover", cs.jobHandler(task.GameOverJob))
+
+	cs.io.OnEvent("/", "taskComplete", cs.ackHandler(true))
+	cs.io.OnEvent("/", "taskFailed", cs.ackHandler(false))
+
+	cs.io.OnError("/", func(s socketio.Conn, err error) {
+		log.Println(err)
+	})
+
+	cs.io.OnDisconnect("/", func(s socketio.Conn, reason string) {
+		if cc, ok := s.Context().(*captureConn); ok {
+			cs.release(cc)
+		}
+		log.Printf("Capture client %s disconnected: %s\n", s.ID(), reason)
+	})
+
+	go func() {
+		err := cs.io.Serve()
+		if err != nil {
+			log.Println(err)
+		}
+	}()
+
+	return cs
+}
+
+// authenticate only accepts codes for games that a bot is currently subscribed to
+func (cs *CaptureServer) authenticate(connectCode string) error {
+	if connectCode == "" {
+		return ErrInvalidConnectCode
+	}
+	_, err := cs.client.ZScore(context.Background(), rediskey.ActiveGamesZSet, connectCode).Result()
+	if errors.Is(err, redis.Nil) {
+		return ErrInvalidConnectCode
+	}
+	return err
+}
+
+// campaign tries to make the socket the leader for its code, and reports whether it is. A socket that newly becomes
+// the leader tells the bot that a capture is connected, since its events are the ones used from now on
+func (cs *CaptureServer) campaign(ctx context.Context, cc *captureConn) bool {
+	cc.Lock()
+	code, clientID, wasLeader := cc.connectCode, cc.clientID, cc.leader
+	cc.Unlock()
+	if code == "" || ctx.Err() != nil {
+		return false
+	}
+
+	leader, err := cs.electLeader(ctx, code, clientID)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Println(err)
+		}
+		return false
+	}
+	cc.Lock()
+	if cc.clientID == clientID {
+		cc.leader = leader
+	}
+	cc.Unlock()
+
+	if leader && !wasLeader {
+		log.Printf("Capture client %s is now the leader for connect code %s\n", clientID, code)
+		cs.pushJob(code, task.ConnectionJob, "true")
+	} else if !leader && wasLeader {
+		log.Printf("Capture client %s is no longer the leader for connect code %s\n", clientID, code)
+	}
+	return leader
+}
+
+// release unlinks a socket from its connect code. The bot is only told the capture went away once no other client
+// is linked to the same code
+func (cs *CaptureServer) release(cc *captureConn) {
+	cc.Lock()
+	code, clientID, leader, cancel := cc.connectCode, cc.clientID, cc.leader, cc.cancel
+	cc.connectCode, cc.clientID, cc.leader, cc.cancel = "", "", false, nil
+	cc.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+	if code == "" {
+		return
+	}
+
+	ctx := context.Background()
+	if leader {
+		err := cs.resignLeader(ctx, code, clientID)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+	remaining, err := cs.unregisterClient(ctx, code, clientID)
+	if err != nil {
+		log.Println(err)
+	}
+	if remaining > 0 {
+		log.Printf("%d other capture client(s) still linked to connect code %s\n", remaining, code)
+		return
+	}
+
+	err = cs.client.Del(ctx, rediskey.CaptureHeartbeat(code)).Err()
+	if err != nil {
+		log.Println(err)
+	}
+	cs.pushJob(code, task.ConnectionJob, "false")
+}
+
+func (cs *CaptureServer) jobHandler(jobType task.JobType) func(socketio.Conn, string) {
+	return func(s socketio.Conn, msg string) {
+		cc, ok := s.Context().(*captureConn)
+		if !ok {
+			return
+		}
+		code := cc.code()
+		if code == "" {
+			log.Printf("Capture client %s sent an event before a connect code; ignoring\n", s.ID())
+			return
+		}
+		// only the leader's events are used; a follower takes over as soon as the leader's key lapses
+		if !cc.isLeader() && !cs.campaign(context.Background(), cc) {
+			return
+		}
+		cs.pushJob(code, jobType, msg)
+		err := cs.rememberEvent(context.Background(), code, jobType, msg)
+		if err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+func (cs *CaptureServer) ackHandler(success bool) func(socketio.Conn, string) {
+	payload := "false"
+	if success {
+		payload = "true"
+	}
+	return func(s socketio.Conn, taskID string) {
+		if cc, ok := s.Context().(*captureConn); ok && cc.resolve(taskID, success) {
+			return
+		}
+		err := cs.client.Publish(context.Background(), rediskey.CompleteTask(taskID), payload).Err()
+		if err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+func (cs *CaptureServer) pushJob(connectCode string, jobType task.JobType, payload string) {
+	err := task.PushJob(context.Background(), cs.client, connectCode, jobType, payload)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// forwardTasks relays the mute/deafen tasks that the token provider hands to this particular client, and the bot's
+// requests for the leader to resend its players
+func (cs *CaptureServer) forwardTasks(ctx context.Context, s socketio.Conn, cc *captureConn, connectCode, clientID string) {
+	tasks := rediskey.CaptureClientTasks(connectCode, clientID)
+	pubsub := cs.client.Subscribe(ctx, tasks, rediskey.CaptureResend(connectCode))
+	defer pubsub.Close()
+	channel := pubsub.Channel()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-channel:
+			if !ok {
+				return
+			}
+			if msg.Channel == tasks {
+				var batch task.BatchModifyTask
+				if json.Unmarshal([]byte(msg.Payload), &batch) == nil && len(batch.Request.Users) > 0 {
+					go cs.fanOutBatch(ctx, s, cc, batch)
+				} else {
+					s.Emit("modify", msg.Payload)
+				}
+			} else if cc.isLeader() {
+				log.Printf("Asking capture client %s to resend its players\n", clientID)
+				s.Emit(ResendPlayersEvent)
+				err := cs.replayState(ctx, connectCode)
+				if err != nil {
+					log.Println(err)
+				}
+			}
+		}
+	}
+}
+
+// fanOutBatch hands a batch to the capture client one "modify" at a time, as that's all AmongUsCapture understands,
+// and answers the bot with a single ack once every user is done or the batch times out
+func (cs *CaptureServer) fanOutBatch(ctx context.Context, s socketio.Conn, cc *captureConn, batch task.BatchModifyTask) {
+	users := batch.Request.Users
+	acks := make([]<-chan bool, len(users))
+	taskIDs := make([]string, len(users))
+	for i, user := range users {
+		modifyTask := task.NewModifyTask(batch.GuildID, user.UserID, task.PatchParams{
+			Deaf: user.Deaf,
+			Mute: user.Mute,
+		})
+		// the tasks of one batch would otherwise share IDs
+		modifyTask.TaskID = fmt.Sprintf("%s-%d", batch.TaskID, i)
+		taskIDs[i] = modifyTask.TaskID
+		acks[i] = cc.await(modifyTask.TaskID)
+
+		jBytes, err := json.Marshal(modifyTask)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		s.Emit("modify", string(jBytes))
+	}
+
+	result := task.BatchModifyAck{
+		TaskID:  batch.TaskID,
+		Results: make([]task.UserModifyResult, len(users)),
+	}
+	timeout := time.NewTimer(time.Millisecond * time.Duration(batch.TimeoutMs))
+	defer timeout.Stop()
+	timedOut := false
+	for i, user := range users {
+		result.Results[i].UserID = user.UserID
+		if timedOut {
+			continue
+		}
+		select {
+		case success := <-acks[i]:
+			result.Results[i].Success = success
+		case <-timeout.C:
+			timedOut = true
+		case <-ctx.Done():
+			timedOut = true
+		}
+	}
+	for _, taskID := range taskIDs {
+		cc.forget(taskID)
+	}
+
+	jBytes, err := json.Marshal(result)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	err = cs.client.Publish(context.Background(), rediskey.CompleteTask(batch.TaskID), string(jBytes)).Err()
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// RequestPlayerResend asks the leading capture client for a code to resend its full player list
+func RequestPlayerResend(ctx context.Context, client *redis.Client, connectCode string) error {
+	return client.Publish(ctx, rediskey.CaptureResend(connectCode), "").Err()
+}
+
+// heartbeat records that a capture for the code is still connected, even when the game itself is quiet (e.g. a long
+// tasks phase). The bot's capture watchdog reads it; socket.io's own pings close dead sockets, which stops the heartbeat.
+// Each beat also keeps the client registered, and renews (or takes over) the leader key
+func (cs *CaptureServer) heartbeat(ctx context.Context, cc *captureConn, connectCode, clientID string) {
+	ticker := time.NewTicker(CaptureHeartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		err := cs.client.Set(ctx, rediskey.CaptureHeartbeat(connectCode), time.Now().Unix(), captureHeartbeatTTL).Err()
+		if err == nil {
+			err = cs.registerClient(ctx, connectCode, clientID)
+		}
+		if err != nil && ctx.Err() == nil {
+			log.Println(err)
+		}
+		cs.campaign(ctx, cc)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// GetCaptureHeartbeat returns the last time a capture linked to the code was known to be connected, or the zero time
+func GetCaptureHeartbeat(ctx context.Context, client *redis.Client, connectCode string) (time.Time, error) {
+	str, err := client.Get(ctx, rediskey.CaptureHeartbeat(connectCode)).Result()
+	if errors.Is(err, redis.Nil) {
+		return time.Time{}, nil
+	} else if err != nil {
+		return time.Time{}, err
+	}
+	unix, err := strconv.ParseInt(str, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(unix, 0), nil
+}
+
+func (cs *CaptureServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	cs.io.ServeHTTP(w, r)
+}
+
+func (cs *CaptureServer) Close() error {
+	return cs.io.Close()
+}
+
+func StartCaptureServer(client *redis.Client, port string) error {
+	cs := NewCaptureServer(client)
+	defer cs.Close()
+
+	r := mux.NewRouter()
+	r.Handle("/socket.io/", cs)
+
+	return http.ListenAndServe(":"+port, r)
+}
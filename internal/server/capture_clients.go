@@ -0,0 +1,102 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/automuteus/automuteus/v8/pkg/rediskey"
+	"github.com/go-redis/redis/v8"
+)
+
+// Several capture clients can be linked to the same connect code (e.g. two people running AmongUsCapture in the same
+// lobby for redundancy). Every linked client is tracked in a sorted set scored by its last heartbeat, and one of them
+// holds a short-lived leader key. Only the leader's game events become jobs; when it goes away, the next client to
+// heartbeat or send an event takes over. Mute/deafen tasks can be handed to any of the clients
+
+// renew the leader key if we hold it, otherwise take it if nobody does
+var electLeaderScript = redis.NewScript(`
+local current = redis.call("GET", KEYS[1])
+if current == ARGV[1] then
+	redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	return 1
+end
+if not current then
+	redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+	return 1
+end
+return 0
+`)
+
+var resignLeaderScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// newInstanceID distinguishes this capture server from others sharing the same Redis; socket IDs are only unique
+// within a single server
+func newInstanceID() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "automuteus"
+	}
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), strconv.FormatInt(time.Now().UnixNano(), 36))
+}
+
+func (cs *CaptureServer) registerClient(ctx context.Context, connectCode, clientID string) error {
+	key := rediskey.CaptureClients(connectCode)
+	err := cs.client.ZAdd(ctx, key, &redis.Z{Score: float64(time.Now().Unix()), Member: clientID}).Err()
+	if err != nil {
+		return err
+	}
+	return cs.client.Expire(ctx, key, captureHeartbeatTTL).Err()
+}
+
+// unregisterClient returns how many clients are still linked to the code
+func (cs *CaptureServer) unregisterClient(ctx context.Context, connectCode, clientID string) (int, error) {
+	err := cs.client.ZRem(ctx, rediskey.CaptureClients(connectCode), clientID).Err()
+	if err != nil {
+		return 0, err
+	}
+	clients, err := GetCaptureClients(ctx, cs.client, connectCode)
+	return len(clients), err
+}
+
+// electLeader reports whether clientID is (still) the leader for the code
+func (cs *CaptureServer) electLeader(ctx context.Context, connectCode, clientID string) (bool, error) {
+	res, err := electLeaderScript.Run(ctx, cs.client, []string{rediskey.CaptureLeader(connectCode)}, clientID, captureHeartbeatTTL.Milliseconds()).Int()
+	return res == 1, err
+}
+
+func (cs *CaptureServer) resignLeader(ctx context.Context, connectCode, clientID string) error {
+	return resignLeaderScript.Run(ctx, cs.client, []string{rediskey.CaptureLeader(connectCode)}, clientID).Err()
+}
+
+// GetCaptureClients lists the capture clients that are linked to the code and still heartbeating, in a stable order
+func GetCaptureClients(ctx context.Context, client *redis.Client, connectCode string) ([]string, error) {
+	oldest := time.Now().Add(-captureHeartbeatTTL).Unix()
+	clients, err := client.ZRangeByScore(ctx, rediskey.CaptureClients(connectCode), &redis.ZRangeBy{
+		Min: strconv.FormatInt(oldest, 10),
+		Max: "+inf",
+	}).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	sort.Strings(clients)
+	return clients, err
+}
+
+// GetCaptureLeader returns the client whose events are currently used for the code, or "" if there isn't one
+func GetCaptureLeader(ctx context.Context, client *redis.Client, connectCode string) (string, error) {
+	leader, err := client.Get(ctx, rediskey.CaptureLeader(connectCode)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", nil
+	}
+	return leader, err
+}
@@ -0,0 +1,135 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/automuteus/automuteus/v8/pkg/game"
+	"github.com/automuteus/automuteus/v8/pkg/rediskey"
+	"github.com/automuteus/automuteus/v8/pkg/task"
+)
+
+// ResendPlayersEvent is our own, and stock AmongUsCapture clients never answer it. So the capture server also keeps
+// the latest lobby, the phase, and the latest event of every player still in the game for a code, and after the bot
+// reports a sequence gap it queues them again itself. Replaying them is harmless when nothing was missed: the bot only
+// acts on a phase or player that differs from what it already has
+
+const (
+	lobbyStateField   = "lobby"
+	phaseStateField   = "phase"
+	playerStatePrefix = "player:"
+)
+
+// rememberEvent keeps the event for replayState. Players who leave are forgotten, a new lobby starts everyone alive
+// again, and leaving for the menu forgets the players altogether
+func (cs *CaptureServer) rememberEvent(ctx context.Context, connectCode string, jobType task.JobType, payload string) error {
+	key := rediskey.CaptureState(connectCode)
+	switch jobType {
+	case task.LobbyJob:
+		err := cs.client.HSet(ctx, key, lobbyStateField, payload).Err()
+		if err != nil {
+			return err
+		}
+	case task.PlayerJob:
+		var player game.Player
+		err := json.Unmarshal([]byte(payload), &player)
+		if err != nil || player.Name == "" {
+			return err
+		}
+		if player.Disconnected || player.Action == game.LEFT || player.Action == game.DISCONNECTED {
+			return cs.client.HDel(ctx, key, playerStatePrefix+player.Name).Err()
+		}
+		// a replayed join would be taken as the player joining all over again
+		if player.Action == game.JOINED {
+			player.Action = game.FORCEUPDATED
+		}
+		jBytes, err := json.Marshal(player)
+		if err != nil {
+			return err
+		}
+		err = cs.client.HSet(ctx, key, playerStatePrefix+player.Name, jBytes).Err()
+		if err != nil {
+			return err
+		}
+	case task.StateJob:
+		phase, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil
+		}
+		switch game.Phase(phase) {
+		case game.MENU:
+			return cs.client.Del(ctx, key).Err()
+		case game.GAMEOVER:
+			// the game over itself isn't replayed, just the lobby that follows
+			return nil
+		}
+		err = cs.client.HSet(ctx, key, phaseStateField, payload).Err()
+		if err != nil {
+			return err
+		}
+		if game.Phase(phase) == game.LOBBY {
+			err = cs.reviveRememberedPlayers(ctx, connectCode)
+			if err != nil {
+				return err
+			}
+		}
+	default:
+		return nil
+	}
+	return cs.client.Expire(ctx, key, task.JobTTLSeconds*time.Second).Err()
+}
+
+func (cs *CaptureServer) reviveRememberedPlayers(ctx context.Context, connectCode string) error {
+	key := rediskey.CaptureState(connectCode)
+	fields, err := cs.client.HGetAll(ctx, key).Result()
+	if err != nil {
+		return err
+	}
+	for field, v := range fields {
+		var player game.Player
+		if !strings.HasPrefix(field, playerStatePrefix) || json.Unmarshal([]byte(v), &player) != nil || !player.IsDead {
+			continue
+		}
+		// an exile would kill them again
+		player.Action = game.FORCEUPDATED
+		player.IsDead = false
+		jBytes, err := json.Marshal(player)
+		if err != nil {
+			return err
+		}
+		err = cs.client.HSet(ctx, key, field, jBytes).Err()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replayState queues the remembered lobby and phase, then every remembered player, as new jobs for the bot
+func (cs *CaptureServer) replayState(ctx context.Context, connectCode string) error {
+	fields, err := cs.client.HGetAll(ctx, rediskey.CaptureState(connectCode)).Result()
+	if err != nil {
+		return err
+	}
+	if lobby, ok := fields[lobbyStateField]; ok {
+		cs.pushJob(connectCode, task.LobbyJob, lobby)
+	}
+	if phase, ok := fields[phaseStateField]; ok {
+		cs.pushJob(connectCode, task.StateJob, phase)
+	}
+	var players []string
+	for field := range fields {
+		if strings.HasPrefix(field, playerStatePrefix) {
+			players = append(players, field)
+		}
+	}
+	sort.Strings(players)
+	for _, field := range players {
+		cs.pushJob(connectCode, task.PlayerJob, fields[field])
+	}
+	return nil
+}
@@ -0,0 +1,408 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/automuteus/automuteus/v8/pkg/rediskey"
+	"github.com/automuteus/automuteus/v8/pkg/task"
+	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/websocket"
+)
+
+const testConnectCode = "ABCD1234"
+
+// fakeCapture speaks just enough of the socket.io (EIO=3) wire protocol to stand in for AmongUsCapture
+type fakeCapture struct {
+	t    *testing.T
+	conn *websocket.Conn
+}
+
+func dialFakeCapture(t *testing.T, url string) *fakeCapture {
+	wsURL := "ws" + strings.TrimPrefix(url, "http") + "/socket.io/?EIO=3&transport=websocket"
+	conn, _, err := websocket.DefaultDialer.Dial(wsURL, nil)
+	if err != nil {
+		t.Fatalf("failed to dial capture server: %v", err)
+	}
+	fc := &fakeCapture{t: t, conn: conn}
+	// engine.io open, then socket.io connect on the root namespace
+	if msg := fc.read(); !strings.HasPrefix(msg, "0") {
+		t.Fatalf("expected engine.io open packet, got %s", msg)
+	}
+	if msg := fc.read(); msg != "40" {
+		t.Fatalf("expected socket.io connect packet, got %s", msg)
+	}
+	return fc
+}
+
+func (fc *fakeCapture) emit(event, payload string) {
+	jBytes, err := json.Marshal([]string{event, payload})
+	if err != nil {
+		fc.t.Fatal(err)
+	}
+	err = fc.conn.WriteMessage(websocket.TextMessage, append([]byte("42"), jBytes...))
+	if err != nil {
+		fc.t.Fatalf("failed to emit %s: %v", event, err)
+	}
+}
+
+func (fc *fakeCapture) read() string {
+	fc.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, msg, err := fc.conn.ReadMessage()
+	if err != nil {
+		fc.t.Fatalf("failed to read from capture server: %v", err)
+	}
+	return string(msg)
+}
+
+func setupCaptureServer(t *testing.T) (*redis.Client, *httptest.Server) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	cs := NewCaptureServer(client)
+	ts := httptest.NewServer(cs)
+	t.Cleanup(func() {
+		ts.Close()
+		cs.Close()
+		client.Close()
+	})
+	return client, ts
+}
+
+func expectJob(t *testing.T, client *redis.Client, jobType task.JobType, payload string) {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		job, err := task.PopJob(context.Background(), client, testConnectCode)
+		if errors.Is(err, redis.Nil) {
+			time.Sleep(10 * time.Millisecond)
+			continue
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if job.JobType != jobType || job.RawPayload() != payload {
+			t.Fatalf("expected job %d with payload %s, got %d with payload %s", jobType, payload, job.JobType, job.RawPayload())
+		}
+		return
+	}
+	t.Fatalf("timed out waiting for job %d", jobType)
+}
+
+func waitForSubscriber(t *testing.T, client *redis.Client, channel string) {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		subs, err := client.PubSubNumSub(context.Background(), channel).Result()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if subs[channel] > 0 {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("timed out waiting for a subscriber on %s", channel)
+}
+
+func TestCaptureServer(t *testing.T) {
+	client, ts := setupCaptureServer(t)
+	ctx := context.Background()
+
+	client.ZAdd(ctx, rediskey.ActiveGamesZSet, &redis.Z{Score: float64(time.Now().Unix()), Member: testConnectCode})
+
+	capture := dialFakeCapture(t, ts.URL)
+
+	capture.emit("lobby", `{"LobbyCode":"ABCDEF","Region":0,"Map":0}`)
+	capture.emit("connectCode", testConnectCode)
+	expectJob(t, client, task.ConnectionJob, "true")
+
+	// a linked capture keeps a heartbeat up for the bot's watchdog
+	heartbeat, err := GetCaptureHeartbeat(ctx, client, testConnectCode)
+	if err != nil || time.Since(heartbeat) > CaptureHeartbeatInterval {
+		t.Errorf("expected a fresh capture heartbeat, got %v (%v)", heartbeat, err)
+	}
+
+	capture.emit("lobby", `{"LobbyCode":"ABCDEF","Region":0,"Map":0}`)
+	expectJob(t, client, task.LobbyJob, `{"LobbyCode":"ABCDEF","Region":0,"Map":0}`)
+	capture.emit("state", "1")
+	expectJob(t, client, task.StateJob, "1")
+	capture.emit("player", `{"Action":0,"Name":"Red","Color":0,"IsDead":false,"Disconnected":false}`)
+	expectJob(t, client, task.PlayerJob, `{"Action":0,"Name":"Red","Color":0,"IsDead":false,"Disconnected":false}`)
+	capture.emit("gameover", `{"GameOverReason":0,"PlayerInfos":[]}`)
+	expectJob(t, client, task.GameOverJob, `{"GameOverReason":0,"PlayerInfos":[]}`)
+
+	// tasks handed to this client by the token provider are relayed to the capture as "modify"
+	clients, err := GetCaptureClients(ctx, client, testConnectCode)
+	if err != nil || len(clients) != 1 {
+		t.Fatalf("expected one linked capture client, got %v (%v)", clients, err)
+	}
+	waitForSubscriber(t, client, rediskey.CaptureClientTasks(testConnectCode, clients[0]))
+	taskObj := task.NewModifyTask(1, 2, task.PatchParams{Mute: true})
+	jBytes, err := json.Marshal(taskObj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Publish(ctx, rediskey.CaptureClientTasks(testConnectCode, clients[0]), jBytes)
+	msg := capture.read()
+	var frame []string
+	err = json.Unmarshal([]byte(strings.TrimPrefix(msg, "42")), &frame)
+	if err != nil || len(frame) != 2 || frame[0] != "modify" || frame[1] != string(jBytes) {
+		t.Fatalf("expected modify task %s, got %s", jBytes, msg)
+	}
+
+	// so are the bot's requests for the leader to resend its players
+	err = RequestPlayerResend(ctx, client, testConnectCode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg := strings.TrimSpace(capture.read()); msg != `42["`+ResendPlayersEvent+`"]` {
+		t.Fatalf("expected a %s request, got %s", ResendPlayersEvent, msg)
+	}
+	// and, for clients that don't know that event, the lobby, phase and players are queued again from what they already sent
+	expectJob(t, client, task.LobbyJob, `{"LobbyCode":"ABCDEF","Region":0,"Map":0}`)
+	expectJob(t, client, task.StateJob, "1")
+	expectJob(t, client, task.PlayerJob, `{"Action":4,"Name":"Red","Color":0,"IsDead":false,"Disconnected":false}`)
+
+	for _, v := range []struct {
+		event    string
+		expected string
+	}{
+		{"taskComplete", "true"},
+		{"taskFailed", "false"},
+	} {
+		pubsub := client.Subscribe(ctx, rediskey.CompleteTask(taskObj.TaskID))
+		_, err = pubsub.Receive(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		capture.emit(v.event, taskObj.TaskID)
+		select {
+		case ack := <-pubsub.Channel():
+			if ack.Payload != v.expected {
+				t.Errorf("expected %s to publish %s, got %s", v.event, v.expected, ack.Payload)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %s ack", v.event)
+		}
+		pubsub.Close()
+	}
+
+	capture.conn.Close()
+	expectJob(t, client, task.ConnectionJob, "false")
+
+	heartbeat, err = GetCaptureHeartbeat(ctx, client, testConnectCode)
+	if err != nil || !heartbeat.IsZero() {
+		t.Errorf("expected the heartbeat to be cleared on disconnect, got %v (%v)", heartbeat, err)
+	}
+}
+
+func TestCaptureServer_InvalidConnectCode(t *testing.T) {
+	client, ts := setupCaptureServer(t)
+
+	capture := dialFakeCapture(t, ts.URL)
+	capture.emit("connectCode", testConnectCode)
+
+	// the server hangs up on unknown codes
+	capture.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		_, _, err := capture.conn.ReadMessage()
+		if err != nil {
+			break
+		}
+	}
+
+	n, err := client.XLen(context.Background(), rediskey.JobStream(testConnectCode)).Result()
+	if errors.Is(err, redis.Nil) {
+		n, err = 0, nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Errorf("expected no jobs for an unauthenticated code, got %d", n)
+	}
+}
+
+func TestCaptureServer_MultipleClients(t *testing.T) {
+	client, ts := setupCaptureServer(t)
+	ctx := context.Background()
+
+	client.ZAdd(ctx, rediskey.ActiveGamesZSet, &redis.Z{Score: float64(time.Now().Unix()), Member: testConnectCode})
+
+	// the first capture to link becomes the leader
+	first := dialFakeCapture(t, ts.URL)
+	first.emit("connectCode", testConnectCode)
+	expectJob(t, client, task.ConnectionJob, "true")
+	second := dialFakeCapture(t, ts.URL)
+	second.emit("connectCode", testConnectCode)
+
+	deadline := time.Now().Add(5 * time.Second)
+	var clients []string
+	for time.Now().Before(deadline) {
+		clients, _ = GetCaptureClients(ctx, client, testConnectCode)
+		if len(clients) == 2 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if len(clients) != 2 {
+		t.Fatalf("expected two linked capture clients, got %v", clients)
+	}
+	leader, err := GetCaptureLeader(ctx, client, testConnectCode)
+	if err != nil || leader == "" {
+		t.Fatalf("expected a leader, got \"%s\" (%v)", leader, err)
+	}
+
+	// both report the same event, but only the leader's becomes a job
+	second.emit("state", "1")
+	first.emit("state", "1")
+	expectJob(t, client, task.StateJob, "1")
+
+	// the follower takes over once the leader goes away, without the bot seeing a disconnect
+	first.conn.Close()
+	deadline = time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		clients, _ = GetCaptureClients(ctx, client, testConnectCode)
+		if len(clients) == 1 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	second.emit("state", "2")
+	expectJob(t, client, task.ConnectionJob, "true")
+	expectJob(t, client, task.StateJob, "2")
+	newLeader, err := GetCaptureLeader(ctx, client, testConnectCode)
+	if err != nil || newLeader == leader || newLeader != clients[0] {
+		t.Errorf("expected %s to take over from %s, got \"%s\" (%v)", clients[0], leader, newLeader, err)
+	}
+
+	second.conn.Close()
+	expectJob(t, client, task.ConnectionJob, "false")
+}
+
+func TestCaptureServer_BatchModify(t *testing.T) {
+	client, ts := setupCaptureServer(t)
+	ctx := context.Background()
+
+	client.ZAdd(ctx, rediskey.ActiveGamesZSet, &redis.Z{Score: float64(time.Now().Unix()), Member: testConnectCode})
+
+	capture := dialFakeCapture(t, ts.URL)
+	capture.emit("connectCode", testConnectCode)
+	expectJob(t, client, task.ConnectionJob, "true")
+
+	clients, err := GetCaptureClients(ctx, client, testConnectCode)
+	if err != nil || len(clients) != 1 {
+		t.Fatalf("expected one linked capture client, got %v (%v)", clients, err)
+	}
+	channel := rediskey.CaptureClientTasks(testConnectCode, clients[0])
+	waitForSubscriber(t, client, channel)
+
+	batch := task.NewBatchModifyTask(1, task.UserModifyRequest{
+		Users: []task.UserModify{
+			{UserID: 2, Mute: true},
+			{UserID: 3, Mute: true, Deaf: true},
+		},
+	}, 5*time.Second)
+	pubsub := client.Subscribe(ctx, rediskey.CompleteTask(batch.TaskID))
+	defer pubsub.Close()
+	_, err = pubsub.Receive(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jBytes, err := json.Marshal(batch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Publish(ctx, channel, jBytes)
+
+	// the capture only understands single tasks, so it gets one "modify" per user; fail the second one
+	for i, ackEvent := range []string{"taskComplete", "taskFailed"} {
+		var frame []string
+		msg := capture.read()
+		err = json.Unmarshal([]byte(strings.TrimPrefix(msg, "42")), &frame)
+		if err != nil || len(frame) != 2 || frame[0] != "modify" {
+			t.Fatalf("expected a modify task, got %s", msg)
+		}
+		var modifyTask task.ModifyTask
+		err = json.Unmarshal([]byte(frame[1]), &modifyTask)
+		if err != nil || modifyTask.UserID != batch.Request.Users[i].UserID || modifyTask.Parameters.Deaf != batch.Request.Users[i].Deaf {
+			t.Fatalf("expected a task for user %d, got %s", batch.Request.Users[i].UserID, frame[1])
+		}
+		capture.emit(ackEvent, modifyTask.TaskID)
+	}
+
+	select {
+	case msg := <-pubsub.Channel():
+		var ack task.BatchModifyAck
+		err = json.Unmarshal([]byte(msg.Payload), &ack)
+		if err != nil {
+			t.Fatal(err)
+		}
+		failed := ack.Failed(batch.Request)
+		if len(failed) != 1 || failed[0].UserID != 3 {
+			t.Errorf("expected only user 3 to fail, got %+v", ack)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the batch ack")
+	}
+}
+
+func TestCaptureServer_ReplayState(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	cs := NewCaptureServer(client)
+	defer cs.Close()
+	ctx := context.Background()
+
+	remember := func(jobType task.JobType, payload string) {
+		t.Helper()
+		err := cs.rememberEvent(ctx, testConnectCode, jobType, payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	replay := func() {
+		t.Helper()
+		err := cs.replayState(ctx, testConnectCode)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	remember(task.LobbyJob, `{"LobbyCode":"ABCDEF","Region":0,"Map":0}`)
+	remember(task.PlayerJob, `{"Action":0,"Name":"Red","Color":0,"IsDead":false,"Disconnected":false}`)
+	remember(task.PlayerJob, `{"Action":0,"Name":"Blue","Color":1,"IsDead":false,"Disconnected":false}`)
+	remember(task.PlayerJob, `{"Action":0,"Name":"Green","Color":2,"IsDead":false,"Disconnected":false}`)
+	remember(task.StateJob, "1")
+	remember(task.PlayerJob, `{"Action":2,"Name":"Red","Color":0,"IsDead":true,"Disconnected":false}`)
+	// someone who left isn't replayed, so they aren't purged (and unlinked) all over again
+	remember(task.PlayerJob, `{"Action":1,"Name":"Green","Color":2,"IsDead":false,"Disconnected":false}`)
+
+	replay()
+	expectJob(t, client, task.LobbyJob, `{"LobbyCode":"ABCDEF","Region":0,"Map":0}`)
+	expectJob(t, client, task.StateJob, "1")
+	expectJob(t, client, task.PlayerJob, `{"Action":4,"Name":"Blue","Color":1,"IsDead":false,"Disconnected":false}`)
+	expectJob(t, client, task.PlayerJob, `{"Action":2,"Name":"Red","Color":0,"IsDead":true,"Disconnected":false}`)
+
+	// back in the lobby, nobody is dead any more
+	remember(task.StateJob, "4")
+	remember(task.StateJob, "0")
+	replay()
+	expectJob(t, client, task.LobbyJob, `{"LobbyCode":"ABCDEF","Region":0,"Map":0}`)
+	expectJob(t, client, task.StateJob, "0")
+	expectJob(t, client, task.PlayerJob, `{"Action":4,"Name":"Blue","Color":1,"IsDead":false,"Disconnected":false}`)
+	expectJob(t, client, task.PlayerJob, `{"Action":4,"Name":"Red","Color":0,"IsDead":false,"Disconnected":false}`)
+	if job, err := task.ReadJob(ctx, client, testConnectCode, -1); !errors.Is(err, redis.Nil) {
+		t.Errorf("expected nothing else to be replayed, got %+v (%v)", job, err)
+	}
+
+	// and leaving for the menu leaves nothing to replay
+	remember(task.StateJob, "3")
+	if mr.Exists(rediskey.CaptureState(testConnectCode)) {
+		t.Error("expected the remembered state to be forgotten in the menu")
+	}
+}
@@ -21,6 +21,10 @@ const (
 	MuteDeafenCapture
 	MuteDeafenWorker
 	InvalidRequest
+	MemberMove
+	MemberRole
+	MemberNickname
+	MemberSuppress
 	OfficialRequest //must be the last metric
 )
 
@@ -31,11 +35,47 @@ var MetricTypeStrings = []string{
 	"mute_deafen_capture",
 	"mute_deafen_worker",
 	"invalid_request",
+	"member_move",
+	"member_role",
+	"member_nickname",
+	"member_suppress",
 	"official_request", //must be the last request
 }
 
+// CaptureEventType counts things that happen with capture data, as opposed to requests made to Discord
+type CaptureEventType int
+
+const (
+	InvalidCapturePayload CaptureEventType = iota
+	CaptureLost
+	StaleCaptureJob
+	CaptureSequenceGap
+)
+
+var CaptureMetricTypeStrings = []string{
+	"invalid_payload",
+	"capture_lost",
+	"stale_job",
+	"sequence_gap",
+}
+
+// VoiceDriftType counts players found in a different voice state than the bot last put them in
+type VoiceDriftType int
+
+const (
+	MuteDrift VoiceDriftType = iota
+	DeafDrift
+)
+
+var VoiceDriftTypeStrings = []string{
+	"mute",
+	"deaf",
+}
+
 type Collector struct {
 	counterDesc *prometheus.Desc
+	captureDesc *prometheus.Desc
+	driftDesc   *prometheus.Desc
 	client      *redis.Client
 	commit      string
 	nodeID      string
@@ -43,6 +83,8 @@ type Collector struct {
 
 func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
 	ch <- c.counterDesc
+	ch <- c.captureDesc
+	ch <- c.driftDesc
 }
 
 func (c *Collector) Collect(ch chan<- prometheus.Metric) {
@@ -84,6 +126,36 @@ func (c *Collector) Collect(ch chan<- prometheus.Metric) {
 			)
 		}
 	}
+
+	for _, str := range CaptureMetricTypeStrings {
+		v, err := c.client.Get(context.Background(), rediskey.CaptureEventsByType(str)).Int64()
+		if !errors.Is(err, redis.Nil) && err != nil {
+			log.Println(err)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.captureDesc,
+			prometheus.CounterValue,
+			float64(v),
+			c.nodeID,
+			str,
+		)
+	}
+
+	for _, str := range VoiceDriftTypeStrings {
+		v, err := c.client.Get(context.Background(), rediskey.VoiceDriftByType(str)).Int64()
+		if !errors.Is(err, redis.Nil) && err != nil {
+			log.Println(err)
+			continue
+		}
+		ch <- prometheus.MustNewConstMetric(
+			c.driftDesc,
+			prometheus.CounterValue,
+			float64(v),
+			c.nodeID,
+			str,
+		)
+	}
 }
 
 func RecordDiscordRequests(client *redis.Client, requestType EventType, num int64) {
@@ -93,9 +165,19 @@ func RecordDiscordRequests(client *redis.Client, requestType EventType, num int6
 	}
 }
 
+func RecordCaptureEvents(client *redis.Client, eventType CaptureEventType, num int64) {
+	client.IncrBy(context.Background(), rediskey.CaptureEventsByType(CaptureMetricTypeStrings[eventType]), num)
+}
+
+func RecordVoiceDrift(client *redis.Client, driftType VoiceDriftType, num int64) {
+	client.IncrBy(context.Background(), rediskey.VoiceDriftByType(VoiceDriftTypeStrings[driftType]), num)
+}
+
 func NewCollector(client *redis.Client, nodeID string) *Collector {
 	return &Collector{
 		counterDesc: prometheus.NewDesc("discord_requests_by_node_and_type", "Number of discord requests made, differentiated by node/type", []string{"nodeID", "type"}, nil),
+		captureDesc: prometheus.NewDesc("capture_events_by_node_and_type", "Number of capture events of note, differentiated by node/type", []string{"nodeID", "type"}, nil),
+		driftDesc:   prometheus.NewDesc("voice_drift_by_node_and_type", "Number of players found muted/deafened differently than they should be, differentiated by node/type", []string{"nodeID", "type"}, nil),
 		client:      client,
 		nodeID:      nodeID,
 	}
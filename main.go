@@ -251,6 +251,22 @@ func discordMainWrapper() error {
 		}
 	}
 
+	// opt-in recording of capture sessions, for replaying mute bugs with cmd/replay
+	recorderMode := os.Getenv("CAPTURE_RECORDER")
+	if recorderMode != "" {
+		recordPath := os.Getenv("CAPTURE_RECORD_PATH")
+		if recordPath == "" {
+			recordPath = path.Join(logPath, "recordings")
+		}
+		for i := range bots {
+			err = bots[i].EnableCaptureRecording(recorderMode, recordPath)
+			if err != nil {
+				return err
+			}
+		}
+		log.Printf("Recording capture sessions using CAPTURE_RECORDER=%s\n", recorderMode)
+	}
+
 	// initialize the token provider using the first shard's redis client and primary session
 	bots[0].InitTokenProvider(tokenProvider)
 	for i := 0; i < len(shards); i++ {
@@ -262,8 +278,28 @@ func discordMainWrapper() error {
 
 	go bots[0].StartMetricsServer(os.Getenv("SCW_NODE_ID"))
 
+	// unmute anyone left muted by a game that's gone, like one the bot was stopped in the middle of
+	for i := range bots {
+		go bots[i].StartStuckMuteSweeper()
+	}
+
 	go bots[0].StartAPIServer("5000")
 
+	// the capture server replaces the external broker; self-hosters still running one can opt out
+	if os.Getenv("DISABLE_CAPTURE_SERVER") == "" {
+		capturePort := os.Getenv("CAPTURE_PORT")
+		if capturePort == "" {
+			capturePort = server.DefaultCapturePort
+		}
+		log.Printf("Starting capture server on port %s\n", capturePort)
+		go func() {
+			err := bots[0].StartCaptureServer(capturePort)
+			if err != nil {
+				log.Println(err)
+			}
+		}()
+	}
+
 	// empty string entry = global
 	slashCommandGuildIds := []string{""}
 	slashCommandGuildIdStr := strings.ReplaceAll(os.Getenv("SLASH_COMMAND_GUILD_IDS"), " ", "")
@@ -10,10 +10,11 @@ type GameData struct {
 	//indexed by amongusname
 	PlayerData map[string]PlayerData `json:"playerData"`
 
-	Phase  game.Phase   `json:"phase"`
-	Room   string       `json:"room"`
-	Region string       `json:"region"`
-	Map    game.PlayMap `json:"map"`
+	Phase  game.Phase    `json:"phase"`
+	Room   string        `json:"room"`
+	Region string        `json:"region"`
+	Map    game.PlayMap  `json:"map"`
+	Mode   game.GameMode `json:"mode,omitempty"`
 }
 
 func NewGameData() GameData {
@@ -32,6 +33,7 @@ func (auData *GameData) Reset() {
 	auData.Room = ""
 	auData.Region = ""
 	auData.Map = game.EMPTYMAP
+	auData.Mode = game.UnknownMode
 }
 
 func (auData *GameData) SetRoomRegionMap(room, region string, playMap game.PlayMap) {
@@ -40,6 +42,14 @@ func (auData *GameData) SetRoomRegionMap(room, region string, playMap game.PlayM
 	auData.Map = playMap
 }
 
+func (auData *GameData) SetGameMode(mode game.GameMode) {
+	auData.Mode = mode
+}
+
+func (auData *GameData) GetGameMode() game.GameMode {
+	return auData.Mode
+}
+
 func (auData *GameData) GetRoomRegionMap() (string, string, game.PlayMap) {
 	return auData.Room, auData.Region, auData.Map
 }
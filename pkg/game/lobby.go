@@ -4,4 +4,6 @@ type Lobby struct {
 	LobbyCode string  `json:"LobbyCode"`
 	Region    Region  `json:"Region"`
 	PlayMap   PlayMap `json:"Map"`
+	// GameMode is only sent by captures that support Hide and Seek
+	GameMode GameMode `json:"GameMode,omitempty"`
 }
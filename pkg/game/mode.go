@@ -0,0 +1,25 @@
+package game
+
+// GameMode matches Among Us's own GameModes values, which is what the capture sends in its lobby data
+type GameMode int
+
+const (
+	// UnknownMode is what older captures send, as they don't report the mode; it's played as a normal game
+	UnknownMode GameMode = iota
+	NormalMode
+	HideAndSeekMode
+)
+
+var GameModeNames = map[GameMode]string{
+	UnknownMode:     "Normal",
+	NormalMode:      "Normal",
+	HideAndSeekMode: "Hide and Seek",
+}
+
+func (mode GameMode) ToString() string {
+	return GameModeNames[mode]
+}
+
+func (mode GameMode) IsHideAndSeek() bool {
+	return mode == HideAndSeekMode
+}
@@ -1,6 +1,9 @@
 package game
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"strings"
+)
 
 type GameResult int16
 
@@ -22,11 +25,32 @@ func (r *Gameover) Marshal() ([]byte, error) {
 type Gameover struct {
 	GameOverReason GameResult   `json:"GameOverReason"`
 	PlayerInfos    []PlayerInfo `json:"PlayerInfos"`
+	// WinningTeam is only sent by modded captures, whose games can end in ways GameOverReason doesn't cover
+	WinningTeam Team `json:"WinningTeam,omitempty"`
 }
 
 type PlayerInfo struct {
 	Name       string `json:"Name"`
 	IsImpostor bool   `json:"IsImpostor"`
+
+	// Role and Team are only sent by modded captures (Town of Us, The Other Roles, ...); e.g. "Jester" and "neutral"
+	Role string `json:"Role,omitempty"`
+	Team Team   `json:"Team,omitempty"`
+	// IsWinner is only sent by modded captures that decide the winners themselves, like a neutral's solo win
+	IsWinner *bool `json:"IsWinner,omitempty"`
+}
+
+type Team string
+
+const (
+	CrewmateTeam Team = "crewmate"
+	ImpostorTeam Team = "impostor"
+	// NeutralTeam players each play for themselves
+	NeutralTeam Team = "neutral"
+)
+
+func (t Team) normalize() Team {
+	return Team(strings.ToLower(strings.TrimSpace(string(t))))
 }
 
 type GameRole int16
@@ -34,4 +58,97 @@ type GameRole int16
 const (
 	CrewmateRole GameRole = iota
 	ImposterRole
+	NeutralRole
+	// HiderRole and SeekerRole are the crewmates and the imposter of a Hide and Seek game. They're kept apart from the
+	// normal roles so those rounds don't count towards the crewmate and imposter stats
+	HiderRole
+	SeekerRole
 )
+
+// InMode gives the role as it's recorded for a game of the given mode
+func (role GameRole) InMode(mode GameMode) GameRole {
+	if !mode.IsHideAndSeek() {
+		return role
+	}
+	switch role {
+	case CrewmateRole:
+		return HiderRole
+	case ImposterRole:
+		return SeekerRole
+	default:
+		return role
+	}
+}
+
+// GetTeam falls back to IsImpostor for captures that don't send a team
+func (p PlayerInfo) GetTeam() Team {
+	switch team := p.Team.normalize(); team {
+	case CrewmateTeam, ImpostorTeam, NeutralTeam:
+		return team
+	}
+	if p.IsImpostor {
+		return ImpostorTeam
+	}
+	return CrewmateTeam
+}
+
+func (p PlayerInfo) GetRole() GameRole {
+	switch p.GetTeam() {
+	case ImpostorTeam:
+		return ImposterRole
+	case NeutralTeam:
+		return NeutralRole
+	default:
+		return CrewmateRole
+	}
+}
+
+func (r Gameover) ImpostorsWon() bool {
+	return r.GameOverReason == ImpostorByKill ||
+		r.GameOverReason == ImpostorByVote ||
+		r.GameOverReason == ImpostorBySabotage ||
+		r.GameOverReason == ImpostorDisconnect
+}
+
+// IsWinner decides whether the player won, from the most specific information the capture sent:
+// per-player winners, then the winning team, then the vanilla GameOverReason
+func (r Gameover) IsWinner(p PlayerInfo) bool {
+	for _, v := range r.PlayerInfos {
+		if v.IsWinner != nil {
+			// once the capture names any winners, everyone it didn't name lost
+			return p.IsWinner != nil && *p.IsWinner
+		}
+	}
+	if winning := r.WinningTeam.normalize(); winning != "" {
+		return p.GetTeam() == winning
+	}
+	switch p.GetTeam() {
+	case ImpostorTeam:
+		return r.ImpostorsWon()
+	case CrewmateTeam:
+		return !r.ImpostorsWon()
+	default:
+		// neutrals only ever win when the capture says so
+		return false
+	}
+}
+
+// NeutralWon covers every player the capture reported on, whether they're linked or not
+func (r Gameover) NeutralWon() bool {
+	for _, v := range r.PlayerInfos {
+		if v.GetRole() == NeutralRole && r.IsWinner(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetPlayerInfo matches names case-insensitively, like the stats always have
+func (r Gameover) GetPlayerInfo(name string) (PlayerInfo, bool) {
+	for _, v := range r.PlayerInfos {
+		if strings.EqualFold(v.Name, name) {
+			return v, true
+		}
+	}
+	return PlayerInfo{Name: name}, false
+}
@@ -1,23 +1,97 @@
 package game
 
+import "strings"
+
+// VoiceRules are keyed by phase, then by who the rule is for; see VoiceRuleKey
 type VoiceRules struct {
 	MuteRules map[PhaseNameString]map[string]bool
 	DeafRules map[PhaseNameString]map[string]bool
 }
 
-func (rules *VoiceRules) GetVoiceState(isAlive, isTracked bool, phase Phase) (bool, bool) {
-	if !isTracked {
-		return false, false
-	}
+// VoiceCategory is which group of players a voice rule is for, on top of whether they're alive
+type VoiceCategory string
+
+const (
+	// PlayerCategory is every linked player; their rules are the plain "alive" and "dead" ones
+	PlayerCategory VoiceCategory = "player"
+	// SpectatorCategory is everyone in the channel who isn't linked. Spectators are never alive, so there's just the one rule
+	SpectatorCategory VoiceCategory = "spectator"
+	// ImpostorCategory and CrewmateCategory only apply once the game is over and the roles have been revealed,
+	// and only where they're set; the player rules apply otherwise
+	ImpostorCategory VoiceCategory = "impostor"
+	CrewmateCategory VoiceCategory = "crewmate"
+)
+
+// VoiceRuleKeys are all the keys a phase's rules can have
+var VoiceRuleKeys = []string{
+	"alive",
+	"dead",
+	VoiceRuleKey(SpectatorCategory, false),
+	VoiceRuleKey(ImpostorCategory, true),
+	VoiceRuleKey(ImpostorCategory, false),
+	VoiceRuleKey(CrewmateCategory, true),
+	VoiceRuleKey(CrewmateCategory, false),
+}
+
+// VoiceRuleKey is e.g. "alive" for living players, "spectator" for spectators, and "impostor-dead" for dead impostors
+func VoiceRuleKey(category VoiceCategory, isAlive bool) string {
 	aliveStr := "dead"
 	if isAlive {
 		aliveStr = "alive"
 	}
-	phaseStr := PhaseNames[phase]
+	switch category {
+	case PlayerCategory, "":
+		return aliveStr
+	case SpectatorCategory:
+		return string(SpectatorCategory)
+	}
+	return string(category) + "-" + aliveStr
+}
 
-	return rules.MuteRules[phaseStr][aliveStr], rules.DeafRules[phaseStr][aliveStr]
+func IsVoiceRuleKey(key string) bool {
+	for _, v := range VoiceRuleKeys {
+		if v == key {
+			return true
+		}
+	}
+	return false
+}
+
+// fallbackKey is the rule that applies for key when it isn't set
+func fallbackKey(key string) string {
+	switch key {
+	case "alive", "dead":
+		return key
+	case string(SpectatorCategory):
+		// spectators used to be treated like the dead
+		return "dead"
+	}
+	if strings.HasSuffix(key, "-alive") {
+		return "alive"
+	}
+	return "dead"
 }
 
+func (rules *VoiceRules) GetRule(isMute bool, phase Phase, key string) bool {
+	phaseRules := rules.DeafRules[PhaseNames[phase]]
+	if isMute {
+		phaseRules = rules.MuteRules[PhaseNames[phase]]
+	}
+	if v, ok := phaseRules[key]; ok {
+		return v
+	}
+	return phaseRules[fallbackKey(key)]
+}
+
+func (rules *VoiceRules) GetVoiceState(category VoiceCategory, isAlive, isTracked bool, phase Phase) (bool, bool) {
+	if !isTracked {
+		return false, false
+	}
+	key := VoiceRuleKey(category, isAlive)
+	return rules.GetRule(true, phase, key), rules.GetRule(false, phase, key)
+}
+
+// MakeMuteAndDeafenRules has no spectator rules, so spectators follow the dead players' until theirs are set
 func MakeMuteAndDeafenRules() VoiceRules {
 	rules := VoiceRules{
 		MuteRules: map[PhaseNameString]map[string]bool{
@@ -51,3 +125,39 @@ func MakeMuteAndDeafenRules() VoiceRules {
 	}
 	return rules
 }
+
+// MakeHideAndSeekRules are used instead of the normal rules during Hide and Seek, which has no meetings. The hiders
+// can talk all round, but anyone who's been caught is muted so they can't give away the seeker
+func MakeHideAndSeekRules() VoiceRules {
+	rules := VoiceRules{
+		MuteRules: map[PhaseNameString]map[string]bool{
+			PhaseNames[LOBBY]: {
+				"alive": false,
+				"dead":  false,
+			},
+			PhaseNames[TASKS]: {
+				"alive": false,
+				"dead":  true,
+			},
+			PhaseNames[DISCUSS]: {
+				"alive": false,
+				"dead":  true,
+			},
+		},
+		DeafRules: map[PhaseNameString]map[string]bool{
+			PhaseNames[LOBBY]: {
+				"alive": false,
+				"dead":  false,
+			},
+			PhaseNames[TASKS]: {
+				"alive": false,
+				"dead":  false,
+			},
+			PhaseNames[DISCUSS]: {
+				"alive": false,
+				"dead":  false,
+			},
+		},
+	}
+	return rules
+}
@@ -12,6 +12,39 @@ func ActiveGamesForGuild(guildID string) string {
 	return "automuteus:discord:" + guildID + ":games:set"
 }
 
+func JobStream(connCode string) string {
+	return JobNamespace + connCode + ":stream"
+}
+
+func JobSequence(connCode string) string {
+	return JobNamespace + connCode + ":seq"
+}
+
+func CaptureHeartbeat(connCode string) string {
+	return "automuteus:capture:heartbeat:" + connCode
+}
+
+func CaptureClients(connCode string) string {
+	return "automuteus:capture:clients:" + connCode
+}
+
+func CaptureLeader(connCode string) string {
+	return "automuteus:capture:leader:" + connCode
+}
+
+func CaptureResend(connCode string) string {
+	return "automuteus:capture:resend:" + connCode
+}
+
+// CaptureState holds the latest lobby and player events the capture server passed on for the code
+func CaptureState(connCode string) string {
+	return "automuteus:capture:state:" + connCode
+}
+
+func CaptureRecording(connCode string) string {
+	return "automuteus:capture:recording:" + connCode
+}
+
 func TextChannelPtr(guildID, channelID string) string {
 	return "automuteus:discord:" + guildID + ":pointer:text:" + channelID
 }
@@ -28,6 +61,21 @@ func ConnectCodeData(guildID, connCode string) string {
 	return "automuteus:discord:" + guildID + ":" + connCode
 }
 
+// GameLog is the list of everything applied to a game, for rebuilding its state if ConnectCodeData is lost
+func GameLog(guildID, connCode string) string {
+	return ConnectCodeData(guildID, connCode) + ":log"
+}
+
+// MutedUsers is the users the bot has muted or deafened in the guild, and the connect code of the game they were muted in
+func MutedUsers(guildID string) string {
+	return "automuteus:discord:" + guildID + ":muted"
+}
+
+// SyncedNicknames is the nicknames the bot has given out in the guild, with the ones to give back, by user ID
+func SyncedNicknames(guildID string) string {
+	return "automuteus:discord:" + guildID + ":nicknames"
+}
+
 func GuildCacheHash(guildID string) string {
 	return "automuteus:discord:" + guildID + ":cache"
 }
@@ -44,6 +92,14 @@ func RequestsByType(typeStr string) string {
 	return "automuteus:requests:type:" + typeStr
 }
 
+func CaptureEventsByType(typeStr string) string {
+	return "automuteus:capture:metrics:type:" + typeStr
+}
+
+func VoiceDriftByType(typeStr string) string {
+	return "automuteus:voice:drift:type:" + typeStr
+}
+
 func CompleteTask(taskID string) string {
 	return "automuteus:tasks:complete:ack:" + taskID
 }
@@ -52,6 +108,10 @@ func TasksList(connectCode string) string {
 	return "automuteus:tasks:list:" + connectCode
 }
 
+func CaptureClientTasks(connectCode, clientID string) string {
+	return TasksList(connectCode) + ":" + clientID
+}
+
 func BotTokenIdentifyLock(token string) string {
 	return "automuteus:token:lock" + token
 }
@@ -0,0 +1,156 @@
+package settings
+
+import (
+	"encoding/json"
+
+	"github.com/automuteus/automuteus/v8/pkg/game"
+)
+
+// the settings a voice channel can have of its own, named as they are in /settings
+const (
+	LanguageOverride            = "language"
+	DelaysOverride              = "delays"
+	VoiceRulesOverride          = "voice-rules"
+	MatchSummaryChannelOverride = "match-summary-channel"
+	MuteSpectatorsOverride      = "mute-spectators"
+)
+
+var OverridableSettings = []string{
+	LanguageOverride,
+	DelaysOverride,
+	VoiceRulesOverride,
+	MatchSummaryChannelOverride,
+	MuteSpectatorsOverride,
+}
+
+// ChannelOverrides are layered on top of the guild's settings for games in the voice channel; a nil field is the
+// guild's setting
+type ChannelOverrides struct {
+	Language              *string          `json:"language,omitempty"`
+	Delays                *game.GameDelays `json:"delays,omitempty"`
+	VoiceRules            *game.VoiceRules `json:"voiceRules,omitempty"`
+	MatchSummaryChannelID *string          `json:"matchSummaryChannelID,omitempty"`
+	MuteSpectator         *bool            `json:"muteSpectator,omitempty"`
+}
+
+// Names lists the settings that are overridden, in the order of OverridableSettings
+func (co ChannelOverrides) Names() []string {
+	var names []string
+	if co.Language != nil {
+		names = append(names, LanguageOverride)
+	}
+	if co.Delays != nil {
+		names = append(names, DelaysOverride)
+	}
+	if co.VoiceRules != nil {
+		names = append(names, VoiceRulesOverride)
+	}
+	if co.MatchSummaryChannelID != nil {
+		names = append(names, MatchSummaryChannelOverride)
+	}
+	if co.MuteSpectator != nil {
+		names = append(names, MuteSpectatorsOverride)
+	}
+	return names
+}
+
+// copyChannelOverrides gives the overrides their own maps, so changing them doesn't change whatever they were copied
+// from
+func copyChannelOverrides(co ChannelOverrides) ChannelOverrides {
+	var c ChannelOverrides
+	jBytes, err := json.Marshal(co)
+	if err == nil {
+		err = json.Unmarshal(jBytes, &c)
+	}
+	if err != nil {
+		return co
+	}
+	return c
+}
+
+func (gs *GuildSettings) GetChannelOverrides(channelID string) ChannelOverrides {
+	return copyChannelOverrides(gs.ChannelOverrides[channelID])
+}
+
+// SetChannelOverride takes the setting's value from scoped, the settings as they are for the channel after the
+// change. It returns false for a setting that can't be overridden
+func (gs *GuildSettings) SetChannelOverride(channelID, name string, scoped *GuildSettings) bool {
+	co := gs.ChannelOverrides[channelID]
+	switch name {
+	case LanguageOverride:
+		language := scoped.GetLanguage()
+		co.Language = &language
+	case DelaysOverride:
+		delays := scoped.Delays
+		co.Delays = &delays
+	case VoiceRulesOverride:
+		rules := *scoped.getVoiceRules(game.NormalMode)
+		co.VoiceRules = &rules
+	case MatchSummaryChannelOverride:
+		channel := scoped.GetMatchSummaryChannelID()
+		co.MatchSummaryChannelID = &channel
+	case MuteSpectatorsOverride:
+		mute := scoped.GetMuteSpectator()
+		co.MuteSpectator = &mute
+	default:
+		return false
+	}
+	if gs.ChannelOverrides == nil {
+		gs.ChannelOverrides = make(map[string]ChannelOverrides)
+	}
+	gs.ChannelOverrides[channelID] = copyChannelOverrides(co)
+	return true
+}
+
+// ClearChannelOverrides returns false if the channel had none
+func (gs *GuildSettings) ClearChannelOverrides(channelID string) bool {
+	if _, ok := gs.ChannelOverrides[channelID]; !ok {
+		return false
+	}
+	delete(gs.ChannelOverrides, channelID)
+	return true
+}
+
+// ApplyChannelOverrides replaces the guild's settings with the channel's own. Like ApplyPreset, it's only meant for
+// the settings a game runs with; the result shouldn't be saved
+func (gs *GuildSettings) ApplyChannelOverrides(channelID string) {
+	if channelID == "" {
+		return
+	}
+	co, ok := gs.ChannelOverrides[channelID]
+	if !ok {
+		return
+	}
+	co = copyChannelOverrides(co)
+	if co.Language != nil {
+		gs.Language = *co.Language
+	}
+	if co.Delays != nil {
+		gs.Delays = *co.Delays
+	}
+	if co.VoiceRules != nil {
+		gs.VoiceRules = *co.VoiceRules
+	}
+	if co.MatchSummaryChannelID != nil {
+		gs.MatchSummaryChannelID = *co.MatchSummaryChannelID
+	}
+	if co.MuteSpectator != nil {
+		gs.MuteSpectator = *co.MuteSpectator
+	}
+}
+
+// ScopedTo is a copy of the settings as they are for games in the voice channel
+func (gs *GuildSettings) ScopedTo(channelID string) (*GuildSettings, error) {
+	// round-trip through JSON rather than copying the struct, which would copy the lock
+	jBytes, err := json.Marshal(gs)
+	if err != nil {
+		return nil, err
+	}
+	scoped := &GuildSettings{}
+	err = json.Unmarshal(jBytes, scoped)
+	if err != nil {
+		return nil, err
+	}
+	scoped.ApplyChannelOverrides(channelID)
+	return scoped, nil
+}
@@ -1,20 +1,48 @@
 package settings
 
 import (
+	"encoding/json"
 	"github.com/automuteus/automuteus/v8/pkg/game"
 	"github.com/automuteus/automuteus/v8/pkg/locale"
+	"github.com/automuteus/automuteus/v8/pkg/webhook"
 	"github.com/bwmarrin/discordgo"
 	"sync"
 )
 
+// the in-game states that can be given a Discord role
+const (
+	AliveGameRole     = "alive"
+	DeadGameRole      = "dead"
+	SpectatorGameRole = "spectator"
+)
+
+// what's done about an unlinked member joining the game's voice channel mid-round; see GetLateJoinerPolicy
+const (
+	LateJoinerIgnore   = "ignore"
+	LateJoinerSpectate = "spectate"
+	LateJoinerWait     = "wait"
+	LateJoinerPing     = "ping"
+)
+
+// what's done about a linked player leaving the game's voice channel mid-round; see GetLeaverPolicy
+const (
+	LeaverIgnore = "ignore"
+	LeaverUnlink = "unlink"
+	LeaverPing   = "ping"
+)
+
 const DefaultLeaderboardSize = 3
 const DefaultLeaderboardMin = 3
 
+// DefaultCaptureGraceSeconds is how long a capture can go silent mid-game before everyone is unmuted
+const DefaultCaptureGraceSeconds = 60
+
 type GuildSettings struct {
 	AdminUserIDs             []string        `json:"adminIDs"`
 	PermissionRoleIDs        []string        `json:"permissionRoleIDs"`
 	Language                 string          `json:"language"`
 	VoiceRules               game.VoiceRules `json:"voiceRules"`
+	HideAndSeekVoiceRules    game.VoiceRules `json:"hideAndSeekVoiceRules"`
 	MapVersion               string          `json:"mapVersion"`
 	Delays                   game.GameDelays `json:"delays"`
 	DeleteGameSummaryMinutes int             `json:"deleteGameSummary"`
@@ -27,6 +55,31 @@ type GuildSettings struct {
 	LeaderboardMin           int    `json:"leaderboardMin"`
 	MuteSpectator            bool   `json:"muteSpectator"`
 	DisplayRoomCode          string `json:"displayRoomCode"`
+	CaptureGraceSeconds      int    `json:"captureGraceSeconds"`
+	// GhostChannelID is where dead players are moved during tasks; they're muted as usual if it's empty
+	GhostChannelID string `json:"ghostChannelID,omitempty"`
+	// the Discord roles given to players for their state in the game; see GetGameRoleID
+	AliveRoleID     string `json:"aliveRoleID,omitempty"`
+	DeadRoleID      string `json:"deadRoleID,omitempty"`
+	SpectatorRoleID string `json:"spectatorRoleID,omitempty"`
+	// SyncNicknames renames linked players after their in-game name and color while a game is running
+	SyncNicknames bool `json:"syncNicknames,omitempty"`
+	// Presets are the guild's own presets, by name; see GetPreset
+	Presets map[string]Preset `json:"presets,omitempty"`
+	// ExemptUserIDs and ExemptRoleIDs are never muted or deafened by the bot, whatever the voice rules say
+	ExemptUserIDs []string `json:"exemptUserIDs,omitempty"`
+	ExemptRoleIDs []string `json:"exemptRoleIDs,omitempty"`
+	// ConflictLogChannelID is told whenever someone else server mutes or deafens a player mid-game
+	ConflictLogChannelID string `json:"conflictLogChannelID,omitempty"`
+	// LateJoinerPolicy and LeaverPolicy are what's done about people coming and going mid-round, and WaitingChannelID
+	// where late joiners are moved for LateJoinerWait
+	LateJoinerPolicy string `json:"lateJoinerPolicy,omitempty"`
+	WaitingChannelID string `json:"waitingChannelID,omitempty"`
+	LeaverPolicy     string `json:"leaverPolicy,omitempty"`
+	// ChannelOverrides are the voice channels' own settings, by channel ID; see ApplyChannelOverrides
+	ChannelOverrides map[string]ChannelOverrides `json:"channelOverrides,omitempty"`
+
+	Webhooks []webhook.Subscription `json:"webhooks,omitempty"`
 }
 
 func MakeGuildSettings() *GuildSettings {
@@ -36,6 +89,7 @@ func MakeGuildSettings() *GuildSettings {
 		PermissionRoleIDs:        []string{},
 		Delays:                   game.MakeDefaultDelays(),
 		VoiceRules:               game.MakeMuteAndDeafenRules(),
+		HideAndSeekVoiceRules:    game.MakeHideAndSeekRules(),
 		UnmuteDeadDuringTasks:    false,
 		DeleteGameSummaryMinutes: 0, //-1 for never delete the match summary
 		AutoRefresh:              false,
@@ -46,6 +100,7 @@ func MakeGuildSettings() *GuildSettings {
 		LeaderboardMin:           DefaultLeaderboardMin,
 		MuteSpectator:            false,
 		DisplayRoomCode:          "always",
+		CaptureGraceSeconds:      DefaultCaptureGraceSeconds, //-1 to never unmute on a lost capture
 		lock:                     sync.RWMutex{},
 	}
 }
@@ -95,6 +150,60 @@ func (gs *GuildSettings) SetPermissionRoleIDs(ids []string) {
 	gs.PermissionRoleIDs = ids
 }
 
+func (gs *GuildSettings) GetExemptUserIDs() []string {
+	return gs.ExemptUserIDs
+}
+
+func (gs *GuildSettings) GetExemptRoleIDs() []string {
+	return gs.ExemptRoleIDs
+}
+
+// toggleID removes the ID if it's there, or adds it if it isn't; it returns whether the ID is there now
+func toggleID(ids []string, id string) ([]string, bool) {
+	for i, v := range ids {
+		if v == id {
+			return append(ids[:i], ids[i+1:]...), false
+		}
+	}
+	return append(ids, id), true
+}
+
+// ToggleExemptUser returns whether the user is exempt now
+func (gs *GuildSettings) ToggleExemptUser(userID string) bool {
+	var exempt bool
+	gs.ExemptUserIDs, exempt = toggleID(gs.ExemptUserIDs, userID)
+	return exempt
+}
+
+// ToggleExemptRole returns whether the role is exempt now
+func (gs *GuildSettings) ToggleExemptRole(roleID string) bool {
+	var exempt bool
+	gs.ExemptRoleIDs, exempt = toggleID(gs.ExemptRoleIDs, roleID)
+	return exempt
+}
+
+func (gs *GuildSettings) ClearExemptions() {
+	gs.ExemptUserIDs = nil
+	gs.ExemptRoleIDs = nil
+}
+
+// IsExempt is whether a member with the roles is never to be muted or deafened
+func (gs *GuildSettings) IsExempt(userID string, roleIDs []string) bool {
+	for _, v := range gs.ExemptUserIDs {
+		if v == userID {
+			return true
+		}
+	}
+	for _, role := range roleIDs {
+		for _, v := range gs.ExemptRoleIDs {
+			if v == role {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 func (gs *GuildSettings) GetUnmuteDeadDuringTasks() bool {
 	return gs.UnmuteDeadDuringTasks
 }
@@ -115,6 +224,89 @@ func (gs *GuildSettings) GetMatchSummaryChannelID() string {
 	return gs.MatchSummaryChannelID
 }
 
+func (gs *GuildSettings) SetGhostChannelID(id string) {
+	gs.GhostChannelID = id
+}
+
+func (gs *GuildSettings) GetGhostChannelID() string {
+	return gs.GhostChannelID
+}
+
+func (gs *GuildSettings) SetConflictLogChannelID(id string) {
+	gs.ConflictLogChannelID = id
+}
+
+func (gs *GuildSettings) GetConflictLogChannelID() string {
+	return gs.ConflictLogChannelID
+}
+
+func (gs *GuildSettings) GetLateJoinerPolicy() string {
+	if gs.LateJoinerPolicy == "" {
+		return LateJoinerIgnore
+	}
+	return gs.LateJoinerPolicy
+}
+
+func (gs *GuildSettings) SetLateJoinerPolicy(policy string) {
+	gs.LateJoinerPolicy = policy
+}
+
+func (gs *GuildSettings) GetWaitingChannelID() string {
+	return gs.WaitingChannelID
+}
+
+func (gs *GuildSettings) SetWaitingChannelID(id string) {
+	gs.WaitingChannelID = id
+}
+
+func (gs *GuildSettings) GetLeaverPolicy() string {
+	if gs.LeaverPolicy == "" {
+		return LeaverIgnore
+	}
+	return gs.LeaverPolicy
+}
+
+func (gs *GuildSettings) SetLeaverPolicy(policy string) {
+	gs.LeaverPolicy = policy
+}
+
+func (gs *GuildSettings) GetSyncNicknames() bool {
+	return gs.SyncNicknames
+}
+
+func (gs *GuildSettings) SetSyncNicknames(sync bool) {
+	gs.SyncNicknames = sync
+}
+
+// GetGameRoleID returns the role for the state, or "" if the guild doesn't give one
+func (gs *GuildSettings) GetGameRoleID(state string) string {
+	switch state {
+	case AliveGameRole:
+		return gs.AliveRoleID
+	case DeadGameRole:
+		return gs.DeadRoleID
+	case SpectatorGameRole:
+		return gs.SpectatorRoleID
+	default:
+		return ""
+	}
+}
+
+// SetGameRoleID returns false for a state that can't have a role
+func (gs *GuildSettings) SetGameRoleID(state, roleID string) bool {
+	switch state {
+	case AliveGameRole:
+		gs.AliveRoleID = roleID
+	case DeadGameRole:
+		gs.DeadRoleID = roleID
+	case SpectatorGameRole:
+		gs.SpectatorRoleID = roleID
+	default:
+		return false
+	}
+	return true
+}
+
 func (gs *GuildSettings) GetAutoRefresh() bool {
 	return gs.AutoRefresh
 }
@@ -193,23 +385,42 @@ func (gs *GuildSettings) SetDelay(oldPhase, newPhase game.Phase, v int) {
 	gs.Delays.Delays[oldPhase.ToString()][newPhase.ToString()] = v
 }
 
-func (gs *GuildSettings) GetVoiceRule(isMute bool, phase game.Phase, alive string) bool {
-	if isMute {
-		return gs.VoiceRules.MuteRules[phase.ToString()][alive]
+// hasHideAndSeekRules is false for settings saved before Hide and Seek was supported
+func (gs *GuildSettings) hasHideAndSeekRules() bool {
+	return gs.HideAndSeekVoiceRules.MuteRules != nil && gs.HideAndSeekVoiceRules.DeafRules != nil
+}
+
+// getVoiceRules returns the rules for the game mode being played
+func (gs *GuildSettings) getVoiceRules(mode game.GameMode) *game.VoiceRules {
+	if !mode.IsHideAndSeek() {
+		return &gs.VoiceRules
+	}
+	if !gs.hasHideAndSeekRules() {
+		rules := game.MakeHideAndSeekRules()
+		return &rules
 	}
-	return gs.VoiceRules.DeafRules[phase.ToString()][alive]
+	return &gs.HideAndSeekVoiceRules
+}
+
+// GetVoiceRule takes a key from game.VoiceRuleKeys
+func (gs *GuildSettings) GetVoiceRule(mode game.GameMode, isMute bool, phase game.Phase, key string) bool {
+	return gs.getVoiceRules(mode).GetRule(isMute, phase, key)
 }
 
-func (gs *GuildSettings) SetVoiceRule(isMute bool, phase game.Phase, alive string, val bool) {
+func (gs *GuildSettings) SetVoiceRule(mode game.GameMode, isMute bool, phase game.Phase, key string, val bool) {
+	if mode.IsHideAndSeek() && !gs.hasHideAndSeekRules() {
+		gs.HideAndSeekVoiceRules = game.MakeHideAndSeekRules()
+	}
+	rules := gs.getVoiceRules(mode)
 	if isMute {
-		gs.VoiceRules.MuteRules[phase.ToString()][alive] = val
+		rules.MuteRules[phase.ToString()][key] = val
 	} else {
-		gs.VoiceRules.DeafRules[phase.ToString()][alive] = val
+		rules.DeafRules[phase.ToString()][key] = val
 	}
 }
 
-func (gs *GuildSettings) GetVoiceState(alive bool, tracked bool, phase game.Phase) (bool, bool) {
-	return gs.VoiceRules.GetVoiceState(alive, tracked, phase)
+func (gs *GuildSettings) GetVoiceState(mode game.GameMode, category game.VoiceCategory, alive bool, tracked bool, phase game.Phase) (bool, bool) {
+	return gs.getVoiceRules(mode).GetVoiceState(category, alive, tracked, phase)
 }
 
 func (gs *GuildSettings) GetDisplayRoomCode() string {
@@ -222,3 +433,61 @@ func (gs *GuildSettings) GetDisplayRoomCode() string {
 func (gs *GuildSettings) SetDisplayRoomCode(r string) {
 	gs.DisplayRoomCode = r
 }
+
+// GetCaptureGraceSeconds returns -1 when the capture watchdog is disabled
+func (gs *GuildSettings) GetCaptureGraceSeconds() int {
+	if gs.CaptureGraceSeconds == 0 {
+		return DefaultCaptureGraceSeconds
+	}
+	return gs.CaptureGraceSeconds
+}
+
+func (gs *GuildSettings) SetCaptureGraceSeconds(v int) {
+	gs.CaptureGraceSeconds = v
+}
+
+func (gs *GuildSettings) GetWebhooks() []webhook.Subscription {
+	return gs.Webhooks
+}
+
+// AddWebhook returns false if the guild already has as many webhooks as it's allowed
+func (gs *GuildSettings) AddWebhook(sub webhook.Subscription) bool {
+	if len(gs.Webhooks) >= webhook.MaxSubscriptions {
+		return false
+	}
+	gs.Webhooks = append(gs.Webhooks, sub)
+	return true
+}
+
+// RemoveWebhook returns false if there was no webhook with that ID
+func (gs *GuildSettings) RemoveWebhook(id string) bool {
+	for i, v := range gs.Webhooks {
+		if v.ID == id {
+			gs.Webhooks = append(gs.Webhooks[:i], gs.Webhooks[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (gs *GuildSettings) ClearWebhooks() {
+	gs.Webhooks = nil
+}
+
+// WithoutSecrets is a copy of the settings that's safe to show in a channel
+func (gs *GuildSettings) WithoutSecrets() (*GuildSettings, error) {
+	// round-trip through JSON rather than copying the struct, which would copy the lock
+	jBytes, err := json.Marshal(gs)
+	if err != nil {
+		return nil, err
+	}
+	redacted := &GuildSettings{}
+	err = json.Unmarshal(jBytes, redacted)
+	if err != nil {
+		return nil, err
+	}
+	for i := range redacted.Webhooks {
+		redacted.Webhooks[i].Secret = ""
+	}
+	return redacted, nil
+}
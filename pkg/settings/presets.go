@@ -0,0 +1,141 @@
+package settings
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+
+	"github.com/automuteus/automuteus/v8/pkg/game"
+)
+
+const (
+	ClassicPreset    = "classic"
+	DeafenOnlyPreset = "deafen-only"
+	GhostTalkPreset  = "ghost-talk"
+
+	MaxPresets = 10
+)
+
+var presetNameRegex = regexp.MustCompile(`^[a-z0-9-]{1,32}$`)
+
+// Preset bundles the settings that decide who's muted when, so a game can be started with a different set of them
+// than the guild's defaults
+type Preset struct {
+	VoiceRules            game.VoiceRules `json:"voiceRules"`
+	Delays                game.GameDelays `json:"delays"`
+	UnmuteDeadDuringTasks bool            `json:"unmuteDeadDuringTasks"`
+	MuteSpectator         bool            `json:"muteSpectator"`
+}
+
+// BuiltinPresets can't be overwritten or deleted
+var BuiltinPresets = map[string]func() Preset{
+	// what a new guild starts with
+	ClassicPreset: func() Preset {
+		return Preset{
+			VoiceRules: game.MakeMuteAndDeafenRules(),
+			Delays:     game.MakeDefaultDelays(),
+		}
+	},
+	// a deafened player can't talk either, so anyone who'd be muted is deafened instead
+	DeafenOnlyPreset: func() Preset {
+		rules := game.MakeMuteAndDeafenRules()
+		for phase, v := range rules.MuteRules {
+			for key, mute := range v {
+				rules.DeafRules[phase][key] = rules.DeafRules[phase][key] || mute
+				v[key] = false
+			}
+		}
+		return Preset{
+			VoiceRules: rules,
+			Delays:     game.MakeDefaultDelays(),
+		}
+	},
+	// the dead and the spectators talk amongst themselves from the moment someone dies
+	GhostTalkPreset: func() Preset {
+		return Preset{
+			VoiceRules:            game.MakeMuteAndDeafenRules(),
+			Delays:                game.MakeDefaultDelays(),
+			UnmuteDeadDuringTasks: true,
+			MuteSpectator:         true,
+		}
+	},
+}
+
+func IsValidPresetName(name string) bool {
+	return presetNameRegex.MatchString(name)
+}
+
+// copyPreset gives a preset its own maps, so changing it doesn't change whatever it was copied from
+func copyPreset(p Preset) Preset {
+	var c Preset
+	jBytes, err := json.Marshal(p)
+	if err == nil {
+		err = json.Unmarshal(jBytes, &c)
+	}
+	if err != nil {
+		return p
+	}
+	return c
+}
+
+// GetPreset returns a copy of the built-in or saved preset
+func (gs *GuildSettings) GetPreset(name string) (Preset, bool) {
+	if makePreset, ok := BuiltinPresets[name]; ok {
+		return makePreset(), true
+	}
+	p, ok := gs.Presets[name]
+	if !ok {
+		return Preset{}, false
+	}
+	return copyPreset(p), true
+}
+
+// GetPresetNames lists the built-in presets, then the guild's own
+func (gs *GuildSettings) GetPresetNames() []string {
+	names := []string{ClassicPreset, DeafenOnlyPreset, GhostTalkPreset}
+	var saved []string
+	for name := range gs.Presets {
+		saved = append(saved, name)
+	}
+	sort.Strings(saved)
+	return append(names, saved...)
+}
+
+// SavePreset saves the guild's current settings under the name. It returns false for a built-in name, or if the
+// guild already has MaxPresets of its own
+func (gs *GuildSettings) SavePreset(name string) bool {
+	if _, ok := BuiltinPresets[name]; ok {
+		return false
+	}
+	if _, ok := gs.Presets[name]; !ok && len(gs.Presets) >= MaxPresets {
+		return false
+	}
+	if gs.Presets == nil {
+		gs.Presets = make(map[string]Preset)
+	}
+	gs.Presets[name] = copyPreset(Preset{
+		VoiceRules:            *gs.getVoiceRules(game.NormalMode),
+		Delays:                gs.Delays,
+		UnmuteDeadDuringTasks: gs.UnmuteDeadDuringTasks,
+		MuteSpectator:         gs.MuteSpectator,
+	})
+	return true
+}
+
+func (gs *GuildSettings) DeletePreset(name string) bool {
+	if _, ok := gs.Presets[name]; !ok {
+		return false
+	}
+	delete(gs.Presets, name)
+	return true
+}
+
+// ApplyPreset replaces the guild's defaults with the preset's. It's only meant for the settings a game runs with;
+// the result shouldn't be saved
+func (gs *GuildSettings) ApplyPreset(p Preset) {
+	p = copyPreset(p)
+	gs.VoiceRules = p.VoiceRules
+	gs.Delays = p.Delays
+	gs.UnmuteDeadDuringTasks = p.UnmuteDeadDuringTasks
+	gs.MuteSpectator = p.MuteSpectator
+}
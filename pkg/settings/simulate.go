@@ -0,0 +1,90 @@
+package settings
+
+import "github.com/automuteus/automuteus/v8/pkg/game"
+
+// the players in a simulated match: one who survives it, one who's killed during tasks, one who's voted out, and
+// someone watching from the voice channel without playing
+const (
+	SimulatedAlive     = "alive"
+	SimulatedKilled    = "killed"
+	SimulatedExiled    = "exiled"
+	SimulatedSpectator = "spectator"
+)
+
+var SimulatedPlayers = []string{SimulatedAlive, SimulatedKilled, SimulatedExiled, SimulatedSpectator}
+
+// the steps of a simulated match
+const (
+	LobbyStep      = "lobby"
+	TasksStep      = "tasks"
+	KillStep       = "kill"
+	DiscussionStep = "discussion"
+	ExileStep      = "exile"
+	GameOverStep   = "game over"
+)
+
+type SimulatedVoiceState struct {
+	Mute bool `json:"mute"`
+	Deaf bool `json:"deaf"`
+}
+
+type SimulationStep struct {
+	Step  string               `json:"step"`
+	Phase game.PhaseNameString `json:"phase"`
+	// Delay is how many seconds after the step the voice states are applied
+	Delay int `json:"delay"`
+	// Changed is false for a step the bot doesn't change anyone's voice state for
+	Changed bool                           `json:"changed"`
+	States  map[string]SimulatedVoiceState `json:"states"`
+}
+
+// SimulateVoiceRules plays a match through the voice rules and delays, the way the bot would apply them:
+// lobby, tasks, a kill, discussion, an exile, tasks again, then game over
+func (gs *GuildSettings) SimulateVoiceRules() []SimulationStep {
+	alive := map[string]bool{
+		SimulatedAlive:  true,
+		SimulatedKilled: true,
+		SimulatedExiled: true,
+	}
+	states := make(map[string]SimulatedVoiceState)
+	var steps []SimulationStep
+
+	record := func(step string, phase game.Phase, delay int, changed bool) {
+		if changed {
+			for _, player := range SimulatedPlayers {
+				var mute, deaf bool
+				if player == SimulatedSpectator {
+					// spectators are only tracked when they're muted like the dead
+					mute, deaf = gs.GetVoiceState(game.NormalMode, game.SpectatorCategory, false, gs.GetMuteSpectator(), phase)
+				} else {
+					mute, deaf = gs.GetVoiceState(game.NormalMode, game.PlayerCategory, alive[player], true, phase)
+				}
+				states[player] = SimulatedVoiceState{Mute: mute, Deaf: deaf}
+			}
+		}
+		stepStates := make(map[string]SimulatedVoiceState, len(states))
+		for k, v := range states {
+			stepStates[k] = v
+		}
+		steps = append(steps, SimulationStep{
+			Step:    step,
+			Phase:   game.PhaseNames[phase],
+			Delay:   delay,
+			Changed: changed,
+			States:  stepStates,
+		})
+	}
+
+	record(LobbyStep, game.LOBBY, 0, true)
+	record(TasksStep, game.TASKS, gs.GetDelay(game.LOBBY, game.TASKS), true)
+	alive[SimulatedKilled] = false
+	// otherwise a kill isn't given away until the meeting
+	record(KillStep, game.TASKS, 0, gs.GetUnmuteDeadDuringTasks())
+	record(DiscussionStep, game.DISCUSS, gs.GetDelay(game.TASKS, game.DISCUSS), true)
+	alive[SimulatedExiled] = false
+	// the exiled player is handled along with everyone else once tasks start
+	record(ExileStep, game.DISCUSS, 0, false)
+	record(TasksStep, game.TASKS, gs.GetDelay(game.DISCUSS, game.TASKS), true)
+	record(GameOverStep, game.LOBBY, gs.GetDelay(game.TASKS, game.LOBBY), true)
+	return steps
+}
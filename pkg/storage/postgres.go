@@ -209,7 +209,8 @@ func (psqlInterface *PsqlInterface) GetGameEvents(matchID string) ([]*PostgresGa
 }
 
 func insertGame(conn PgxIface, game *PostgresGame) (uint64, error) {
-	t, err := conn.Query(context.Background(), "INSERT INTO games VALUES (DEFAULT, $1, $2, $3, $4, $5) RETURNING game_id;", game.GuildID, game.ConnectCode, game.StartTime, game.WinType, game.EndTime)
+	t, err := conn.Query(context.Background(), "INSERT INTO games (game_id, guild_id, connect_code, start_time, win_type, end_time, game_mode) "+
+		"VALUES (DEFAULT, $1, $2, $3, $4, $5, $6) RETURNING game_id;", game.GuildID, game.ConnectCode, game.StartTime, game.WinType, game.EndTime, game.GameMode)
 	if t != nil {
 		for t.Next() {
 			g := uint64(0)
@@ -227,13 +228,14 @@ func insertGame(conn PgxIface, game *PostgresGame) (uint64, error) {
 	return 0, err
 }
 
-func updateGame(conn PgxIface, gameID int64, winType int16, endTime int64) error {
-	_, err := conn.Exec(context.Background(), "UPDATE games SET (win_type, end_time) = ($1, $2) WHERE game_id = $3;", winType, endTime, gameID)
+func updateGame(conn PgxIface, gameID int64, winType int16, endTime int64, neutralWon bool) error {
+	_, err := conn.Exec(context.Background(), "UPDATE games SET (win_type, end_time, neutral_won) = ($1, $2, $3) WHERE game_id = $4;", winType, endTime, neutralWon, gameID)
 	return err
 }
 
 func insertPlayer(conn PgxIface, player *PostgresUserGame) error {
-	_, err := conn.Exec(context.Background(), "INSERT INTO users_games VALUES ($1, $2, $3, $4, $5, $6, $7);", player.UserID, player.GuildID, player.GameID, player.PlayerName, player.PlayerColor, player.PlayerRole, player.PlayerWon)
+	_, err := conn.Exec(context.Background(), "INSERT INTO users_games (user_id, guild_id, game_id, player_name, player_color, player_role, player_won, role_name) "+
+		"VALUES ($1, $2, $3, $4, $5, $6, $7, $8);", player.UserID, player.GuildID, player.GameID, player.PlayerName, player.PlayerColor, player.PlayerRole, player.PlayerWon, player.RoleName)
 	return err
 }
 
@@ -497,14 +499,14 @@ func (psqlInterface *PsqlInterface) AddEvent(event *PostgresGameEvent) error {
 }
 
 // make sure to call the relevant "ensure" methods before this one...
-func (psqlInterface *PsqlInterface) UpdateGameAndPlayers(gameID int64, winType int16, endTime int64, players []*PostgresUserGame) error {
+func (psqlInterface *PsqlInterface) UpdateGameAndPlayers(gameID int64, winType int16, endTime int64, neutralWon bool, players []*PostgresUserGame) error {
 	conn, err := psqlInterface.Pool.Acquire(context.Background())
 	if err != nil {
 		return err
 	}
 	defer conn.Release()
 
-	err = updateGame(conn.Conn(), gameID, winType, endTime)
+	err = updateGame(conn.Conn(), gameID, winType, endTime, neutralWon)
 	if err != nil {
 		return err
 	}
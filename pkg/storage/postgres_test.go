@@ -129,6 +129,57 @@ func TestInsertUser(t *testing.T) {
 	}
 }
 
+func TestInsertPlayer(t *testing.T) {
+	mock, err := pgxmock.NewConn()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectExec("^INSERT INTO users_games \\((.+), role_name\\) VALUES (.+)$").
+		WithArgs(UserIDInt, GuildIDInt, int64(1), "Red", int16(0), int16(2), true, "Jester").
+		WillReturnResult(pgconn.CommandTag{})
+
+	err = insertPlayer(mock, &PostgresUserGame{
+		UserID:      UserIDInt,
+		GuildID:     GuildIDInt,
+		GameID:      1,
+		PlayerName:  "Red",
+		PlayerColor: 0,
+		PlayerRole:  2,
+		PlayerWon:   true,
+		RoleName:    "Jester",
+	})
+	if err != nil {
+		t.Error(err)
+	}
+
+	// we make sure that all expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUpdateGame(t *testing.T) {
+	mock, err := pgxmock.NewConn()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	mock.ExpectExec("^UPDATE games SET \\(win_type, end_time, neutral_won\\) = (.+) WHERE game_id = (.+)$").
+		WithArgs(int16(0), int64(1000), true, int64(1)).
+		WillReturnResult(pgconn.CommandTag{})
+
+	err = updateGame(mock, 1, 0, 1000, true)
+	if err != nil {
+		t.Error(err)
+	}
+
+	// we make sure that all expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
 func TestGetUser(t *testing.T) {
 	mock, err := pgxmock.NewConn()
 	if err != nil {
@@ -239,14 +239,29 @@ func (psqlInterface *PsqlInterface) NumGamesPlayedOnGuild(guildID string) int64
 	return r
 }
 
+func (psqlInterface *PsqlInterface) NumGamesPlayedOnGuildInMode(guildID string, mode game.GameMode) int64 {
+	gid, _ := strconv.ParseInt(guildID, 10, 64)
+	var r int64
+	err := pgxscan.Get(context.Background(), psqlInterface.Pool, &r, "SELECT COUNT(*) FROM games WHERE guild_id=$1 AND end_time != -1 AND game_mode=$2;", gid, int16(mode))
+	if err != nil {
+		return -1
+	}
+	return r
+}
+
+// NumGamesWonAsRoleOnServer leaves out Hide and Seek games, which have their own roles
 func (psqlInterface *PsqlInterface) NumGamesWonAsRoleOnServer(guildID string, role game.GameRole) int64 {
 	gid, _ := strconv.ParseInt(guildID, 10, 64)
 	var r int64
 	var err error
-	if role == game.CrewmateRole {
-		err = pgxscan.Get(context.Background(), psqlInterface.Pool, &r, "SELECT COUNT(*) FROM games WHERE guild_id=$1 AND (win_type=0 OR win_type=1 OR win_type=6)", gid)
-	} else {
-		err = pgxscan.Get(context.Background(), psqlInterface.Pool, &r, "SELECT COUNT(*) FROM games WHERE guild_id=$1 AND (win_type=2 OR win_type=3 OR win_type=4 OR win_type=5)", gid)
+	switch role {
+	case game.CrewmateRole:
+		err = pgxscan.Get(context.Background(), psqlInterface.Pool, &r, "SELECT COUNT(*) FROM games WHERE guild_id=$1 AND game_mode<>$2 AND (win_type=0 OR win_type=1 OR win_type=6)", gid, int16(game.HideAndSeekMode))
+	case game.NeutralRole:
+		// neutral wins don't have a win_type of their own, and users_games only has the linked players
+		err = pgxscan.Get(context.Background(), psqlInterface.Pool, &r, "SELECT COUNT(*) FROM games WHERE guild_id=$1 AND game_mode<>$2 AND neutral_won=true", gid, int16(game.HideAndSeekMode))
+	default:
+		err = pgxscan.Get(context.Background(), psqlInterface.Pool, &r, "SELECT COUNT(*) FROM games WHERE guild_id=$1 AND game_mode<>$2 AND (win_type=2 OR win_type=3 OR win_type=4 OR win_type=5)", gid, int16(game.HideAndSeekMode))
 	}
 	if err != nil {
 		log.Println(err)
@@ -390,6 +405,23 @@ func (psqlInterface *PsqlInterface) NamesRankingForPlayerOnServer(userID, guildI
 	return r
 }
 
+type RoleCount struct {
+	RoleName string `db:"role_name"`
+	Count    int64  `db:"count"`
+	Wins     int64  `db:"wins"`
+}
+
+// RoleRankingForPlayerOnServer only covers games played with a modded role
+func (psqlInterface *PsqlInterface) RoleRankingForPlayerOnServer(userID, guildID string) []*RoleCount {
+	var r []*RoleCount
+	err := pgxscan.Select(context.Background(), psqlInterface.Pool, &r, "SELECT role_name,count(*),count(*) FILTER ( WHERE player_won = TRUE ) AS wins FROM users_games WHERE user_id=$1 AND guild_id=$2 AND role_name <> '' GROUP BY role_name ORDER BY count desc;", userID, guildID)
+
+	if err != nil {
+		log.Println(err)
+	}
+	return r
+}
+
 func (psqlInterface *PsqlInterface) TotalGamesRankingForServer(guildID uint64) []*Uint64ModeCount {
 	var r []*Uint64ModeCount
 	err := pgxscan.Select(context.Background(), psqlInterface.Pool, &r, "SELECT count(*),mode() within GROUP (ORDER BY user_id) AS mode FROM users_games WHERE guild_id=$1 GROUP BY user_id ORDER BY count desc;", guildID)
@@ -35,6 +35,9 @@ type PostgresGame struct {
 	StartTime   int32  `db:"start_time"`
 	WinType     int16  `db:"win_type"`
 	EndTime     int32  `db:"end_time"`
+	GameMode    int16  `db:"game_mode"`
+	// NeutralWon is set from every player in the game, not just the linked ones in users_games
+	NeutralWon bool `db:"neutral_won"`
 }
 
 func GamesToCSV(g []*PostgresGame) string {
@@ -64,6 +67,9 @@ func UsersToCSV(u []*PostgresUser) string {
 	return s.String()
 }
 
+// MaxRoleNameLength matches users_games.role_name
+const MaxRoleNameLength = 32
+
 type PostgresUserGame struct {
 	UserID      uint64 `db:"user_id"`
 	GuildID     uint64 `db:"guild_id"`
@@ -72,6 +78,8 @@ type PostgresUserGame struct {
 	PlayerColor int16  `db:"player_color"`
 	PlayerRole  int16  `db:"player_role"`
 	PlayerWon   bool   `db:"player_won"`
+	// RoleName is the modded role the player had, like "Jester"; empty for vanilla games
+	RoleName string `db:"role_name"`
 }
 
 func UsersGamesToCSV(ug []*PostgresUserGame) string {
@@ -3,9 +3,15 @@ package task
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
 	"github.com/automuteus/automuteus/v8/pkg/rediskey"
 	"github.com/go-redis/redis/v8"
-	"time"
 )
 
 type JobType int
@@ -18,56 +24,255 @@ const (
 	GameOverJob
 )
 
+// JobSchemaVersion is bumped whenever the Job envelope or one of its payloads changes shape.
+// Jobs without a version (0) predate the envelope, and carry their payload as a JSON string
+const JobSchemaVersion = 1
+
 type Job struct {
-	JobType JobType     `json:"type"`
-	Payload interface{} `json:"payload"`
+	Version int `json:"version,omitempty"`
+	// Seq orders the jobs for a connect code; jobs without one (0) are applied as they arrive
+	Seq int64 `json:"seq,omitempty"`
+	// CaptureTime is when the event was received from the capture, in unix milliseconds
+	CaptureTime int64           `json:"captureTime,omitempty"`
+	JobType     JobType         `json:"type"`
+	Payload     json.RawMessage `json:"payload"`
+
+	// ID is the stream entry ID; it's needed to ack the job once it has been applied
+	ID string `json:"-"`
 }
 
 const JobTTLSeconds = 3600
 
-func PushJob(ctx context.Context, redis *redis.Client, connCode string, jobType JobType, payload string) error {
+const (
+	// JobConsumerGroup is shared by all shards, so any of them can pick up the jobs for a game
+	JobConsumerGroup = "automuteus"
+	// JobStreamMaxLen bounds how many (acked or not) jobs are kept around per game
+	JobStreamMaxLen = 1000
+	// JobClaimIdle is how long a job can sit un-acked before another consumer is allowed to claim it
+	JobClaimIdle = time.Second * 30
+	// JobReadBlock is how long a single blocking read waits for new jobs
+	JobReadBlock = time.Second * 5
+)
+
+const jobField = "job"
+
+// consumerName identifies this process within the consumer group
+var consumerName = func() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "automuteus"
+	}
+	return fmt.Sprintf("%s-%d", host, os.Getpid())
+}()
+
+func PushJob(ctx context.Context, client *redis.Client, connCode string, jobType JobType, payload string) error {
+	seq, err := nextJobSeq(ctx, client, connCode)
+	if err != nil {
+		return err
+	}
 	job := Job{
-		JobType: jobType,
-		Payload: payload,
+		Version:     JobSchemaVersion,
+		Seq:         seq,
+		CaptureTime: time.Now().UnixMilli(),
+		JobType:     jobType,
+		Payload:     encodePayload(payload),
+	}
+	_, err = AddJob(ctx, client, connCode, job)
+	return err
+}
+
+// nextJobSeq hands out per-connect-code sequence numbers, starting at 1, so the subscriber can restore the order
+// the capture sent events in
+func nextJobSeq(ctx context.Context, client *redis.Client, connCode string) (int64, error) {
+	key := rediskey.JobSequence(connCode)
+	seq, err := client.Incr(ctx, key).Result()
+	if err != nil {
+		return 0, err
 	}
+	return seq, client.Expire(ctx, key, JobTTLSeconds*time.Second).Err()
+}
+
+// AddJob queues an already-built job as-is, such as one from a recording, and returns its stream entry ID
+func AddJob(ctx context.Context, client *redis.Client, connCode string, job Job) (string, error) {
 	jBytes, err := json.Marshal(job)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	count, err := redis.RPush(ctx, rediskey.JobNamespace+connCode, string(jBytes)).Result()
-	if err == nil {
-		notify(ctx, redis, connCode)
+	key := rediskey.JobStream(connCode)
+	id, err := client.XAdd(ctx, &redis.XAddArgs{
+		Stream:       key,
+		MaxLenApprox: JobStreamMaxLen,
+		Values:       map[string]interface{}{jobField: string(jBytes)},
+	}).Result()
+	if err != nil {
+		return "", err
 	}
 
-	// new list
-	if count < 2 {
-		// log.Printf("Set TTL for List")
-		redis.Expire(ctx, rediskey.JobNamespace+connCode, JobTTLSeconds*time.Second)
+	return id, client.Expire(ctx, key, JobTTLSeconds*time.Second).Err()
+}
+
+// encodePayload embeds the capture's payload as-is when it is already JSON, and as a JSON string otherwise,
+// so a malformed payload is rejected by the decoders instead of corrupting the envelope
+func encodePayload(payload string) json.RawMessage {
+	if json.Valid([]byte(payload)) {
+		return json.RawMessage(payload)
 	}
+	b, _ := json.Marshal(payload)
+	return b
+}
 
-	return err
+// PopJob returns the next job for the connect code without blocking, or redis.Nil if there is none.
+// Jobs that another consumer left un-acked for longer than JobClaimIdle are returned first.
+// The job stays pending until AckJob is called
+func PopJob(ctx context.Context, client *redis.Client, connCode string) (Job, error) {
+	job, err := ClaimStaleJob(ctx, client, connCode)
+	if !errors.Is(err, redis.Nil) {
+		return job, err
+	}
+	return ReadJob(ctx, client, connCode, -1)
 }
 
-func notify(ctx context.Context, redis *redis.Client, connCode string) {
-	redis.Publish(ctx, rediskey.JobNamespace+connCode+":notify", true)
+// ReadJob waits up to block for a new job for the connect code; a negative block doesn't wait at all.
+// Returns redis.Nil if no job arrived in time
+func ReadJob(ctx context.Context, client *redis.Client, connCode string, block time.Duration) (Job, error) {
+	key := rediskey.JobStream(connCode)
+	args := &redis.XReadGroupArgs{
+		Group:    JobConsumerGroup,
+		Consumer: consumerName,
+		Streams:  []string{key, ">"},
+		Count:    1,
+		Block:    block,
+	}
+	streams, err := client.XReadGroup(ctx, args).Result()
+	if isNoGroup(err) {
+		err = createJobGroup(ctx, client, key)
+		if err != nil {
+			return Job{}, err
+		}
+		streams, err = client.XReadGroup(ctx, args).Result()
+	}
+	if err != nil {
+		return Job{}, err
+	}
+	for _, stream := range streams {
+		for _, msg := range stream.Messages {
+			return parseJob(msg)
+		}
+	}
+	return Job{}, redis.Nil
 }
 
-func Subscribe(ctx context.Context, redis *redis.Client, connCode string) *redis.PubSub {
-	return redis.Subscribe(ctx, rediskey.JobNamespace+connCode+":notify")
+// ClaimStaleJob takes over the oldest job that has been pending for longer than JobClaimIdle,
+// such as one read by a shard that crashed before acking it. Returns redis.Nil if there is none
+func ClaimStaleJob(ctx context.Context, client *redis.Client, connCode string) (Job, error) {
+	key := rediskey.JobStream(connCode)
+	pending, err := client.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: key,
+		Group:  JobConsumerGroup,
+		Start:  "-",
+		End:    "+",
+		Count:  10,
+	}).Result()
+	if isNoGroup(err) {
+		return Job{}, redis.Nil
+	} else if err != nil {
+		return Job{}, err
+	}
+
+	for _, p := range pending {
+		if p.Idle < JobClaimIdle {
+			continue
+		}
+		msgs, err := client.XClaim(ctx, &redis.XClaimArgs{
+			Stream:   key,
+			Group:    JobConsumerGroup,
+			Consumer: consumerName,
+			MinIdle:  JobClaimIdle,
+			Messages: []string{p.ID},
+		}).Result()
+		if err != nil {
+			return Job{}, err
+		}
+		// someone else may have claimed it in the meantime
+		if len(msgs) > 0 {
+			log.Printf("Claimed job %s for %s from consumer %s after %s\n", p.ID, connCode, p.Consumer, p.Idle.String())
+			return parseJob(msgs[0])
+		}
+	}
+	return Job{}, redis.Nil
 }
 
-func PopJob(ctx context.Context, redis *redis.Client, connCode string) (Job, error) {
-	str, err := redis.LPop(ctx, rediskey.JobNamespace+connCode).Result()
+// AckJob marks the job as applied, so it won't be handed out again
+func AckJob(ctx context.Context, client *redis.Client, connCode string, job Job) error {
+	if job.ID == "" {
+		return nil
+	}
+	return client.XAck(ctx, rediskey.JobStream(connCode), JobConsumerGroup, job.ID).Err()
+}
 
-	j := Job{}
-	if err != nil {
-		return j, err
+// ReadJobs streams the jobs for the connect code into the returned channel until ctx is cancelled.
+// Stale jobs from other consumers are claimed every JobClaimIdle
+func ReadJobs(ctx context.Context, client *redis.Client, connCode string) <-chan Job {
+	jobs := make(chan Job)
+	go func() {
+		defer close(jobs)
+		var lastClaim time.Time
+		for ctx.Err() == nil {
+			var job Job
+			var err error
+			if time.Since(lastClaim) > JobClaimIdle {
+				lastClaim = time.Now()
+				job, err = ClaimStaleJob(ctx, client, connCode)
+			} else {
+				err = redis.Nil
+			}
+			if errors.Is(err, redis.Nil) {
+				job, err = ReadJob(ctx, client, connCode, JobReadBlock)
+			}
+			if errors.Is(err, redis.Nil) {
+				continue
+			} else if err != nil {
+				if ctx.Err() == nil {
+					log.Println(err)
+					time.Sleep(time.Second)
+				}
+				continue
+			}
+			select {
+			case jobs <- job:
+			case <-ctx.Done():
+			}
+		}
+	}()
+	return jobs
+}
+
+func parseJob(msg redis.XMessage) (Job, error) {
+	j := Job{ID: msg.ID}
+	str, ok := msg.Values[jobField].(string)
+	if !ok {
+		return j, fmt.Errorf("job %s has no %s field", msg.ID, jobField)
 	}
-	err = json.Unmarshal([]byte(str), &j)
+	err := json.Unmarshal([]byte(str), &j)
 	return j, err
 }
 
+func createJobGroup(ctx context.Context, client *redis.Client, key string) error {
+	err := client.XGroupCreateMkStream(ctx, key, JobConsumerGroup, "0").Err()
+	if err != nil && strings.HasPrefix(err.Error(), "BUSYGROUP") {
+		return nil
+	}
+	if err == nil {
+		client.Expire(ctx, key, JobTTLSeconds*time.Second)
+	}
+	return err
+}
+
+func isNoGroup(err error) bool {
+	return err != nil && strings.HasPrefix(err.Error(), "NOGROUP")
+}
+
 func Ack(ctx context.Context, redis *redis.Client, connCode string) {
 	redis.Publish(ctx, rediskey.JobNamespace+connCode+":ack", true)
 }
@@ -0,0 +1,85 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/go-redis/redis/v8"
+)
+
+func TestPushPopAckJob(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	ctx := context.Background()
+
+	_, err := PopJob(ctx, client, "ABCD")
+	if !errors.Is(err, redis.Nil) {
+		t.Fatalf("expected redis.Nil on an empty queue, got %v", err)
+	}
+
+	for _, payload := range []string{"1", "2"} {
+		err = PushJob(ctx, client, "ABCD", StateJob, payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i, expected := range []string{"1", "2"} {
+		job, err := PopJob(ctx, client, "ABCD")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if job.JobType != StateJob || job.RawPayload() != expected || job.ID == "" {
+			t.Errorf("expected state job %s, got %+v", expected, job)
+		}
+		if job.Seq != int64(i+1) {
+			t.Errorf("expected sequence number %d, got %d", i+1, job.Seq)
+		}
+		err = AckJob(ctx, client, "ABCD", job)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, err = PopJob(ctx, client, "ABCD")
+	if !errors.Is(err, redis.Nil) {
+		t.Fatalf("expected redis.Nil once all jobs are popped, got %v", err)
+	}
+}
+
+func TestClaimStaleJob(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	ctx := context.Background()
+
+	err := PushJob(ctx, client, "ABCD", LobbyJob, "{}")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// read, but never acked (the shard "crashed")
+	lost, err := PopJob(ctx, client, "ABCD")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = ClaimStaleJob(ctx, client, "ABCD")
+	if !errors.Is(err, redis.Nil) {
+		t.Fatalf("expected a recently read job not to be claimable, got %v", err)
+	}
+
+	mr.SetTime(time.Now().Add(JobClaimIdle + time.Second))
+
+	claimed, err := PopJob(ctx, client, "ABCD")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claimed.ID != lost.ID || claimed.JobType != LobbyJob {
+		t.Errorf("expected to claim job %s, got %+v", lost.ID, claimed)
+	}
+}
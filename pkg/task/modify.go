@@ -42,6 +42,59 @@ func NewModifyTask(guildID, userID uint64, params PatchParams) ModifyTask {
 	}
 }
 
+// BatchModifyTask hands a capture client every mute/deafen of a UserModifyRequest in one go.
+// The capture server answers with a single BatchModifyAck on CompleteTask(TaskID)
+type BatchModifyTask struct {
+	GuildID uint64            `json:"guildID"`
+	Request UserModifyRequest `json:"request"`
+	TaskID  string            `json:"taskID"`
+	// TimeoutMs is how long the capture server waits on its client before reporting the remaining users as failed
+	TimeoutMs int64 `json:"timeoutMs"`
+}
+
+func NewBatchModifyTask(guildID uint64, request UserModifyRequest, timeout time.Duration) BatchModifyTask {
+	h := sha256.New()
+	h.Write([]byte(fmt.Sprintf("%d", guildID)))
+	for _, user := range request.Users {
+		h.Write([]byte(fmt.Sprintf("%d", user.UserID)))
+	}
+	h.Write([]byte(fmt.Sprintf("%d", time.Now().UnixNano())))
+	return BatchModifyTask{
+		GuildID:   guildID,
+		Request:   request,
+		TaskID:    hex.EncodeToString(h.Sum(nil))[0:IDLength],
+		TimeoutMs: timeout.Milliseconds(),
+	}
+}
+
+type UserModifyResult struct {
+	UserID  uint64 `json:"userID"`
+	Success bool   `json:"success"`
+}
+
+// BatchModifyAck reports how each user of a BatchModifyTask fared
+type BatchModifyAck struct {
+	TaskID  string             `json:"taskID"`
+	Results []UserModifyResult `json:"results"`
+}
+
+// Failed returns the users of the request that weren't successfully modified, including any missing from the ack
+func (ack BatchModifyAck) Failed(request UserModifyRequest) []UserModify {
+	succeeded := make(map[uint64]bool, len(ack.Results))
+	for _, result := range ack.Results {
+		if result.Success {
+			succeeded[result.UserID] = true
+		}
+	}
+	var failed []UserModify
+	for _, user := range request.Users {
+		if !succeeded[user.UserID] {
+			failed = append(failed, user)
+		}
+	}
+	return failed
+}
+
 type PatchParams struct {
 	Deaf bool `json:"deaf"`
 	Mute bool `json:"mute"`
@@ -64,3 +117,20 @@ type MuteDeafenSuccessCounts struct {
 	Official  int64 `json:"official"`
 	RateLimit int64 `json:"ratelimit"`
 }
+
+// SuppressParams moves a user in a stage channel between the speakers and the audience
+type SuppressParams struct {
+	ChannelID string `json:"channel_id"`
+	Suppress  bool   `json:"suppress"`
+}
+
+// ApplySuppress moves the user to the stage channel's audience, or (without suppress) makes them a speaker
+func ApplySuppress(sess *discordgo.Session, guildID, channelID, userID string, suppress bool) error {
+	p := SuppressParams{
+		ChannelID: channelID,
+		Suppress:  suppress,
+	}
+
+	_, err := sess.RequestWithBucketID("PATCH", discordgo.EndpointGuild(guildID)+"/voice-states/"+userID, p, discordgo.EndpointGuild(guildID)+"/voice-states/")
+	return err
+}
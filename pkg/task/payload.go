@@ -0,0 +1,135 @@
+package task
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/automuteus/automuteus/v8/pkg/game"
+)
+
+var (
+	// ErrInvalidPayload wraps any payload that can't be decoded into the type its job says it carries
+	ErrInvalidPayload = errors.New("invalid job payload")
+	// ErrUnsupportedVersion is returned for jobs written by a newer schema than this bot understands
+	ErrUnsupportedVersion = errors.New("unsupported job schema version")
+)
+
+func invalidPayload(format string, a ...interface{}) error {
+	return fmt.Errorf("%w: %s", ErrInvalidPayload, fmt.Sprintf(format, a...))
+}
+
+// body returns the JSON document carried by the job.
+// Jobs pushed before the envelope was versioned carry it double-encoded, as a JSON string
+func (job Job) body() ([]byte, error) {
+	if job.Version > JobSchemaVersion {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, job.Version)
+	}
+	raw := bytes.TrimSpace(job.Payload)
+	if len(raw) == 0 {
+		return nil, invalidPayload("empty payload for job type %d", job.JobType)
+	}
+	if raw[0] == '"' {
+		var str string
+		err := json.Unmarshal(raw, &str)
+		if err != nil {
+			return nil, invalidPayload("%s", err)
+		}
+		return []byte(str), nil
+	}
+	return raw, nil
+}
+
+// RawPayload is the payload as the capture sent it, for logging and storing alongside game events
+func (job Job) RawPayload() string {
+	b, err := job.body()
+	if err != nil {
+		return string(job.Payload)
+	}
+	return string(b)
+}
+
+func (job Job) expect(jobType JobType) ([]byte, error) {
+	if job.JobType != jobType {
+		return nil, invalidPayload("expected job type %d, got %d", jobType, job.JobType)
+	}
+	return job.body()
+}
+
+func (job Job) DecodeConnection() (bool, error) {
+	b, err := job.expect(ConnectionJob)
+	if err != nil {
+		return false, err
+	}
+	var connected bool
+	err = json.Unmarshal(b, &connected)
+	if err != nil {
+		return false, invalidPayload("connection: %s", err)
+	}
+	return connected, nil
+}
+
+func (job Job) DecodeLobby() (game.Lobby, error) {
+	var lobby game.Lobby
+	b, err := job.expect(LobbyJob)
+	if err != nil {
+		return lobby, err
+	}
+	err = json.Unmarshal(b, &lobby)
+	if err != nil {
+		return lobby, invalidPayload("lobby: %s", err)
+	}
+	return lobby, nil
+}
+
+func (job Job) DecodePhase() (game.Phase, error) {
+	b, err := job.expect(StateJob)
+	if err != nil {
+		return game.UNINITIALIZED, err
+	}
+	var phase game.Phase
+	err = json.Unmarshal(b, &phase)
+	if err != nil {
+		return game.UNINITIALIZED, invalidPayload("phase: %s", err)
+	}
+	if phase < game.LOBBY || phase >= game.UNINITIALIZED {
+		return game.UNINITIALIZED, invalidPayload("unknown phase %d", phase)
+	}
+	return phase, nil
+}
+
+func (job Job) DecodePlayer() (game.Player, error) {
+	var player game.Player
+	b, err := job.expect(PlayerJob)
+	if err != nil {
+		return player, err
+	}
+	err = json.Unmarshal(b, &player)
+	if err != nil {
+		return player, invalidPayload("player: %s", err)
+	}
+	if player.Color < game.Red || player.Color > game.Coral {
+		return player, invalidPayload("unknown color %d for player %s", player.Color, player.Name)
+	}
+	if player.Action < game.JOINED || player.Action > game.EXILED {
+		return player, invalidPayload("unknown action %d for player %s", player.Action, player.Name)
+	}
+	return player, nil
+}
+
+func (job Job) DecodeGameOver() (game.Gameover, error) {
+	var gameOver game.Gameover
+	b, err := job.expect(GameOverJob)
+	if err != nil {
+		return gameOver, err
+	}
+	err = json.Unmarshal(b, &gameOver)
+	if err != nil {
+		return gameOver, invalidPayload("gameover: %s", err)
+	}
+	if gameOver.GameOverReason < game.HumansByVote || gameOver.GameOverReason > game.Unknown {
+		return gameOver, invalidPayload("unknown game over reason %d", gameOver.GameOverReason)
+	}
+	return gameOver, nil
+}
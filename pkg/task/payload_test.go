@@ -0,0 +1,88 @@
+package task
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/automuteus/automuteus/v8/pkg/game"
+)
+
+func TestDecodeLegacyPayload(t *testing.T) {
+	// jobs queued before the envelope was versioned carry their payload as a JSON string
+	var job Job
+	err := json.Unmarshal([]byte(`{"type":3,"payload":"{\"Action\":2,\"Name\":\"Red\",\"Color\":0,\"IsDead\":true}"}`), &job)
+	if err != nil {
+		t.Fatal(err)
+	}
+	player, err := job.DecodePlayer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if player.Name != "Red" || player.Action != game.DIED || !player.IsDead {
+		t.Errorf("unexpected player %+v", player)
+	}
+}
+
+func TestDecodePayloads(t *testing.T) {
+	job := Job{Version: JobSchemaVersion, JobType: StateJob, Payload: encodePayload("2")}
+	phase, err := job.DecodePhase()
+	if err != nil || phase != game.DISCUSS {
+		t.Errorf("expected DISCUSS, got %d (%v)", phase, err)
+	}
+
+	job = Job{Version: JobSchemaVersion, JobType: ConnectionJob, Payload: encodePayload("true")}
+	connected, err := job.DecodeConnection()
+	if err != nil || !connected {
+		t.Errorf("expected connected, got %v (%v)", connected, err)
+	}
+
+	job = Job{Version: JobSchemaVersion, JobType: LobbyJob, Payload: encodePayload(`{"LobbyCode":"ABCDEF","Region":2,"Map":1}`)}
+	lobby, err := job.DecodeLobby()
+	if err != nil || lobby.LobbyCode != "ABCDEF" || lobby.Region != game.EU || lobby.PlayMap != game.MIRA {
+		t.Errorf("unexpected lobby %+v (%v)", lobby, err)
+	}
+
+	job = Job{Version: JobSchemaVersion, JobType: GameOverJob, Payload: encodePayload(`{"GameOverReason":3,"PlayerInfos":[{"Name":"Red","IsImpostor":true}]}`)}
+	gameOver, err := job.DecodeGameOver()
+	if err != nil || gameOver.GameOverReason != game.ImpostorByKill || len(gameOver.PlayerInfos) != 1 {
+		t.Errorf("unexpected gameover %+v (%v)", gameOver, err)
+	}
+}
+
+func TestDecodeInvalidPayloads(t *testing.T) {
+	tests := []struct {
+		name     string
+		job      Job
+		expected error
+	}{
+		{"not json", Job{Version: JobSchemaVersion, JobType: LobbyJob, Payload: encodePayload("{garbage")}, ErrInvalidPayload},
+		{"empty", Job{Version: JobSchemaVersion, JobType: StateJob}, ErrInvalidPayload},
+		{"unknown phase", Job{Version: JobSchemaVersion, JobType: StateJob, Payload: encodePayload("42")}, ErrInvalidPayload},
+		{"bad color", Job{Version: JobSchemaVersion, JobType: PlayerJob, Payload: encodePayload(`{"Name":"Red","Color":18}`)}, ErrInvalidPayload},
+		{"bad reason", Job{Version: JobSchemaVersion, JobType: GameOverJob, Payload: encodePayload(`{"GameOverReason":99}`)}, ErrInvalidPayload},
+		{"wrong type", Job{Version: JobSchemaVersion, JobType: LobbyJob, Payload: encodePayload("1")}, ErrInvalidPayload},
+		{"newer version", Job{Version: JobSchemaVersion + 1, JobType: StateJob, Payload: encodePayload("1")}, ErrUnsupportedVersion},
+	}
+	for _, test := range tests {
+		var err error
+		switch test.name {
+		case "wrong type":
+			_, err = test.job.DecodePhase()
+		default:
+			switch test.job.JobType {
+			case LobbyJob:
+				_, err = test.job.DecodeLobby()
+			case StateJob:
+				_, err = test.job.DecodePhase()
+			case PlayerJob:
+				_, err = test.job.DecodePlayer()
+			case GameOverJob:
+				_, err = test.job.DecodeGameOver()
+			}
+		}
+		if !errors.Is(err, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.name, test.expected, err)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package task
+
+import "time"
+
+const (
+	// JobReorderWindow is how long a job that arrived ahead of its turn is held back, waiting for the ones before it
+	JobReorderWindow = time.Millisecond * 500
+	// JobReorderLimit is how many jobs can be held back before the missing ones are given up on right away
+	JobReorderLimit = 32
+)
+
+// Sequencer puts a game's jobs back in Seq order before they're applied.
+// Jobs without a Seq (queued before jobs were sequenced, or replayed from an old recording) pass straight through
+type Sequencer struct {
+	next    int64
+	pending map[int64]Job
+	// heldSince is when the sequencer started waiting on the job at next
+	heldSince time.Time
+}
+
+func NewSequencer() *Sequencer {
+	return &Sequencer{
+		pending: make(map[int64]Job),
+	}
+}
+
+// Push returns the jobs that can be applied now, in order. A stale job is one at or behind a sequence number that was
+// already applied (or given up on); it should be discarded
+func (s *Sequencer) Push(job Job) (ready []Job, stale bool) {
+	if job.Seq == 0 {
+		return []Job{job}, false
+	}
+	if s.next == 0 {
+		s.next = job.Seq
+	}
+	if job.Seq < s.next {
+		return nil, true
+	}
+	if _, ok := s.pending[job.Seq]; ok {
+		return nil, true
+	}
+
+	if len(s.pending) == 0 {
+		s.heldSince = time.Now()
+	}
+	s.pending[job.Seq] = job
+	return s.drain(), false
+}
+
+// Deadline is when Skip should be called, if any jobs are being held back
+func (s *Sequencer) Deadline() (time.Time, bool) {
+	if len(s.pending) == 0 {
+		return time.Time{}, false
+	}
+	if len(s.pending) >= JobReorderLimit {
+		return time.Now(), true
+	}
+	return s.heldSince.Add(JobReorderWindow), true
+}
+
+// Skip gives up on the missing jobs in front of the ones being held back. It returns the jobs that can be applied now,
+// and how many sequence numbers were skipped over
+func (s *Sequencer) Skip() (ready []Job, missing int64) {
+	if len(s.pending) == 0 {
+		return nil, 0
+	}
+	first := int64(-1)
+	for seq := range s.pending {
+		if first == -1 || seq < first {
+			first = seq
+		}
+	}
+	missing = first - s.next
+	s.next = first
+	return s.drain(), missing
+}
+
+func (s *Sequencer) drain() []Job {
+	var ready []Job
+	for {
+		job, ok := s.pending[s.next]
+		if !ok {
+			break
+		}
+		ready = append(ready, job)
+		delete(s.pending, s.next)
+		s.next++
+	}
+	if len(ready) > 0 && len(s.pending) > 0 {
+		// the jobs still held back are now waiting on a new gap
+		s.heldSince = time.Now()
+	}
+	return ready
+}
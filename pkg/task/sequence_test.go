@@ -0,0 +1,96 @@
+package task
+
+import (
+	"testing"
+	"time"
+)
+
+func seqJob(seq int64) Job {
+	return Job{Version: JobSchemaVersion, Seq: seq, JobType: StateJob}
+}
+
+func seqs(jobs []Job) []int64 {
+	var out []int64
+	for _, job := range jobs {
+		out = append(out, job.Seq)
+	}
+	return out
+}
+
+func equalSeqs(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestSequencerReorders(t *testing.T) {
+	s := NewSequencer()
+
+	ready, stale := s.Push(seqJob(5))
+	if stale || !equalSeqs(seqs(ready), []int64{5}) {
+		t.Fatalf("expected the first job to pass through, got %v (stale %v)", seqs(ready), stale)
+	}
+
+	// 7 arrives before 6, so it's held back until 6 shows up
+	ready, _ = s.Push(seqJob(7))
+	if len(ready) != 0 {
+		t.Errorf("expected job 7 to be held back, got %v", seqs(ready))
+	}
+	if _, ok := s.Deadline(); !ok {
+		t.Error("expected a deadline while a job is held back")
+	}
+	ready, _ = s.Push(seqJob(6))
+	if !equalSeqs(seqs(ready), []int64{6, 7}) {
+		t.Errorf("expected jobs 6 and 7, got %v", seqs(ready))
+	}
+	if _, ok := s.Deadline(); ok {
+		t.Error("expected no deadline once nothing is held back")
+	}
+
+	// duplicates and late arrivals are stale
+	for _, seq := range []int64{6, 3} {
+		ready, stale = s.Push(seqJob(seq))
+		if !stale || len(ready) != 0 {
+			t.Errorf("expected job %d to be stale, got %v (stale %v)", seq, seqs(ready), stale)
+		}
+	}
+
+	// unsequenced jobs aren't held back
+	ready, stale = s.Push(seqJob(0))
+	if stale || len(ready) != 1 {
+		t.Errorf("expected an unsequenced job to pass through, got %v (stale %v)", seqs(ready), stale)
+	}
+}
+
+func TestSequencerSkipsGaps(t *testing.T) {
+	s := NewSequencer()
+	s.Push(seqJob(1))
+	s.Push(seqJob(4))
+	s.Push(seqJob(5))
+
+	deadline, ok := s.Deadline()
+	if !ok || deadline.After(time.Now().Add(JobReorderWindow)) {
+		t.Fatalf("expected a deadline within the reorder window, got %v (%v)", deadline, ok)
+	}
+
+	ready, missing := s.Skip()
+	if missing != 2 || !equalSeqs(seqs(ready), []int64{4, 5}) {
+		t.Errorf("expected to skip 2 jobs and apply 4 and 5, got %v (missing %d)", seqs(ready), missing)
+	}
+
+	// the jobs that were skipped over are stale when they finally show up
+	_, stale := s.Push(seqJob(2))
+	if !stale {
+		t.Error("expected a skipped job to be stale")
+	}
+	ready, _ = s.Push(seqJob(6))
+	if !equalSeqs(seqs(ready), []int64{6}) {
+		t.Errorf("expected job 6, got %v", seqs(ready))
+	}
+}
@@ -0,0 +1,75 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrInvalidURL = errors.New("webhook URL must be an absolute http or https URL")
+	// ErrForbiddenHost is for webhooks pointing at loopback, private or link-local addresses (cloud metadata
+	// endpoints included); they'd let a guild reach into the network the bot runs on
+	ErrForbiddenHost = errors.New("webhook host is a loopback, private or link-local address")
+)
+
+// lookupTimeout bounds resolving a webhook's host when it's added
+const lookupTimeout = time.Second * 2
+
+// sharedAddressSpace is carrier-grade NAT, which some clouds use for their metadata endpoints
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+func forbiddenIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() || sharedAddressSpace.Contains(ip)
+}
+
+// ValidateURL parses a webhook URL, refusing anything but http(s) and hosts on the bot's own network. Names are
+// resolved on a best-effort basis; every connection is checked again when it's dialed, as a name can be pointed
+// somewhere else after it's added
+func ValidateURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return nil, ErrInvalidURL
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return nil, ErrForbiddenHost
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		if forbiddenIP(ip) {
+			return nil, ErrForbiddenHost
+		}
+		return u, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), lookupTimeout)
+	defer cancel()
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		// a name that doesn't resolve (yet) can't be reached either; it's refused at dial time if it ever resolves badly
+		return u, nil
+	}
+	for _, v := range addrs {
+		if forbiddenIP(v.IP) {
+			return nil, ErrForbiddenHost
+		}
+	}
+	return u, nil
+}
+
+// dialControl refuses connections to forbidden addresses, whatever the webhook's host resolved to this time
+func dialControl(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || forbiddenIP(ip) {
+		return ErrForbiddenHost
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+)
+
+const (
+	DefaultMaxAttempts = 5
+	DefaultBackoff     = time.Second
+	DefaultTimeout     = time.Second * 10
+)
+
+// Payload is the JSON body of every webhook POST
+type Payload struct {
+	Event       Event       `json:"event"`
+	GuildID     string      `json:"guildID"`
+	ConnectCode string      `json:"connectCode"`
+	MatchID     int64       `json:"matchID,omitempty"`
+	Timestamp   int64       `json:"timestamp"`
+	Data        interface{} `json:"data,omitempty"`
+}
+
+type PlayerData struct {
+	Name   string `json:"name"`
+	Color  string `json:"color"`
+	UserID string `json:"userID,omitempty"`
+}
+
+type PhaseData struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type GameOverData struct {
+	Reason       int16            `json:"reason"`
+	ImpostorsWon bool             `json:"impostorsWon"`
+	Players      []GameOverPlayer `json:"players"`
+}
+
+type GameOverPlayer struct {
+	Name     string `json:"name"`
+	UserID   string `json:"userID,omitempty"`
+	Impostor bool   `json:"impostor"`
+	Won      bool   `json:"won"`
+	// Team is "crewmate", "impostor" or "neutral"; Role is only set for modded games
+	Team string `json:"team"`
+	Role string `json:"role,omitempty"`
+}
+
+// Dispatcher delivers payloads in the background, retrying with exponential backoff on network errors, 5xx and 429
+type Dispatcher struct {
+	Client      *http.Client
+	MaxAttempts int
+	// Backoff is the wait before the first retry; it doubles for every one after that
+	Backoff time.Duration
+}
+
+// NewDispatcher delivers over a client that can't connect to the bot's own network; see ValidateURL
+func NewDispatcher() *Dispatcher {
+	dialer := &net.Dialer{
+		Timeout:   DefaultTimeout,
+		KeepAlive: 30 * time.Second,
+		Control:   dialControl,
+	}
+	return &Dispatcher{
+		Client: &http.Client{
+			Timeout: DefaultTimeout,
+			// no proxy; the dialer has to see where each request is really going
+			Transport: &http.Transport{
+				DialContext:         dialer.DialContext,
+				MaxIdleConns:        100,
+				IdleConnTimeout:     90 * time.Second,
+				TLSHandshakeTimeout: DefaultTimeout,
+			},
+		},
+		MaxAttempts: DefaultMaxAttempts,
+		Backoff:     DefaultBackoff,
+	}
+}
+
+// Dispatch sends the payload to every subscription that wants its event, without blocking
+func (d *Dispatcher) Dispatch(subs []Subscription, payload Payload) {
+	if payload.Timestamp == 0 {
+		payload.Timestamp = time.Now().Unix()
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Println(err)
+		return
+	}
+	for _, sub := range subs {
+		if !sub.Wants(payload.Event) {
+			continue
+		}
+		go func(sub Subscription) {
+			err := d.Deliver(sub, payload.Event, body)
+			if err != nil {
+				log.Printf("Giving up on webhook %s for guild %s: %s\n", sub.ID, payload.GuildID, err)
+			}
+		}(sub)
+	}
+}
+
+// Deliver POSTs the body to the subscription, blocking until it's accepted or every attempt has failed.
+// Each attempt carries the same DeliveryHeader, so receivers can discard duplicates
+func (d *Dispatcher) Deliver(sub Subscription, event Event, body []byte) error {
+	deliveryID := randomHex(8)
+	signature := Sign(sub.Secret, body)
+	backoff := d.Backoff
+
+	var err error
+	for attempt := 1; attempt <= d.MaxAttempts; attempt++ {
+		var retry bool
+		retry, err = d.post(sub.URL, event, deliveryID, signature, body)
+		if err == nil || !retry {
+			return err
+		}
+		if attempt < d.MaxAttempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+	return err
+}
+
+func (d *Dispatcher) post(url string, event Event, deliveryID, signature string, body []byte) (retry bool, err error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "AutoMuteUs-Webhook")
+	req.Header.Set(EventHeader, string(event))
+	req.Header.Set(DeliveryHeader, deliveryID)
+	req.Header.Set(SignatureHeader, signature)
+
+	resp, err := d.Client.Do(req)
+	if err != nil {
+		// a forbidden host stays forbidden
+		return !errors.Is(err, ErrForbiddenHost), err
+	}
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return true, fmt.Errorf("webhook responded with %s", resp.Status)
+	default:
+		// the receiver rejected the payload; sending it again won't change that
+		return false, fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+}
@@ -0,0 +1,154 @@
+package webhook
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDeliverSignsAndRetries(t *testing.T) {
+	sub := NewSubscription("", []Event{GameStart})
+
+	var lock sync.Mutex
+	var attempts int
+	var deliveries []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if !Verify(sub.Secret, body, r.Header.Get(SignatureHeader)) {
+			t.Errorf("bad signature %s", r.Header.Get(SignatureHeader))
+		}
+		if r.Header.Get(EventHeader) != string(GameStart) {
+			t.Errorf("expected event header %s, got %s", GameStart, r.Header.Get(EventHeader))
+		}
+		var payload Payload
+		err := json.Unmarshal(body, &payload)
+		if err != nil {
+			t.Error(err)
+		}
+		if payload.GuildID != "100" || payload.Event != GameStart {
+			t.Errorf("unexpected payload %+v", payload)
+		}
+
+		lock.Lock()
+		defer lock.Unlock()
+		attempts++
+		deliveries = append(deliveries, r.Header.Get(DeliveryHeader))
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+	sub.URL = srv.URL
+
+	d := &Dispatcher{Client: srv.Client(), MaxAttempts: 5, Backoff: time.Millisecond}
+	body, _ := json.Marshal(Payload{Event: GameStart, GuildID: "100", Timestamp: 1})
+	err := d.Deliver(sub, GameStart, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+	for _, v := range deliveries {
+		if v == "" || v != deliveries[0] {
+			t.Errorf("expected every attempt to share one delivery ID, got %v", deliveries)
+		}
+	}
+}
+
+func TestDeliverGivesUp(t *testing.T) {
+	var attempts int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if r.URL.Path == "/gone" {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	d := &Dispatcher{Client: srv.Client(), MaxAttempts: 3, Backoff: time.Millisecond}
+	err := d.Deliver(Subscription{URL: srv.URL, Secret: "s"}, GameOver, []byte("{}"))
+	if err == nil || attempts != 3 {
+		t.Errorf("expected 3 failed attempts, got %d (%v)", attempts, err)
+	}
+
+	// client errors aren't retried
+	attempts = 0
+	err = d.Deliver(Subscription{URL: srv.URL + "/gone", Secret: "s"}, GameOver, []byte("{}"))
+	if err == nil || attempts != 1 {
+		t.Errorf("expected a single failed attempt, got %d (%v)", attempts, err)
+	}
+}
+
+func TestParseEvents(t *testing.T) {
+	events, ok := ParseEvents("all")
+	if !ok || events != nil {
+		t.Error("\"all\" should subscribe to every event")
+	}
+	events, ok = ParseEvents("game.start, game.over")
+	if !ok || len(events) != 2 || events[0] != GameStart || events[1] != GameOver {
+		t.Errorf("unexpected events %v", events)
+	}
+	_, ok = ParseEvents("game.start,nope")
+	if ok {
+		t.Error("unknown events should be rejected")
+	}
+
+	sub := Subscription{Events: events}
+	if !sub.Wants(GameOver) || sub.Wants(PlayerDeath) {
+		t.Error("subscription should only want the events it lists")
+	}
+	if !(Subscription{}).Wants(PlayerDeath) {
+		t.Error("subscriptions without events should want everything")
+	}
+}
+
+func TestValidateURL(t *testing.T) {
+	for _, v := range []string{
+		"http://localhost:8080/hook",
+		"http://127.0.0.1/hook",
+		"https://10.0.0.5/hook",
+		"https://192.168.1.1/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://[::1]/hook",
+		"http://100.100.100.200/hook",
+	} {
+		if _, err := ValidateURL(v); !errors.Is(err, ErrForbiddenHost) {
+			t.Errorf("expected %s to be refused, got %v", v, err)
+		}
+	}
+	for _, v := range []string{"ftp://example.com", "example.com/hook", "http://"} {
+		if _, err := ValidateURL(v); !errors.Is(err, ErrInvalidURL) {
+			t.Errorf("expected %s to be invalid, got %v", v, err)
+		}
+	}
+	if _, err := ValidateURL("https://203.0.113.7/hook"); err != nil {
+		t.Errorf("expected a public address to be accepted, got %v", err)
+	}
+}
+
+func TestDispatcherRefusesLocalAddresses(t *testing.T) {
+	var attempts int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	// as if the webhook's name had been pointed at the loopback after it was added
+	d := NewDispatcher()
+	d.Backoff = time.Millisecond
+	err := d.Deliver(Subscription{URL: srv.URL, Secret: "s"}, GameOver, []byte("{}"))
+	if !errors.Is(err, ErrForbiddenHost) || attempts != 0 {
+		t.Errorf("expected the delivery to be refused at dial time, got %d attempts (%v)", attempts, err)
+	}
+}
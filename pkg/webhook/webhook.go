@@ -0,0 +1,108 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+type Event string
+
+const (
+	GameStart    Event = "game.start"
+	PhaseChange  Event = "game.phase"
+	GameOver     Event = "game.over"
+	PlayerDeath  Event = "player.death"
+	PlayerExile  Event = "player.exile"
+	PlayerLink   Event = "player.link"
+	PlayerUnlink Event = "player.unlink"
+)
+
+var AllEvents = []Event{GameStart, PhaseChange, GameOver, PlayerDeath, PlayerExile, PlayerLink, PlayerUnlink}
+
+// MaxSubscriptions is how many webhooks a single guild can register
+const MaxSubscriptions = 5
+
+const (
+	SignatureHeader = "X-AutoMuteUs-Signature"
+	EventHeader     = "X-AutoMuteUs-Event"
+	DeliveryHeader  = "X-AutoMuteUs-Delivery"
+)
+
+// Subscription is a URL that gets POSTed to when any of its events happen in a guild
+type Subscription struct {
+	ID     string `json:"id"`
+	URL    string `json:"url"`
+	Secret string `json:"secret,omitempty"`
+	// Events is empty to receive every event
+	Events []Event `json:"events,omitempty"`
+}
+
+func NewSubscription(url string, events []Event) Subscription {
+	return Subscription{
+		ID:     randomHex(4),
+		URL:    url,
+		Secret: randomHex(32),
+		Events: events,
+	}
+}
+
+func (s Subscription) Wants(event Event) bool {
+	if len(s.Events) == 0 {
+		return true
+	}
+	for _, v := range s.Events {
+		if v == event {
+			return true
+		}
+	}
+	return false
+}
+
+func IsEvent(s string) bool {
+	for _, v := range AllEvents {
+		if string(v) == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseEvents accepts a comma-separated list of events; "" or "all" means every event
+func ParseEvents(s string) ([]Event, bool) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "all" {
+		return nil, true
+	}
+	var events []Event
+	for _, v := range strings.Split(s, ",") {
+		v = strings.TrimSpace(v)
+		if !IsEvent(v) {
+			return nil, false
+		}
+		events = append(events, Event(v))
+	}
+	return events, true
+}
+
+// Sign returns the value of the SignatureHeader for a body: the hex HMAC-SHA256 of the body, keyed by the
+// subscription's secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify is what a receiver does with the SignatureHeader; it's here for tests and Go consumers
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	// crypto/rand only fails if the OS can't provide randomness, in which case nothing else is going to work either
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
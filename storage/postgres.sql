@@ -18,6 +18,9 @@ create table if not exists games
     end_time     integer                                      --2038 problem, but I do not care
 );
 
+alter table games add column if not exists game_mode smallint NOT NULL DEFAULT 0; --normal, hide and seek, etc
+alter table games add column if not exists neutral_won bool NOT NULL DEFAULT false; --any neutral won, linked to a user or not
+
 -- links userIDs to their hashed variants. Allows for deletion of users without deleting underlying game_event data
 create table if not exists users
 (
@@ -51,6 +54,8 @@ create table if not exists users_games
     PRIMARY KEY (user_id, game_id)
 );
 
+alter table users_games add column if not exists role_name VARCHAR(32) NOT NULL DEFAULT ''; --modded role, like Jester; empty for vanilla games
+
 create index if not exists guilds_id_index ON guilds (guild_id); --query guilds by ID
 create index if not exists guilds_premium_index ON guilds (premium); --query guilds by prem status
 